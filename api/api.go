@@ -0,0 +1,9 @@
+// Пакет api содержит спецификацию OpenAPI сервиса, встроенную в бинарный файл
+package api
+
+import _ "embed"
+
+// Документ OpenAPI 3.1, отдается по /api/openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
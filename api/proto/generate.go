@@ -0,0 +1,4 @@
+// Пакет proto содержит описания gRPC API и сгенерированный по ним код
+package proto
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative orders/v1/orders.proto
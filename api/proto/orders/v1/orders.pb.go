@@ -0,0 +1,1092 @@
+// Описание gRPC API сервиса заказов
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: orders/v1/orders.proto
+
+package ordersv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Заказ
+type Order struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	OrderUid          string                 `protobuf:"bytes,1,opt,name=order_uid,json=orderUid,proto3" json:"order_uid,omitempty"`
+	TrackNumber       string                 `protobuf:"bytes,2,opt,name=track_number,json=trackNumber,proto3" json:"track_number,omitempty"`
+	Entry             string                 `protobuf:"bytes,3,opt,name=entry,proto3" json:"entry,omitempty"`
+	Locale            string                 `protobuf:"bytes,4,opt,name=locale,proto3" json:"locale,omitempty"`
+	InternalSignature string                 `protobuf:"bytes,5,opt,name=internal_signature,json=internalSignature,proto3" json:"internal_signature,omitempty"`
+	CustomerId        string                 `protobuf:"bytes,6,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	DeliveryService   string                 `protobuf:"bytes,7,opt,name=delivery_service,json=deliveryService,proto3" json:"delivery_service,omitempty"`
+	Shardkey          string                 `protobuf:"bytes,8,opt,name=shardkey,proto3" json:"shardkey,omitempty"`
+	SmId              uint32                 `protobuf:"varint,9,opt,name=sm_id,json=smId,proto3" json:"sm_id,omitempty"`
+	DateCreated       *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=date_created,json=dateCreated,proto3" json:"date_created,omitempty"`
+	OofShard          string                 `protobuf:"bytes,11,opt,name=oof_shard,json=oofShard,proto3" json:"oof_shard,omitempty"`
+	Delivery          *Delivery              `protobuf:"bytes,12,opt,name=delivery,proto3" json:"delivery,omitempty"`
+	Payment           *Payment               `protobuf:"bytes,13,opt,name=payment,proto3" json:"payment,omitempty"`
+	Items             []*Item                `protobuf:"bytes,14,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_orders_v1_orders_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Order) GetOrderUid() string {
+	if x != nil {
+		return x.OrderUid
+	}
+	return ""
+}
+
+func (x *Order) GetTrackNumber() string {
+	if x != nil {
+		return x.TrackNumber
+	}
+	return ""
+}
+
+func (x *Order) GetEntry() string {
+	if x != nil {
+		return x.Entry
+	}
+	return ""
+}
+
+func (x *Order) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *Order) GetInternalSignature() string {
+	if x != nil {
+		return x.InternalSignature
+	}
+	return ""
+}
+
+func (x *Order) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *Order) GetDeliveryService() string {
+	if x != nil {
+		return x.DeliveryService
+	}
+	return ""
+}
+
+func (x *Order) GetShardkey() string {
+	if x != nil {
+		return x.Shardkey
+	}
+	return ""
+}
+
+func (x *Order) GetSmId() uint32 {
+	if x != nil {
+		return x.SmId
+	}
+	return 0
+}
+
+func (x *Order) GetDateCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DateCreated
+	}
+	return nil
+}
+
+func (x *Order) GetOofShard() string {
+	if x != nil {
+		return x.OofShard
+	}
+	return ""
+}
+
+func (x *Order) GetDelivery() *Delivery {
+	if x != nil {
+		return x.Delivery
+	}
+	return nil
+}
+
+func (x *Order) GetPayment() *Payment {
+	if x != nil {
+		return x.Payment
+	}
+	return nil
+}
+
+func (x *Order) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// Доставка
+type Delivery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Phone         string                 `protobuf:"bytes,2,opt,name=phone,proto3" json:"phone,omitempty"`
+	Zip           string                 `protobuf:"bytes,3,opt,name=zip,proto3" json:"zip,omitempty"`
+	City          string                 `protobuf:"bytes,4,opt,name=city,proto3" json:"city,omitempty"`
+	Address       string                 `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	Region        string                 `protobuf:"bytes,6,opt,name=region,proto3" json:"region,omitempty"`
+	Email         string                 `protobuf:"bytes,7,opt,name=email,proto3" json:"email,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Delivery) Reset() {
+	*x = Delivery{}
+	mi := &file_orders_v1_orders_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Delivery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Delivery) ProtoMessage() {}
+
+func (x *Delivery) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Delivery.ProtoReflect.Descriptor instead.
+func (*Delivery) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Delivery) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Delivery) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *Delivery) GetZip() string {
+	if x != nil {
+		return x.Zip
+	}
+	return ""
+}
+
+func (x *Delivery) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Delivery) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Delivery) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *Delivery) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+// Оплата. Суммы отсутствуют, если роль субъекта не позволяет их видеть
+type Payment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transaction   string                 `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	RequestId     string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Currency      string                 `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	Provider      string                 `protobuf:"bytes,4,opt,name=provider,proto3" json:"provider,omitempty"`
+	Amount        *int64                 `protobuf:"varint,5,opt,name=amount,proto3,oneof" json:"amount,omitempty"`
+	PaymentDt     uint64                 `protobuf:"varint,6,opt,name=payment_dt,json=paymentDt,proto3" json:"payment_dt,omitempty"`
+	Bank          string                 `protobuf:"bytes,7,opt,name=bank,proto3" json:"bank,omitempty"`
+	DeliveryCost  *uint64                `protobuf:"varint,8,opt,name=delivery_cost,json=deliveryCost,proto3,oneof" json:"delivery_cost,omitempty"`
+	GoodsTotal    *uint64                `protobuf:"varint,9,opt,name=goods_total,json=goodsTotal,proto3,oneof" json:"goods_total,omitempty"`
+	CustomFee     *uint64                `protobuf:"varint,10,opt,name=custom_fee,json=customFee,proto3,oneof" json:"custom_fee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Payment) Reset() {
+	*x = Payment{}
+	mi := &file_orders_v1_orders_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Payment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Payment) ProtoMessage() {}
+
+func (x *Payment) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Payment.ProtoReflect.Descriptor instead.
+func (*Payment) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Payment) GetTransaction() string {
+	if x != nil {
+		return x.Transaction
+	}
+	return ""
+}
+
+func (x *Payment) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *Payment) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Payment) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Payment) GetAmount() int64 {
+	if x != nil && x.Amount != nil {
+		return *x.Amount
+	}
+	return 0
+}
+
+func (x *Payment) GetPaymentDt() uint64 {
+	if x != nil {
+		return x.PaymentDt
+	}
+	return 0
+}
+
+func (x *Payment) GetBank() string {
+	if x != nil {
+		return x.Bank
+	}
+	return ""
+}
+
+func (x *Payment) GetDeliveryCost() uint64 {
+	if x != nil && x.DeliveryCost != nil {
+		return *x.DeliveryCost
+	}
+	return 0
+}
+
+func (x *Payment) GetGoodsTotal() uint64 {
+	if x != nil && x.GoodsTotal != nil {
+		return *x.GoodsTotal
+	}
+	return 0
+}
+
+func (x *Payment) GetCustomFee() uint64 {
+	if x != nil && x.CustomFee != nil {
+		return *x.CustomFee
+	}
+	return 0
+}
+
+// Предмет заказа
+type Item struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChrtId        uint64                 `protobuf:"varint,1,opt,name=chrt_id,json=chrtId,proto3" json:"chrt_id,omitempty"`
+	TrackNumber   string                 `protobuf:"bytes,2,opt,name=track_number,json=trackNumber,proto3" json:"track_number,omitempty"`
+	Price         uint64                 `protobuf:"varint,3,opt,name=price,proto3" json:"price,omitempty"`
+	Rid           string                 `protobuf:"bytes,4,opt,name=rid,proto3" json:"rid,omitempty"`
+	Name          string                 `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	Sale          uint64                 `protobuf:"varint,6,opt,name=sale,proto3" json:"sale,omitempty"`
+	Size          string                 `protobuf:"bytes,7,opt,name=size,proto3" json:"size,omitempty"`
+	TotalPrice    uint64                 `protobuf:"varint,8,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
+	NmId          uint64                 `protobuf:"varint,9,opt,name=nm_id,json=nmId,proto3" json:"nm_id,omitempty"`
+	Brand         string                 `protobuf:"bytes,10,opt,name=brand,proto3" json:"brand,omitempty"`
+	Status        uint32                 `protobuf:"varint,11,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Item) Reset() {
+	*x = Item{}
+	mi := &file_orders_v1_orders_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Item) ProtoMessage() {}
+
+func (x *Item) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Item.ProtoReflect.Descriptor instead.
+func (*Item) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Item) GetChrtId() uint64 {
+	if x != nil {
+		return x.ChrtId
+	}
+	return 0
+}
+
+func (x *Item) GetTrackNumber() string {
+	if x != nil {
+		return x.TrackNumber
+	}
+	return ""
+}
+
+func (x *Item) GetPrice() uint64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Item) GetRid() string {
+	if x != nil {
+		return x.Rid
+	}
+	return ""
+}
+
+func (x *Item) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Item) GetSale() uint64 {
+	if x != nil {
+		return x.Sale
+	}
+	return 0
+}
+
+func (x *Item) GetSize() string {
+	if x != nil {
+		return x.Size
+	}
+	return ""
+}
+
+func (x *Item) GetTotalPrice() uint64 {
+	if x != nil {
+		return x.TotalPrice
+	}
+	return 0
+}
+
+func (x *Item) GetNmId() uint64 {
+	if x != nil {
+		return x.NmId
+	}
+	return 0
+}
+
+func (x *Item) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *Item) GetStatus() uint32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+// Условия отбора заказов, как в параметрах поиска REST API
+type OrderFilter struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	CustomerId      string                 `protobuf:"bytes,1,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	TrackNumber     string                 `protobuf:"bytes,2,opt,name=track_number,json=trackNumber,proto3" json:"track_number,omitempty"`
+	DeliveryService string                 `protobuf:"bytes,3,opt,name=delivery_service,json=deliveryService,proto3" json:"delivery_service,omitempty"`
+	Locale          string                 `protobuf:"bytes,4,opt,name=locale,proto3" json:"locale,omitempty"`
+	// Нижняя граница date_created включительно
+	CreatedFrom *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	// Верхняя граница date_created не включительно
+	CreatedTo     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderFilter) Reset() {
+	*x = OrderFilter{}
+	mi := &file_orders_v1_orders_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderFilter) ProtoMessage() {}
+
+func (x *OrderFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderFilter.ProtoReflect.Descriptor instead.
+func (*OrderFilter) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *OrderFilter) GetCustomerId() string {
+	if x != nil {
+		return x.CustomerId
+	}
+	return ""
+}
+
+func (x *OrderFilter) GetTrackNumber() string {
+	if x != nil {
+		return x.TrackNumber
+	}
+	return ""
+}
+
+func (x *OrderFilter) GetDeliveryService() string {
+	if x != nil {
+		return x.DeliveryService
+	}
+	return ""
+}
+
+func (x *OrderFilter) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *OrderFilter) GetCreatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return nil
+}
+
+func (x *OrderFilter) GetCreatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return nil
+}
+
+type GetOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderUid      string                 `protobuf:"bytes,1,opt,name=order_uid,json=orderUid,proto3" json:"order_uid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderRequest) Reset() {
+	*x = GetOrderRequest{}
+	mi := &file_orders_v1_orders_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderRequest) ProtoMessage() {}
+
+func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetOrderRequest) GetOrderUid() string {
+	if x != nil {
+		return x.OrderUid
+	}
+	return ""
+}
+
+type BatchGetOrdersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderUids     []string               `protobuf:"bytes,1,rep,name=order_uids,json=orderUids,proto3" json:"order_uids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetOrdersRequest) Reset() {
+	*x = BatchGetOrdersRequest{}
+	mi := &file_orders_v1_orders_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetOrdersRequest) ProtoMessage() {}
+
+func (x *BatchGetOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetOrdersRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchGetOrdersRequest) GetOrderUids() []string {
+	if x != nil {
+		return x.OrderUids
+	}
+	return nil
+}
+
+type BatchGetOrdersResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Orders []*Order               `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	// UID, для которых заказ не найден
+	MissingUids   []string `protobuf:"bytes,2,rep,name=missing_uids,json=missingUids,proto3" json:"missing_uids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetOrdersResponse) Reset() {
+	*x = BatchGetOrdersResponse{}
+	mi := &file_orders_v1_orders_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetOrdersResponse) ProtoMessage() {}
+
+func (x *BatchGetOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetOrdersResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BatchGetOrdersResponse) GetOrders() []*Order {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+func (x *BatchGetOrdersResponse) GetMissingUids() []string {
+	if x != nil {
+		return x.MissingUids
+	}
+	return nil
+}
+
+type ListOrdersRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *OrderFilter           `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// Ограничение числа заказов, 0 - без ограничения
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOrdersRequest) Reset() {
+	*x = ListOrdersRequest{}
+	mi := &file_orders_v1_orders_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOrdersRequest) ProtoMessage() {}
+
+func (x *ListOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOrdersRequest.ProtoReflect.Descriptor instead.
+func (*ListOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListOrdersRequest) GetFilter() *OrderFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListOrdersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type WatchOrdersRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *OrderFilter           `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// Идентификатор последнего полученного события для продолжения без пропусков
+	LastEventId   uint64 `protobuf:"varint,2,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchOrdersRequest) Reset() {
+	*x = WatchOrdersRequest{}
+	mi := &file_orders_v1_orders_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOrdersRequest) ProtoMessage() {}
+
+func (x *WatchOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOrdersRequest.ProtoReflect.Descriptor instead.
+func (*WatchOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchOrdersRequest) GetFilter() *OrderFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *WatchOrdersRequest) GetLastEventId() uint64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+// Событие о заказе.
+// Тип stream.reset без заказа означает, что часть событий пропущена
+type OrderEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	Order         *Order                 `protobuf:"bytes,4,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderEvent) Reset() {
+	*x = OrderEvent{}
+	mi := &file_orders_v1_orders_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderEvent) ProtoMessage() {}
+
+func (x *OrderEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_orders_v1_orders_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderEvent.ProtoReflect.Descriptor instead.
+func (*OrderEvent) Descriptor() ([]byte, []int) {
+	return file_orders_v1_orders_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *OrderEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *OrderEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *OrderEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *OrderEvent) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+var File_orders_v1_orders_proto protoreflect.FileDescriptor
+
+const file_orders_v1_orders_proto_rawDesc = "" +
+	"\n" +
+	"\x16orders/v1/orders.proto\x12\torders.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x83\x04\n" +
+	"\x05Order\x12\x1b\n" +
+	"\torder_uid\x18\x01 \x01(\tR\borderUid\x12!\n" +
+	"\ftrack_number\x18\x02 \x01(\tR\vtrackNumber\x12\x14\n" +
+	"\x05entry\x18\x03 \x01(\tR\x05entry\x12\x16\n" +
+	"\x06locale\x18\x04 \x01(\tR\x06locale\x12-\n" +
+	"\x12internal_signature\x18\x05 \x01(\tR\x11internalSignature\x12\x1f\n" +
+	"\vcustomer_id\x18\x06 \x01(\tR\n" +
+	"customerId\x12)\n" +
+	"\x10delivery_service\x18\a \x01(\tR\x0fdeliveryService\x12\x1a\n" +
+	"\bshardkey\x18\b \x01(\tR\bshardkey\x12\x13\n" +
+	"\x05sm_id\x18\t \x01(\rR\x04smId\x12=\n" +
+	"\fdate_created\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\vdateCreated\x12\x1b\n" +
+	"\toof_shard\x18\v \x01(\tR\boofShard\x12/\n" +
+	"\bdelivery\x18\f \x01(\v2\x13.orders.v1.DeliveryR\bdelivery\x12,\n" +
+	"\apayment\x18\r \x01(\v2\x12.orders.v1.PaymentR\apayment\x12%\n" +
+	"\x05items\x18\x0e \x03(\v2\x0f.orders.v1.ItemR\x05items\"\xa2\x01\n" +
+	"\bDelivery\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05phone\x18\x02 \x01(\tR\x05phone\x12\x10\n" +
+	"\x03zip\x18\x03 \x01(\tR\x03zip\x12\x12\n" +
+	"\x04city\x18\x04 \x01(\tR\x04city\x12\x18\n" +
+	"\aaddress\x18\x05 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06region\x18\x06 \x01(\tR\x06region\x12\x14\n" +
+	"\x05email\x18\a \x01(\tR\x05email\"\x82\x03\n" +
+	"\aPayment\x12 \n" +
+	"\vtransaction\x18\x01 \x01(\tR\vtransaction\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12\x1a\n" +
+	"\bcurrency\x18\x03 \x01(\tR\bcurrency\x12\x1a\n" +
+	"\bprovider\x18\x04 \x01(\tR\bprovider\x12\x1b\n" +
+	"\x06amount\x18\x05 \x01(\x03H\x00R\x06amount\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"payment_dt\x18\x06 \x01(\x04R\tpaymentDt\x12\x12\n" +
+	"\x04bank\x18\a \x01(\tR\x04bank\x12(\n" +
+	"\rdelivery_cost\x18\b \x01(\x04H\x01R\fdeliveryCost\x88\x01\x01\x12$\n" +
+	"\vgoods_total\x18\t \x01(\x04H\x02R\n" +
+	"goodsTotal\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"custom_fee\x18\n" +
+	" \x01(\x04H\x03R\tcustomFee\x88\x01\x01B\t\n" +
+	"\a_amountB\x10\n" +
+	"\x0e_delivery_costB\x0e\n" +
+	"\f_goods_totalB\r\n" +
+	"\v_custom_fee\"\x8a\x02\n" +
+	"\x04Item\x12\x17\n" +
+	"\achrt_id\x18\x01 \x01(\x04R\x06chrtId\x12!\n" +
+	"\ftrack_number\x18\x02 \x01(\tR\vtrackNumber\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x04R\x05price\x12\x10\n" +
+	"\x03rid\x18\x04 \x01(\tR\x03rid\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\x12\x12\n" +
+	"\x04sale\x18\x06 \x01(\x04R\x04sale\x12\x12\n" +
+	"\x04size\x18\a \x01(\tR\x04size\x12\x1f\n" +
+	"\vtotal_price\x18\b \x01(\x04R\n" +
+	"totalPrice\x12\x13\n" +
+	"\x05nm_id\x18\t \x01(\x04R\x04nmId\x12\x14\n" +
+	"\x05brand\x18\n" +
+	" \x01(\tR\x05brand\x12\x16\n" +
+	"\x06status\x18\v \x01(\rR\x06status\"\x8e\x02\n" +
+	"\vOrderFilter\x12\x1f\n" +
+	"\vcustomer_id\x18\x01 \x01(\tR\n" +
+	"customerId\x12!\n" +
+	"\ftrack_number\x18\x02 \x01(\tR\vtrackNumber\x12)\n" +
+	"\x10delivery_service\x18\x03 \x01(\tR\x0fdeliveryService\x12\x16\n" +
+	"\x06locale\x18\x04 \x01(\tR\x06locale\x12=\n" +
+	"\fcreated_from\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedFrom\x129\n" +
+	"\n" +
+	"created_to\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedTo\".\n" +
+	"\x0fGetOrderRequest\x12\x1b\n" +
+	"\torder_uid\x18\x01 \x01(\tR\borderUid\"6\n" +
+	"\x15BatchGetOrdersRequest\x12\x1d\n" +
+	"\n" +
+	"order_uids\x18\x01 \x03(\tR\torderUids\"e\n" +
+	"\x16BatchGetOrdersResponse\x12(\n" +
+	"\x06orders\x18\x01 \x03(\v2\x10.orders.v1.OrderR\x06orders\x12!\n" +
+	"\fmissing_uids\x18\x02 \x03(\tR\vmissingUids\"Y\n" +
+	"\x11ListOrdersRequest\x12.\n" +
+	"\x06filter\x18\x01 \x01(\v2\x16.orders.v1.OrderFilterR\x06filter\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"h\n" +
+	"\x12WatchOrdersRequest\x12.\n" +
+	"\x06filter\x18\x01 \x01(\v2\x16.orders.v1.OrderFilterR\x06filter\x12\"\n" +
+	"\rlast_event_id\x18\x02 \x01(\x04R\vlastEventId\"\x88\x01\n" +
+	"\n" +
+	"OrderEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12&\n" +
+	"\x05order\x18\x04 \x01(\v2\x10.orders.v1.OrderR\x05order2\xa6\x02\n" +
+	"\fOrderService\x128\n" +
+	"\bGetOrder\x12\x1a.orders.v1.GetOrderRequest\x1a\x10.orders.v1.Order\x12U\n" +
+	"\x0eBatchGetOrders\x12 .orders.v1.BatchGetOrdersRequest\x1a!.orders.v1.BatchGetOrdersResponse\x12>\n" +
+	"\n" +
+	"ListOrders\x12\x1c.orders.v1.ListOrdersRequest\x1a\x10.orders.v1.Order0\x01\x12E\n" +
+	"\vWatchOrders\x12\x1d.orders.v1.WatchOrdersRequest\x1a\x15.orders.v1.OrderEvent0\x01BFZDgithub.com/venexene/wbl0-orders-service/api/proto/orders/v1;ordersv1b\x06proto3"
+
+var (
+	file_orders_v1_orders_proto_rawDescOnce sync.Once
+	file_orders_v1_orders_proto_rawDescData []byte
+)
+
+func file_orders_v1_orders_proto_rawDescGZIP() []byte {
+	file_orders_v1_orders_proto_rawDescOnce.Do(func() {
+		file_orders_v1_orders_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_orders_v1_orders_proto_rawDesc), len(file_orders_v1_orders_proto_rawDesc)))
+	})
+	return file_orders_v1_orders_proto_rawDescData
+}
+
+var file_orders_v1_orders_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_orders_v1_orders_proto_goTypes = []any{
+	(*Order)(nil),                  // 0: orders.v1.Order
+	(*Delivery)(nil),               // 1: orders.v1.Delivery
+	(*Payment)(nil),                // 2: orders.v1.Payment
+	(*Item)(nil),                   // 3: orders.v1.Item
+	(*OrderFilter)(nil),            // 4: orders.v1.OrderFilter
+	(*GetOrderRequest)(nil),        // 5: orders.v1.GetOrderRequest
+	(*BatchGetOrdersRequest)(nil),  // 6: orders.v1.BatchGetOrdersRequest
+	(*BatchGetOrdersResponse)(nil), // 7: orders.v1.BatchGetOrdersResponse
+	(*ListOrdersRequest)(nil),      // 8: orders.v1.ListOrdersRequest
+	(*WatchOrdersRequest)(nil),     // 9: orders.v1.WatchOrdersRequest
+	(*OrderEvent)(nil),             // 10: orders.v1.OrderEvent
+	(*timestamppb.Timestamp)(nil),  // 11: google.protobuf.Timestamp
+}
+var file_orders_v1_orders_proto_depIdxs = []int32{
+	11, // 0: orders.v1.Order.date_created:type_name -> google.protobuf.Timestamp
+	1,  // 1: orders.v1.Order.delivery:type_name -> orders.v1.Delivery
+	2,  // 2: orders.v1.Order.payment:type_name -> orders.v1.Payment
+	3,  // 3: orders.v1.Order.items:type_name -> orders.v1.Item
+	11, // 4: orders.v1.OrderFilter.created_from:type_name -> google.protobuf.Timestamp
+	11, // 5: orders.v1.OrderFilter.created_to:type_name -> google.protobuf.Timestamp
+	0,  // 6: orders.v1.BatchGetOrdersResponse.orders:type_name -> orders.v1.Order
+	4,  // 7: orders.v1.ListOrdersRequest.filter:type_name -> orders.v1.OrderFilter
+	4,  // 8: orders.v1.WatchOrdersRequest.filter:type_name -> orders.v1.OrderFilter
+	11, // 9: orders.v1.OrderEvent.time:type_name -> google.protobuf.Timestamp
+	0,  // 10: orders.v1.OrderEvent.order:type_name -> orders.v1.Order
+	5,  // 11: orders.v1.OrderService.GetOrder:input_type -> orders.v1.GetOrderRequest
+	6,  // 12: orders.v1.OrderService.BatchGetOrders:input_type -> orders.v1.BatchGetOrdersRequest
+	8,  // 13: orders.v1.OrderService.ListOrders:input_type -> orders.v1.ListOrdersRequest
+	9,  // 14: orders.v1.OrderService.WatchOrders:input_type -> orders.v1.WatchOrdersRequest
+	0,  // 15: orders.v1.OrderService.GetOrder:output_type -> orders.v1.Order
+	7,  // 16: orders.v1.OrderService.BatchGetOrders:output_type -> orders.v1.BatchGetOrdersResponse
+	0,  // 17: orders.v1.OrderService.ListOrders:output_type -> orders.v1.Order
+	10, // 18: orders.v1.OrderService.WatchOrders:output_type -> orders.v1.OrderEvent
+	15, // [15:19] is the sub-list for method output_type
+	11, // [11:15] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_orders_v1_orders_proto_init() }
+func file_orders_v1_orders_proto_init() {
+	if File_orders_v1_orders_proto != nil {
+		return
+	}
+	file_orders_v1_orders_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_orders_v1_orders_proto_rawDesc), len(file_orders_v1_orders_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_orders_v1_orders_proto_goTypes,
+		DependencyIndexes: file_orders_v1_orders_proto_depIdxs,
+		MessageInfos:      file_orders_v1_orders_proto_msgTypes,
+	}.Build()
+	File_orders_v1_orders_proto = out.File
+	file_orders_v1_orders_proto_goTypes = nil
+	file_orders_v1_orders_proto_depIdxs = nil
+}
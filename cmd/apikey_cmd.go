@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+)
+
+// Подкоманда apikey: генерация ключа или вычисление хэша существующего
+func runAPIKeyCommand(args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "generate":
+		key, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("key:  %s\nhash: %s\n", key, hash)
+		return nil
+
+	case len(args) == 2 && args[0] == "hash":
+		fmt.Println(auth.HashAPIKey(args[1]))
+		return nil
+
+	default:
+		return fmt.Errorf("Unknown apikey command, usage: apikey generate | apikey hash <key>")
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+)
+
+// Подкоманда config: вывод итоговой конфигурации со скрытыми секретами
+func runConfigCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("Unknown config command, usage: config print [flags]")
+	}
+
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		return err
+	}
+
+	return cfg.Masked().Print(os.Stdout)
+}
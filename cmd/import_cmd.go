@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/importer"
+)
+
+// Подкоманда import: пакетный импорт заказов из NDJSON или JSON массива.
+// После каждого пакета номер последней записи сохраняется в файл состояния,
+// с флагом -resume импорт продолжается с этого места
+func runImportCommand(args []string) error {
+	fset := flag.NewFlagSet("import", flag.ContinueOnError)
+	configPath := fset.String("config", "", "path to YAML config file")
+	dryRun := fset.Bool("dry-run", false, "validate and check duplicates without writing to the database")
+	batchSize := fset.Int("batch-size", 0, "number of orders in one transaction, defaults to import_batch_size")
+	reportPath := fset.String("report", "", "file for the per-line NDJSON report, empty writes to stdout")
+	statePath := fset.String("state", "", "file with the last imported line, defaults to <file>.import-state")
+	resume := fset.Bool("resume", false, "continue after the line saved in the state file")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: import [flags] <file.ndjson|file.json|->")
+		fset.PrintDefaults()
+	}
+
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		fset.Usage()
+		return fmt.Errorf("Expected exactly one input file")
+	}
+	inputPath := fset.Arg(0)
+
+	var configArgs []string
+	if *configPath != "" {
+		configArgs = []string{"-config", *configPath}
+	}
+	cfg, err := config.Load(configArgs)
+	if err != nil {
+		return err
+	}
+
+	options := importer.Options{BatchSize: cfg.ImportBatchSize, DryRun: *dryRun}
+	if *batchSize > 0 {
+		options.BatchSize = *batchSize
+	}
+
+	// Входные данные
+	var input io.Reader = os.Stdin
+	if inputPath != "-" {
+		file, err := os.Open(inputPath)
+		if err != nil {
+			return fmt.Errorf("Failed to open input: %v", err)
+		}
+		defer file.Close()
+		input = file
+
+		if *statePath == "" {
+			*statePath = inputPath + ".import-state"
+		}
+	}
+
+	// Продолжение после прерванного импорта
+	if *resume {
+		if *statePath == "" {
+			return fmt.Errorf("Flag -resume requires -state when reading from stdin")
+		}
+		line, err := readImportState(*statePath)
+		if err != nil {
+			return err
+		}
+		options.ResumeAfter = line
+		log.Printf("Resuming import after line %d", line)
+	}
+	if *statePath != "" && !*dryRun {
+		options.Checkpoint = func(line int) error {
+			return os.WriteFile(*statePath, []byte(strconv.Itoa(line)+"\n"), 0o644)
+		}
+	}
+
+	// Отчет по строкам
+	var report io.Writer = os.Stdout
+	if *reportPath != "" {
+		file, err := os.Create(*reportPath)
+		if err != nil {
+			return fmt.Errorf("Failed to create report: %v", err)
+		}
+		defer file.Close()
+		report = file
+	}
+	encoder := json.NewEncoder(report)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := database.CreatePool(cfg)
+	if err != nil {
+		return fmt.Errorf("Failed to connect database: %v", err)
+	}
+	defer pool.Close()
+	storage := database.NewStorage(pool)
+
+	summary, err := importer.New(storage, options).Run(ctx, input, func(entry importer.Entry) error {
+		return encoder.Encode(entry)
+	})
+	log.Printf("Import summary: %d accepted, %d duplicate, %d invalid, %d skipped, last line %d, dry run %t",
+		summary.Accepted, summary.Duplicate, summary.Invalid, summary.Skipped, summary.LastLine, summary.DryRun)
+	if err != nil {
+		if *statePath != "" && !*dryRun {
+			log.Printf("Run again with -resume to continue after line %d", summary.LastLine)
+		}
+		return err
+	}
+
+	// Файл состояния больше не нужен после успешного завершения
+	if *statePath != "" && !*dryRun {
+		if err := os.Remove(*statePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Error("Failed to remove import state", "err", err)
+		}
+	}
+	return nil
+}
+
+// Чтение номера последней импортированной записи
+func readImportState(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("Failed to read import state: %v", err)
+	}
+
+	line, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || line < 0 {
+		return 0, fmt.Errorf("Malformed import state in %s", path)
+	}
+	return line, nil
+}
@@ -3,29 +3,83 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
 	"github.com/venexene/wbl0-orders-service/internal/handlers"
 	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/compress"
 	"github.com/venexene/wbl0-orders-service/internal/config"
 	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/decoder"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/gql"
+	"github.com/venexene/wbl0-orders-service/internal/grpcserver"
 	"github.com/venexene/wbl0-orders-service/internal/kafka"
+	"github.com/venexene/wbl0-orders-service/internal/logger"
+	"github.com/venexene/wbl0-orders-service/internal/outbox"
+	"github.com/venexene/wbl0-orders-service/internal/ratelimit"
 )
 
 func main() {
+	args := os.Args[1:]
+
+	// Выполнение подкоманды для работы с конфигурацией
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfigCommand(args[1:]); err != nil {
+			log.Fatalf("Failed to run config command: %v", err)
+		}
+		return
+	}
+
+	// Выполнение подкоманды для работы с API ключами
+	if len(args) > 0 && args[0] == "apikey" {
+		if err := runAPIKeyCommand(args[1:]); err != nil {
+			log.Fatalf("Failed to run apikey command: %v", err)
+		}
+		return
+	}
+
+	// Выполнение подкоманды импорта заказов из файла
+	if len(args) > 0 && args[0] == "import" {
+		if err := runImportCommand(args[1:]); err != nil {
+			log.Fatalf("Failed to run import command: %v", err)
+		}
+		return
+	}
+
+	// Выполнение подкоманды повтора сообщений Kafka
+	if len(args) > 0 && args[0] == "replay" {
+		if err := runReplayCommand(args[1:]); err != nil {
+			log.Fatalf("Failed to run replay command: %v", err)
+		}
+		return
+	}
+
 	// Получение конфигураций
-	cfg, err := config.Load()
+	cfg, err := config.Load(args)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 	log.Println("Loaded config")
 
+	// Настройка уровня логирования
+	if err := logger.Setup(cfg.LogLevel); err != nil {
+		log.Fatalf("Failed to setup logger: %v", err)
+	}
+	// Отладочный вывод и ошибки gin тоже идут через slog и уровень из конфигурации
+	gin.DefaultWriter = logger.Writer(slog.LevelDebug)
+	gin.DefaultErrorWriter = logger.Writer(slog.LevelError)
+
 
 	// Создание контекста для получения сигнала о завершении
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -41,37 +95,111 @@ func main() {
 	storage := database.NewStorage(pool)
 	log.Println("Connected database")
 
+	// Очистка устаревших ключей идемпотентности
+	go storage.RunIdempotencyKeyPurge(ctx, time.Hour, cfg.IdempotencyKeyTTL)
+
 
 	// Создание кэша
 	cache := cache.NewCache(cfg.CacheCapacity)
+	cache.SetTTL(cfg.CacheTTL)
 	log.Println("Created cache")
 
 	// Заполнение кэша
 	if err := cache.Populate(context.Background(), storage); err != nil {
-		log.Printf("Failed to populate cache: %v", err)
+		slog.Error("Failed to populate cache", "err", err)
 	} else {
 		log.Printf("Populated cache with %d orders", cache.Size())
 	}
 	
+	// Создание ограничителя частоты запросов с очисткой неактивных клиентов
+	rateRules, err := ratelimit.RulesFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure rate limits: %v", err)
+	}
+	rateStore := ratelimit.NewMemoryStore()
+	limiter := ratelimit.NewLimiter(rateRules, rateStore)
+	// Устаревший маршрут заказа ограничивается вместе с /api/v1
+	limiter.Alias("GET /api/orders/:uid", "GET /api/v1/orders/:uid")
+	go rateStore.RunCleanup(ctx.Done(), time.Minute, 10*time.Minute)
+	log.Println("Created rate limiter")
+
+	// Применение перезагружаемых настроек по SIGHUP и через админский эндпоинт
+	reloader := config.NewReloader(cfg, args)
+	reloader.Subscribe(func(newCfg *config.Config) {
+		cache.Resize(newCfg.CacheCapacity)
+		cache.SetTTL(newCfg.CacheTTL)
+		if err := logger.SetLevel(newCfg.LogLevel); err != nil {
+			slog.Error("Failed to apply log level", "err", err)
+		}
+		// При ошибке в правилах продолжают действовать прежние
+		if rules, err := ratelimit.RulesFromConfig(newCfg); err != nil {
+			slog.Error("Failed to apply rate limits", "err", err)
+		} else {
+			limiter.Update(rules)
+		}
+	})
+	go reloader.WatchSignals(ctx)
+	log.Println("Started config reloader")
+
+	// Шина событий о новых заказах для потоковых подписчиков
+	bus := events.NewBus(cfg.StreamReplaySize)
+
 	// Создание консьюмера Kafka
-	kafkaConsumer := consumer.NewConsumer(
-		strings.Split(cfg.KafkaBrokers, ","),
-		cfg.KafkaTopic,
-		storage,
-		cache,
-	)
-	defer kafkaConsumer.Close()
+	decoders, err := decoder.FromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create message decoders: %v", err)
+	}
+	orderHandler := consumer.NewOrderHandler(storage, cache, bus, decoders)
+
+	// Маршрутизатор топиков; новые топики добавляются отдельными маршрутами со своими настройками
+	consumerMetrics := consumer.NewMetrics()
+	kafkaRouter := consumer.NewRouter(cfg, consumerMetrics)
+	if err := kafkaRouter.Handle(ordersRoute(cfg, orderHandler)); err != nil {
+		log.Fatalf("Failed to configure Kafka consumer: %v", err)
+	}
+	defer kafkaRouter.Close()
 	log.Println("Created Kafka consumer")
 
 	//Запуск консьюмера в горутине
 	go func() {
-		kafkaConsumer.Consume(context.Background())
+		if err := kafkaRouter.Run(ctx); err != nil {
+			slog.Error("Kafka consumer stopped", "err", err)
+		}
 	} ()
 	log.Printf("Started consume proccess for topic %s", cfg.KafkaTopic)
+
+	// Публикация событий о сохраненных заказах из outbox
+	relay := outbox.NewRelay(storage, outbox.NewWriter(cfg), cfg)
+	defer relay.Close()
+	go relay.Run(ctx)
+	log.Printf("Started outbox relay to topic %s", cfg.KafkaOutboxTopic)
 	
 
+	// Настройки сжатия ответов
+	compressOptions, err := compress.OptionsFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure compression: %v", err)
+	}
+
 	// Создание роутера
-	router := gin.Default()
+	router := gin.New()
+
+	// IP адрес клиента берется из X-Forwarded-For только от доверенных прокси,
+	// иначе клиент мог бы выбрать себе ведро ограничения частоты запросов
+	if err := router.SetTrustedProxies(cfg.TrustedProxies()); err != nil {
+		log.Fatalf("Failed to configure trusted proxies: %v", err)
+	}
+	router.Use(
+		handlers.AccessLog(),
+		handlers.RequestID(),
+		compress.Middleware(compressOptions),
+		gin.CustomRecovery(handlers.RecoveryHandle),
+	)
+
+	// Ошибки маршрутизации отдаются в формате application/problem+json
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(handlers.NoRouteHandle)
+	router.NoMethod(handlers.NoMethodHandle)
 	log.Printf("Created GIN router")
 
 	
@@ -79,8 +207,55 @@ func main() {
 	router.Static("/static", "./web/static") // Загрузка статических файлов
 
 
+	// Ограничение по IP адресу до аутентификации: запросы без учетных данных
+	// и перебор API ключей не доходят до проверки ключа в БД без ограничения
+	router.Use(ratelimit.IPMiddleware(limiter, handlers.DenyWithProblem))
+
+	// Подключение аутентификации, эндпоинты проверки состояния остаются открытыми
+	authenticators, err := auth.FromConfig(cfg, storage)
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+	router.Use(auth.Middleware(auth.MiddlewareConfig{
+		Authenticators: authenticators,
+		DefaultRole:    auth.Role(cfg.AuthDefaultRole),
+		AnonymousRole:  auth.Role(cfg.AuthAnonRole),
+		Deny:           handlers.DenyWithProblem,
+		ExemptPaths: []string{
+			"/api/server_check",
+			"/api/db_check",
+			"/api/kafka_check",
+			"/api/openapi.json",
+			"/api/docs",
+			"/static/",
+		},
+	}))
+	log.Printf("Configured authentication mode %s", cfg.AuthMode)
+
+	// Ограничение частоты запросов по API ключу, субъекту токена или IP адресу анонимного клиента
+	router.Use(ratelimit.Middleware(limiter, handlers.DenyWithProblem))
+
+
+	// Открытие журнала аудита доступа к персональным данным
+	auditLog, err := audit.Open(cfg.AuditLogPath)
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer auditLog.Close()
+
+
 	// Создание хендлера
-	handler := handlers.NewHandler(storage, cfg, cache)
+	handler := handlers.NewHandler(storage, cfg, cache, auditLog, bus)
+	adminHandler := handlers.NewAdminHandler(reloader, cfg, consumerMetrics, kafkaRouter)
+	streamHandler := handlers.NewStreamHandler(bus, cfg, auditLog)
+	liveHandler := handlers.NewLiveHandler(bus, cfg, auditLog)
+
+	// Создание схемы GraphQL поверх того же хранилища и кэша
+	graphqlSchema, err := gql.NewSchema(storage, cache)
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+	graphqlHandler := handlers.NewGraphQLHandler(graphqlSchema, cfg, auditLog)
 
 
     //Тестовый эндпоинт для проверки работы сервера
@@ -98,14 +273,91 @@ func main() {
 		handler.TestKafkaHandle(c)
 	})
 
-	//Эндпоинт для получения информации о заказе по UID
-	router.GET("/api/orders/:uid", func(c *gin.Context) {
-    	handler.GetOrderByUIDHandle(c)
+	// Эндпоинты спецификации OpenAPI и Swagger UI
+	router.GET("/api/openapi.json", handlers.OpenAPIHandle)
+	router.GET("/api/docs", handlers.DocsPageHandle)
+
+	// Версионированное API
+	v1 := router.Group("/api/v1")
+
+	// Эндпоинт для поиска заказов по фильтру
+	v1.GET("/orders", func(c *gin.Context) {
+		handler.SearchOrdersHandle(c)
+	})
+
+	// Эндпоинт для потоковой выгрузки заказов по тому же фильтру
+	v1.GET("/orders/export", func(c *gin.Context) {
+		handler.ExportOrdersHandle(c)
+	})
+
+	// Эндпоинт для потока новых заказов (Server-Sent Events)
+	v1.GET("/orders/stream", func(c *gin.Context) {
+		streamHandler.StreamOrdersHandle(c)
 	})
 
-	//Эндпоинт для получения UID всех заказов
-	router.GET("/api/all_orders_uids", func(c *gin.Context) {
-    	handler.GetAllOrdersUIDHandle(c)
+	// Эндпоинт WebSocket для живого обновления списка заказов на главной странице
+	v1.GET("/orders/live", func(c *gin.Context) {
+		liveHandler.LiveOrdersHandle(c)
+	})
+
+	// Эндпоинт для получения информации о заказе по UID
+	v1.GET("/orders/:uid", func(c *gin.Context) {
+		handler.GetOrderByUIDHandle(c)
+	})
+
+	// Эндпоинт для создания заказа, доступен поставщикам заказов (writer) и администраторам
+	v1.POST("/orders", auth.RequireRole(handlers.DenyWithProblem, auth.RoleWriter, auth.RoleAdmin), func(c *gin.Context) {
+		handler.CreateOrderHandle(c)
+	})
+
+	// Пользовательские методы коллекции заказов: /api/v1/orders:<метод>
+	v1.POST("/orders:method", handlers.CustomMethods(map[string]gin.HandlersChain{
+		"batchGet": {handler.BatchGetOrdersHandle},
+		"import":   {auth.RequireRole(handlers.DenyWithProblem, auth.RoleWriter, auth.RoleAdmin), handler.ImportOrdersHandle},
+	}))
+
+	// Эндпоинт для получения UID всех заказов
+	v1.GET("/order_uids", func(c *gin.Context) {
+		handler.GetAllOrdersUIDHandle(c)
+	})
+
+	// Устаревшие маршруты без версии, оставлены как псевдонимы /api/v1
+	sunset, _ := cfg.LegacySunsetTime()
+	deprecatedSince := config.LegacyDeprecatedSince
+
+	router.GET("/api/orders/:uid", handlers.Deprecated(deprecatedSince, sunset, func(c *gin.Context) string {
+		return "/api/v1/orders/" + c.Param("uid")
+	}), func(c *gin.Context) {
+		handler.GetOrderByUIDHandle(c)
+	})
+
+	router.GET("/api/all_orders_uids", handlers.Deprecated(deprecatedSince, sunset, func(c *gin.Context) string {
+		return "/api/v1/order_uids"
+	}), func(c *gin.Context) {
+		handler.GetAllOrdersUIDHandle(c)
+	})
+
+	// Эндпоинт GraphQL для выборочных запросов к заказам
+	router.GET("/graphql", func(c *gin.Context) {
+		graphqlHandler.GraphQLHandle(c)
+	})
+	router.POST("/graphql", func(c *gin.Context) {
+		graphqlHandler.GraphQLHandle(c)
+	})
+
+	// Эндпоинт для перезагрузки конфигурации
+	router.POST("/admin/config/reload", auth.RequireRole(handlers.DenyWithProblem, auth.RoleAdmin), func(c *gin.Context) {
+		adminHandler.ReloadConfigHandle(c)
+	})
+
+	// Эндпоинт для счетчиков обработки сообщений Kafka
+	router.GET("/admin/consumer/stats", auth.RequireRole(handlers.DenyWithProblem, auth.RoleAdmin), func(c *gin.Context) {
+		adminHandler.ConsumerStatsHandle(c)
+	})
+
+	// Эндпоинт для повтора сообщений топика с заданного смещения или времени
+	router.POST("/admin/consumer/replay", auth.RequireRole(handlers.DenyWithProblem, auth.RoleAdmin), func(c *gin.Context) {
+		adminHandler.ReplayHandle(c)
 	})
 
 	// Эндпоинт для основной страницы со списком заказов
@@ -120,9 +372,14 @@ func main() {
 
 	// Создание сервера
 	srv := &http.Server{
-		Addr:    ":" + cfg.HTTPPort,
-		Handler: router,
+		Addr:         ":" + cfg.HTTPPort,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
 	}
+	// Shutdown не отменяет контексты запросов, поэтому открытые потоки закрываются через шину
+	srv.RegisterOnShutdown(bus.Close)
 	log.Printf("Created server")
 
 
@@ -134,6 +391,27 @@ func main() {
 	}()
 	log.Printf("Started HTTP server on port %s", cfg.HTTPPort)
 
+	// Запуск gRPC сервера на отдельном порту с тем же хранилищем, кэшем и аутентификацией
+	var grpcServer *grpcserver.Server
+	if cfg.GRPCPort != "" {
+		grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			log.Fatalf("Failed to listen gRPC port: %v", err)
+		}
+		grpcServer = grpcserver.NewServer(grpcserver.NewOrderService(storage, cfg, cache, auditLog, bus), grpcserver.Options{
+			Authenticators: authenticators,
+			DefaultRole:    auth.Role(cfg.AuthDefaultRole),
+			AnonymousRole:  auth.Role(cfg.AuthAnonRole),
+			Reflection:     cfg.GRPCReflection,
+		})
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				log.Fatalf("gRPC server error: %v", err)
+			}
+		}()
+		log.Printf("Started gRPC server on port %s", cfg.GRPCPort)
+	}
+
 
 	// Ожидание сигнала завершения
 	<-ctx.Done()
@@ -141,12 +419,24 @@ func main() {
 	log.Println("Shutting down server...")
 	
 	//Создание контекста с таймаутом для корректного завершения
-	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 	
 	// Закрытие сервера
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		log.Fatalf("Failed to shutdown server: %v", err)
 	}
+
+	// Закрытие WebSocket соединений живого списка заказов
+	if err := liveHandler.Shutdown(ctxShutdown); err != nil {
+		slog.Error("Failed to close live connections", "err", err)
+	}
+
+	// Закрытие gRPC сервера, подписки WatchOrders уже завершены закрытием шины
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(ctxShutdown); err != nil {
+			slog.Error("Failed to gracefully stop gRPC server", "err", err)
+		}
+	}
 	log.Println("Shutdown server")
 }
\ No newline at end of file
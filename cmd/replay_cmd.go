@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/decoder"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	consumer "github.com/venexene/wbl0-orders-service/internal/kafka"
+)
+
+// Маршрут топика заказов, общий для сервиса и подкоманды replay
+func ordersRoute(cfg *config.Config, handler *consumer.OrderHandler) consumer.Route {
+	return consumer.Route{
+		Topic:        cfg.KafkaTopic,
+		GroupID:      cfg.KafkaGroupID,
+		Handler:      handler,
+		Validate:     handler.Validate,
+		Concurrency:  cfg.KafkaConcurrency,
+		Retries:      cfg.KafkaMaxRetries,
+		RetryBackoff: cfg.KafkaRetryBackoff,
+		DLQTopic:     cfg.KafkaDLQTopic,
+	}
+}
+
+// Подкоманда replay: повторная обработка сообщений топика заказов с заданного смещения или времени.
+// Сообщения читаются без группы консьюмеров, поэтому смещения работающего сервиса не меняются.
+// Результат по каждому сообщению выводится в NDJSON, итог по разделам в лог
+func runReplayCommand(args []string) error {
+	fset := flag.NewFlagSet("replay", flag.ContinueOnError)
+	configPath := fset.String("config", "", "path to YAML config file")
+	topic := fset.String("topic", "", "topic to replay, defaults to kafka_topic")
+	partition := fset.Int("partition", -1, "partition to replay, all partitions when negative")
+	offset := fset.Int64("offset", -1, "first offset to replay, requires -partition")
+	since := fset.String("since", "", "replay messages produced at or after this RFC 3339 time")
+	limit := fset.Int("limit", 0, "maximum number of messages, defaults to kafka_replay_max_messages")
+	dryRun := fset.Bool("dry-run", false, "report what would change without writing to the database or DLQ")
+	reportPath := fset.String("report", "", "file for the per-message NDJSON report, empty writes to stdout")
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: replay [flags] (-offset N -partition P | -since TIME)")
+		fset.PrintDefaults()
+	}
+
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 0 {
+		fset.Usage()
+		return fmt.Errorf("Unexpected arguments %v", fset.Args())
+	}
+
+	request := consumer.ReplayRequest{Topic: *topic, Limit: *limit, DryRun: *dryRun}
+	if *partition >= 0 {
+		request.Partition = partition
+	}
+	if *offset >= 0 {
+		request.Offset = offset
+	}
+	if *since != "" {
+		at, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return fmt.Errorf("Failed to parse -since: %v", err)
+		}
+		request.Since = &at
+	}
+
+	var configArgs []string
+	if *configPath != "" {
+		configArgs = []string{"-config", *configPath}
+	}
+	cfg, err := config.Load(configArgs)
+	if err != nil {
+		return err
+	}
+
+	// Отчет по сообщениям
+	var report io.Writer = os.Stdout
+	if *reportPath != "" {
+		file, err := os.Create(*reportPath)
+		if err != nil {
+			return fmt.Errorf("Failed to create report: %v", err)
+		}
+		defer file.Close()
+		report = file
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := database.CreatePool(cfg)
+	if err != nil {
+		return fmt.Errorf("Failed to connect database: %v", err)
+	}
+	defer pool.Close()
+	storage := database.NewStorage(pool)
+
+	decoders, err := decoder.FromConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("Failed to create message decoders: %v", err)
+	}
+
+	// Кэш и шина событий сервиса находятся в другом процессе, сервис загрузит новые заказы из БД
+	orderHandler := consumer.NewOrderHandler(storage, cache.NewCache(cfg.CacheCapacity), events.NewBus(0), decoders)
+	router := consumer.NewRouter(cfg, consumer.NewMetrics())
+	if err := router.Handle(ordersRoute(cfg, orderHandler)); err != nil {
+		return err
+	}
+	defer router.Close()
+
+	result, err := router.Replay(ctx, request)
+	if result != nil {
+		encoder := json.NewEncoder(report)
+		for _, entry := range result.Entries {
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("Failed to write report: %v", err)
+			}
+		}
+		for _, p := range result.Partitions {
+			log.Printf("Partition %d: offsets %d-%d, next offset %d", p.Partition, p.StartOffset, p.EndOffset, p.NextOffset)
+		}
+		log.Printf("Replay summary: %v, dry run %t", result.Outcomes, result.DryRun)
+	}
+	return err
+}
@@ -57,10 +57,58 @@ CREATE TABLE IF NOT EXISTS item (
 );
 
 
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    role VARCHAR(20) NOT NULL DEFAULT 'viewer' CHECK (role IN ('viewer', 'writer', 'support', 'finance', 'admin')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    subject VARCHAR(200) NOT NULL,
+    key VARCHAR(200) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    order_uid UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (subject, key)
+);
+
+
+CREATE TABLE IF NOT EXISTS processed_messages (
+    message_id VARCHAR(200) PRIMARY KEY,
+    order_uid UUID NOT NULL,
+    content_hash CHAR(32) NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    kafka_partition INTEGER NOT NULL,
+    kafka_offset BIGINT NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_key VARCHAR(200) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+
 CREATE INDEX IF NOT EXISTS idx_orders_order_uid ON orders(order_uid);
 CREATE INDEX IF NOT EXISTS idx_delivery_order_uid ON delivery(order_uid);
 CREATE INDEX IF NOT EXISTS idx_payment_order_uid ON payment(order_uid);
 CREATE INDEX IF NOT EXISTS idx_item_order_uid ON item(order_uid);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+CREATE INDEX IF NOT EXISTS idx_processed_messages_order_uid ON processed_messages(order_uid);
+CREATE INDEX IF NOT EXISTS idx_processed_messages_processed_at ON processed_messages(processed_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate_key ON outbox(aggregate_key, id);
+CREATE INDEX IF NOT EXISTS idx_outbox_next_attempt_at ON outbox(next_attempt_at, id);
 
 
 
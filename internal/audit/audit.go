@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Запись аудита о доступе к персональным данным
+type Record struct {
+	Time     time.Time `json:"time"`
+	Subject  string    `json:"subject"`
+	Method   string    `json:"method"`
+	Role     string    `json:"role"`
+	Action   string    `json:"action"`
+	OrderUID string    `json:"order_uid"`
+	Fields   []string  `json:"fields"`
+	ClientIP string    `json:"client_ip,omitempty"`
+	Path     string    `json:"path,omitempty"`
+}
+
+// Журнал аудита в формате JSON Lines
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// Конструктор журнала, пишущего в заданный поток
+func NewLogger(out io.Writer) *Logger {
+	return &Logger{out: out}
+}
+
+// Открытие журнала в файле, пустой путь означает стандартный вывод
+func Open(path string) (*Logger, error) {
+	if path == "" {
+		return NewLogger(os.Stdout), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open audit log %s: %v", path, err)
+	}
+
+	return &Logger{out: file, closer: file}, nil
+}
+
+// Запись события. Пустой журнал ничего не делает
+func (l *Logger) Log(record Record) {
+	if l == nil {
+		return
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		slog.Error("Failed to marshal audit record", "err", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.out.Write(append(data, '\n')); err != nil {
+		slog.Error("Failed to write audit record", "err", err)
+	}
+}
+
+// Закрытие файла журнала
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
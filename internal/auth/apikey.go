@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Ошибка отсутствия ключа в хранилище
+var ErrKeyNotFound = errors.New("api key not found")
+
+// Описание API ключа. Сам ключ не хранится, только его хэш
+type APIKey struct {
+	Name string
+	Hash string
+	Role Role
+}
+
+// Хранилище API ключей
+type KeyStore interface {
+	LookupAPIKey(ctx context.Context, hash string) (*APIKey, error)
+}
+
+// Вычисление хэша API ключа
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Генерация нового API ключа и его хэша
+func GenerateAPIKey() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("Failed to generate api key: %v", err)
+	}
+	key := base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
+
+// Хранилище ключей из конфигурации
+type StaticKeyStore struct {
+	keys map[string]*APIKey
+}
+
+// Разбор списка ключей вида name:sha256hex[:role] через запятую
+func ParseStaticKeys(raw string) (*StaticKeyStore, error) {
+	store := &StaticKeyStore{keys: make(map[string]*APIKey)}
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rest, found := strings.Cut(entry, ":")
+		if !found || name == "" {
+			return nil, fmt.Errorf("Failed to parse api key entry %q, expected name:sha256hex[:role]", entry)
+		}
+
+		hash, roleName, hasRole := strings.Cut(rest, ":")
+		var role Role
+		if hasRole {
+			parsed, err := ParseRole(roleName)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to parse api key %q: %v", name, err)
+			}
+			role = parsed
+		}
+
+		hash = strings.ToLower(hash)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("Failed to parse api key %q: hash must be sha256 hex", name)
+		}
+
+		store.keys[hash] = &APIKey{Name: name, Hash: hash, Role: role}
+	}
+
+	return store, nil
+}
+
+// Поиск ключа по хэшу
+func (s *StaticKeyStore) LookupAPIKey(ctx context.Context, hash string) (*APIKey, error) {
+	if key, exists := s.keys[hash]; exists {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Аутентификация по статическому API ключу
+type APIKeyAuthenticator struct {
+	stores []KeyStore
+}
+
+// Конструктор аутентификатора по API ключу.
+// Хранилища опрашиваются по порядку до первого совпадения
+func NewAPIKeyAuthenticator(stores ...KeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{stores: stores}
+}
+
+// Проверка API ключа из заголовка X-API-Key или Authorization: ApiKey
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		scheme, value, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if found && strings.EqualFold(scheme, "ApiKey") {
+			key = strings.TrimSpace(value)
+		}
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	hash := HashAPIKey(key)
+	for _, store := range a.stores {
+		apiKey, err := store.LookupAPIKey(r.Context(), hash)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to lookup api key: %w", err)
+		}
+		return &Principal{Subject: apiKey.Name, Method: MethodAPIKey, Role: apiKey.Role}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+}
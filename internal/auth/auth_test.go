@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Создание файла JWKS с одним RSA ключом
+func writeJWKS(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	t.Helper()
+
+	jwks := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+// Подписание токена с заданными утверждениями
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+// Тестирование проверки JWT
+func TestJWTAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	jwks, err := LoadJWKS(writeJWKS(t, key, "test-key"))
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+	authenticator := NewJWTAuthenticator(jwks, "https://issuer.test", "orders-api", 0)
+
+	valid := jwt.MapClaims{
+		"sub": "user-1",
+		"iss": "https://issuer.test",
+		"aud": "orders-api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", signToken(t, key, "test-key", valid), false},
+		{"expired", signToken(t, key, "test-key", jwt.MapClaims{"sub": "user-1", "iss": "https://issuer.test", "aud": "orders-api", "exp": time.Now().Add(-time.Hour).Unix()}), true},
+		{"wrong audience", signToken(t, key, "test-key", jwt.MapClaims{"sub": "user-1", "iss": "https://issuer.test", "aud": "other", "exp": time.Now().Add(time.Hour).Unix()}), true},
+		{"wrong issuer", signToken(t, key, "test-key", jwt.MapClaims{"sub": "user-1", "iss": "https://evil.test", "aud": "orders-api", "exp": time.Now().Add(time.Hour).Unix()}), true},
+		{"no expiry", signToken(t, key, "test-key", jwt.MapClaims{"sub": "user-1", "iss": "https://issuer.test", "aud": "orders-api"}), true},
+		{"unknown kid", signToken(t, key, "other-key", valid), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			principal, err := authenticator.Authenticate(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, but token was accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to authenticate: %v", err)
+			}
+			if principal.Subject != "user-1" || principal.Method != MethodJWT {
+				t.Errorf("Unexpected principal %+v", principal)
+			}
+		})
+	}
+}
+
+// Тестирование middleware с API ключами
+func TestMiddlewareAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	key, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	store, err := ParseStaticKeys("reports:" + hash)
+	if err != nil {
+		t.Fatalf("Failed to parse keys: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(Middleware(MiddlewareConfig{
+		Authenticators: []Authenticator{NewAPIKeyAuthenticator(store)},
+		ExemptPaths:    []string{"/api/server_check"},
+	}))
+	router.GET("/api/server_check", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/orders/:uid", func(c *gin.Context) {
+		principal, _ := PrincipalFromContext(c)
+		c.String(http.StatusOK, principal.Subject)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"exempt health", "/api/server_check", "", "", http.StatusOK},
+		{"no credentials", "/api/orders/1", "", "", http.StatusUnauthorized},
+		{"wrong key", "/api/orders/1", "X-API-Key", "wrong", http.StatusUnauthorized},
+		{"valid header", "/api/orders/1", "X-API-Key", key, http.StatusOK},
+		{"valid authorization", "/api/orders/1", "Authorization", "ApiKey " + key, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d for %s, but got %d", tt.want, tt.path, w.Code)
+			}
+		})
+	}
+}
+
+// Тестирование разбора списка ключей
+func TestParseStaticKeys(t *testing.T) {
+	if _, err := ParseStaticKeys("broken"); err == nil {
+		t.Error("Expected error for entry without hash")
+	}
+	if _, err := ParseStaticKeys("name:1234"); err == nil {
+		t.Error("Expected error for short hash")
+	}
+	if _, err := ParseStaticKeys(""); err != nil {
+		t.Errorf("Failed to parse empty list: %v", err)
+	}
+}
+
+// Тестирование ролей из ключей и токенов и проверки роли на маршруте
+func TestRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	adminKey, adminHash, _ := GenerateAPIKey()
+	supportKey, supportHash, _ := GenerateAPIKey()
+	writerKey, writerHash, _ := GenerateAPIKey()
+	plainKey, plainHash, _ := GenerateAPIKey()
+	store, err := ParseStaticKeys("ops:" + adminHash + ":admin,desk:" + supportHash + ":support,upstream:" + writerHash + ":writer,plain:" + plainHash)
+	if err != nil {
+		t.Fatalf("Failed to parse keys: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(Middleware(MiddlewareConfig{
+		Authenticators: []Authenticator{NewAPIKeyAuthenticator(store)},
+		DefaultRole:    RoleViewer,
+	}))
+	router.POST("/admin/config/reload", RequireRole(nil, RoleAdmin), func(c *gin.Context) {
+		c.String(http.StatusOK, string(RoleFromContext(c)))
+	})
+	router.POST("/api/v1/orders", RequireRole(nil, RoleWriter, RoleAdmin), func(c *gin.Context) {
+		c.String(http.StatusOK, string(RoleFromContext(c)))
+	})
+
+	// Поставщик заказов может писать, но не администрировать
+	tests := []struct {
+		path string
+		key  string
+		want int
+	}{
+		{"/admin/config/reload", adminKey, http.StatusOK},
+		{"/admin/config/reload", supportKey, http.StatusForbidden},
+		{"/admin/config/reload", plainKey, http.StatusForbidden},
+		{"/admin/config/reload", writerKey, http.StatusForbidden},
+		{"/api/v1/orders", writerKey, http.StatusOK},
+		{"/api/v1/orders", adminKey, http.StatusOK},
+		{"/api/v1/orders", supportKey, http.StatusForbidden},
+		{"/api/v1/orders", plainKey, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", tt.path, nil)
+		req.Header.Set("X-API-Key", tt.key)
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d, but got %d", tt.want, w.Code)
+		}
+	}
+
+	if _, err := ParseStaticKeys("ops:" + adminHash + ":root"); err == nil {
+		t.Error("Expected error for unknown role")
+	}
+
+	claims := jwt.MapClaims{"roles": []any{"viewer", "finance", "unknown"}}
+	if role := rolesFromClaims(claims); role != RoleFinance {
+		t.Errorf("Expected finance role from claims, but got %q", role)
+	}
+}
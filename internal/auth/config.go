@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+)
+
+// Создание способов аутентификации по конфигурации.
+// Хранилище ключей из БД используется вместе с ключами из конфигурации
+func FromConfig(cfg *config.Config, dbKeys KeyStore) ([]Authenticator, error) {
+	var authenticators []Authenticator
+
+	for _, mode := range strings.Split(cfg.AuthMode, ",") {
+		switch strings.TrimSpace(mode) {
+		case "none":
+			log.Println("Authentication is disabled, all routes are public")
+
+		case MethodAPIKey:
+			static, err := ParseStaticKeys(cfg.AuthAPIKeys)
+			if err != nil {
+				return nil, err
+			}
+			stores := []KeyStore{static}
+			if dbKeys != nil {
+				stores = append(stores, dbKeys)
+			}
+			authenticators = append(authenticators, NewAPIKeyAuthenticator(stores...))
+
+		case MethodJWT:
+			jwks, err := LoadJWKS(cfg.AuthJWKSFile)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, NewJWTAuthenticator(jwks, cfg.AuthJWTIssuer, cfg.AuthJWTAudience, cfg.AuthJWTLeeway))
+
+		default:
+			return nil, fmt.Errorf("Unknown auth mode %q", mode)
+		}
+	}
+
+	return authenticators, nil
+}
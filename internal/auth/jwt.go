@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Ключ из набора JWKS
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Набор открытых ключей для проверки подписи токенов
+type JWKS struct {
+	keys map[string]crypto.PublicKey
+}
+
+// Загрузка набора ключей из локального файла JWKS
+func LoadJWKS(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read JWKS file %s: %v", path, err)
+	}
+
+	var raw struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal JWKS: %v", err)
+	}
+
+	jwks := &JWKS{keys: make(map[string]crypto.PublicKey)}
+	for _, key := range raw.Keys {
+		// Ключи шифрования для проверки подписи не используются
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse JWK %q: %v", key.Kid, err)
+		}
+		jwks.keys[key.Kid] = publicKey
+	}
+
+	if len(jwks.keys) == 0 {
+		return nil, errors.New("JWKS contains no signing keys")
+	}
+
+	return jwks, nil
+}
+
+// Преобразование JWK в открытый ключ
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %v", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %v", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %v", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %v", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// Декодирование числа из base64url
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// Поиск ключа для проверки подписи токена
+func (j *JWKS) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, exists := j.keys[kid]; exists {
+		return key, nil
+	}
+
+	// Токен без kid допустим, если ключ в наборе единственный
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// Аутентификация по JWT
+type JWTAuthenticator struct {
+	jwks   *JWKS
+	parser *jwt.Parser
+}
+
+// Конструктор аутентификатора по JWT с проверкой издателя, аудитории и срока действия
+func NewJWTAuthenticator(jwks *JWKS, issuer, audience string, leeway time.Duration) *JWTAuthenticator {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	)
+
+	return &JWTAuthenticator{
+		jwks:   jwks,
+		parser: parser,
+	}
+}
+
+// Проверка токена из заголовка Authorization: Bearer
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, tokenStr, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(strings.TrimSpace(tokenStr), claims, a.jwks.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &Principal{Subject: subject, Method: MethodJWT, Role: rolesFromClaims(claims)}, nil
+}
+
+// Получение роли из утверждений role или roles
+func rolesFromClaims(claims jwt.MapClaims) Role {
+	var names []string
+	if role, ok := claims["role"].(string); ok {
+		names = append(names, role)
+	}
+	if roles, ok := claims["roles"].([]any); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return highestRole(names)
+}
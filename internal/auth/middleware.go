@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Способ проверки учетных данных запроса.
+// Возвращает ErrNoCredentials, если запрос не содержит подходящих данных
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Настройки middleware аутентификации
+type MiddlewareConfig struct {
+	// Способы аутентификации, проверяемые по порядку
+	Authenticators []Authenticator
+	// Пути и префиксы (с завершающим /), не требующие аутентификации
+	ExemptPaths []string
+	// Роль субъектов, у которых она не указана
+	DefaultRole Role
+	// Роль запросов без аутентификации, когда способы не настроены
+	AnonymousRole Role
+	// Формирование ответа с ошибкой
+	Deny DenyFunc
+}
+
+// Middleware аутентификации.
+// Без настроенных способов все запросы выполняются от анонимного субъекта
+func Middleware(cfg MiddlewareConfig) gin.HandlerFunc {
+	deny := cfg.Deny
+	if deny == nil {
+		deny = defaultDeny
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = RoleViewer
+	}
+	if cfg.AnonymousRole == "" {
+		cfg.AnonymousRole = RoleViewer
+	}
+
+	return func(c *gin.Context) {
+		if len(cfg.Authenticators) == 0 {
+			SetPrincipal(c, &Principal{Subject: MethodAnonymous, Method: MethodAnonymous, Role: cfg.AnonymousRole})
+			c.Next()
+			return
+		}
+		if isExempt(c.Request.URL.Path, cfg.ExemptPaths) {
+			SetPrincipal(c, &Principal{Subject: MethodAnonymous, Method: MethodAnonymous, Role: RoleViewer})
+			c.Next()
+			return
+		}
+
+		for _, authenticator := range cfg.Authenticators {
+			principal, err := authenticator.Authenticate(c.Request)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if errors.Is(err, ErrInvalidCredentials) {
+				slog.Warn("Rejected credentials", "path", c.Request.URL.Path, "err", err)
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				deny(c, http.StatusUnauthorized, "Invalid credentials")
+				return
+			}
+			if err != nil {
+				slog.Error("Failed to authenticate request", "path", c.Request.URL.Path, "err", err)
+				deny(c, http.StatusInternalServerError, "Failed to authenticate request")
+				return
+			}
+
+			if principal.Role == "" {
+				principal.Role = cfg.DefaultRole
+			}
+			SetPrincipal(c, principal)
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", `Bearer, ApiKey`)
+		deny(c, http.StatusUnauthorized, "Authentication required")
+	}
+}
+
+// Проверка, что путь не требует аутентификации
+func isExempt(path string, exempt []string) bool {
+	for _, p := range exempt {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
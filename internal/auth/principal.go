@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ключ, под которым аутентифицированный субъект хранится в контексте gin
+const principalKey = "auth.principal"
+
+// Способы аутентификации
+const (
+	MethodAnonymous = "anonymous"
+	MethodAPIKey    = "apikey"
+	MethodJWT       = "jwt"
+)
+
+// Ошибка отсутствия учетных данных в запросе
+var ErrNoCredentials = errors.New("no credentials provided")
+
+// Ошибка неверных учетных данных
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Аутентифицированный субъект запроса
+type Principal struct {
+	Subject string
+	Method  string
+	Role    Role
+}
+
+// Сохранение субъекта в контексте запроса
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+}
+
+// Получение субъекта из контекста запроса
+func PrincipalFromContext(c *gin.Context) (*Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
+
+// Получение роли субъекта запроса.
+// Без субъекта используется роль с наименьшими правами
+func RoleFromContext(c *gin.Context) Role {
+	if principal, ok := PrincipalFromContext(c); ok && principal.Role != "" {
+		return principal.Role
+	}
+	return RoleViewer
+}
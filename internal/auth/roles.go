@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Роль субъекта, определяющая доступ к маршрутам и видимость персональных данных
+type Role string
+
+const (
+	// Видит заказ без контактов клиента и платежных данных
+	RoleViewer Role = "viewer"
+	// Видит то же, что viewer, и может создавать и импортировать заказы.
+	// Роль для вышестоящих систем-поставщиков заказов без доступа к администрированию
+	RoleWriter Role = "writer"
+	// Видит статус и состав заказа, контакты и платеж частично скрыты
+	RoleSupport Role = "support"
+	// Видит платежные данные, контакты частично скрыты
+	RoleFinance Role = "finance"
+	// Видит все данные и имеет доступ к административным операциям
+	RoleAdmin Role = "admin"
+)
+
+// Роли в порядке возрастания привилегий
+var roleRanks = []Role{RoleViewer, RoleWriter, RoleSupport, RoleFinance, RoleAdmin}
+
+// Разбор названия роли
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if !slices.Contains(roleRanks, role) {
+		return "", fmt.Errorf("Unknown role %q", name)
+	}
+	return role, nil
+}
+
+// Выбор наиболее привилегированной из известных ролей
+func highestRole(names []string) Role {
+	best := -1
+	for _, name := range names {
+		if rank := slices.Index(roleRanks, Role(name)); rank > best {
+			best = rank
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return roleRanks[best]
+}
+
+// Функция формирования ответа с ошибкой доступа
+type DenyFunc func(c *gin.Context, status int, detail string)
+
+// Ответ с ошибкой по умолчанию
+func defaultDeny(c *gin.Context, status int, detail string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": detail})
+}
+
+// Middleware проверки роли субъекта
+func RequireRole(deny DenyFunc, roles ...Role) gin.HandlerFunc {
+	if deny == nil {
+		deny = defaultDeny
+	}
+
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFromContext(c)
+		if !ok || !slices.Contains(roles, principal.Role) {
+			deny(c, http.StatusForbidden, "Insufficient role for this operation")
+			return
+		}
+		c.Next()
+	}
+}
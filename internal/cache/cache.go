@@ -3,23 +3,39 @@ package cache
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/venexene/wbl0-orders-service/internal/database"
 	"github.com/venexene/wbl0-orders-service/internal/models"
 )
 
 type cacheNode struct {
-	key   string
-	value *models.Order
-	prev  *cacheNode
-	next  *cacheNode
+	key       string
+	value     *models.Order
+	etag      string
+	expiresAt time.Time
+	prev      *cacheNode
+	next      *cacheNode
+}
+
+// Запись кэша вместе с хэшем содержимого заказа
+type Entry struct {
+	Order *models.Order
+	// Хэш содержимого заказа, используемый как основа ETag
+	ETag string
+}
+
+// Вычисление хэша содержимого заказа, используемого как основа ETag
+func ContentHash(order *models.Order) string {
+	return models.ContentHash(order)
 }
 
 // Структура кэша
 type Cache struct {
 	capacity int
+	ttl      time.Duration
 	elems    map[string] *cacheNode
 	head     *cacheNode
 	tail     *cacheNode
@@ -43,6 +59,13 @@ func NewCache(capacity int) *Cache {
 	return &cache
 }
 
+// Установка времени жизни записей, 0 отключает устаревание
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
 
 
 // Добавление узла
@@ -76,18 +99,23 @@ func (c*Cache) popTail() *cacheNode {
 
 
 
-// Добавление данных в кэш
-func (c *Cache) Set(order *models.Order) {
+// Добавление данных в кэш.
+// Хэш содержимого вычисляется один раз при добавлении и возвращается вместе с записью
+func (c *Cache) Set(order *models.Order) Entry {
+	etag := ContentHash(order)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if n, exist := c.elems[order.OrderUID]; exist {
 		n.value = order
+		n.etag = etag
+		n.expiresAt = c.expiration()
 		c.moveToHead(n)
-		return
+		return Entry{Order: order, ETag: etag}
 	}
 
-	n := &cacheNode{key: order.OrderUID, value: order}
+	n := &cacheNode{key: order.OrderUID, value: order, etag: etag, expiresAt: c.expiration()}
 	c.elems[order.OrderUID] = n
 	c.addNode(n)
 
@@ -95,26 +123,71 @@ func (c *Cache) Set(order *models.Order) {
 		tail := c.popTail()
 		delete(c.elems, tail.key)
 	}
+	return Entry{Order: order, ETag: etag}
 }
 
 // Получение данных из кэша
 func (c *Cache) Get(key string) (*models.Order, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	entry, exist := c.GetEntry(key)
+	return entry.Order, exist
+}
+
+// Получение записи кэша вместе с хэшем содержимого.
+// Используется полная блокировка, так как чтение меняет порядок списка
+func (c *Cache) GetEntry(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	
 	if n, exist := c.elems[key]; exist {
+		// Устаревшая запись удаляется при обращении
+		if !n.expiresAt.IsZero() && time.Now().After(n.expiresAt) {
+			c.removeNode(n)
+			delete(c.elems, key)
+			return Entry{}, false
+		}
 		c.moveToHead(n)
-		return n.value, true
+		return Entry{Order: n.value, ETag: n.etag}, true
 	}
 
-	return nil, false
+	return Entry{}, false
 }
 
 // Удаление из кэша
 func (c *Cache) Delete(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	delete(c.elems, key)
+	if n, exist := c.elems[key]; exist {
+		c.removeNode(n)
+		delete(c.elems, key)
+	}
+}
+
+// Изменение емкости кэша без его очистки.
+// При уменьшении вытесняются самые давно использованные записи
+func (c *Cache) Resize(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	for len(c.elems) > c.capacity {
+		tail := c.popTail()
+		delete(c.elems, tail.key)
+	}
+}
+
+// Получение емкости кэша
+func (c *Cache) Capacity() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.capacity
+}
+
+// Вычисление момента устаревания новой записи
+func (c *Cache) expiration() time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.ttl)
 }
 
 // Получение UID всех заказов в кэше
@@ -137,7 +210,7 @@ func (c *Cache) Size() int {
 
 // Заполнение кэша
 func (c *Cache) Populate(ctx context.Context, storage *database.Storage) error {
-	uids, err := storage.GetRecentOrdersUID(ctx, c.capacity)
+	uids, err := storage.GetRecentOrdersUID(ctx, c.Capacity())
 	if err != nil {
 		return fmt.Errorf("Failed to get recent orders: %v", err)
 	}
@@ -146,7 +219,7 @@ func (c *Cache) Populate(ctx context.Context, storage *database.Storage) error {
 	for _, uid := range uids {
 		order, err := storage.GetOrderByUID(ctx, uid)
 		if err != nil {
-			log.Printf("Failed to load order %s into cache: %v", uid, err)
+			slog.Error("Failed to load order into cache", "order_uid", uid, "err", err)
 			continue
 		}
 		c.Set(order)
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"testing"
+	"time"
 
 	"github.com/venexene/wbl0-orders-service/internal/models"
 )
@@ -103,4 +104,83 @@ func TestCacheDelete(t *testing.T) {
 	if _, exist := cache.Get(""); exist {
 		t.Error("Failed to delete order from cache")
 	}
-}
\ No newline at end of file
+}
+
+// Тестирование устаревания записей
+func TestCacheTTL(t *testing.T) {
+	cache := NewCache(2)
+	cache.SetTTL(20 * time.Millisecond)
+
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Errorf("Failed to load order1 from file: %v", err)
+	}
+
+	cache.Set(order)
+	if _, exist := cache.Get(order.OrderUID); !exist {
+		t.Error("Failed to get fresh order from cache")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, exist := cache.Get(order.OrderUID); exist {
+		t.Error("Failed to expire order in cache")
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected empty cache, but got %d", cache.Size())
+	}
+}
+
+// Тестирование изменения емкости без очистки
+func TestCacheResize(t *testing.T) {
+	cache := NewCache(3)
+
+	for _, path := range []string{"../../testdata/order1.json", "../../testdata/order2.json", "../../testdata/order3.json"} {
+		order, err := models.LoadOrderFromFile(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", path, err)
+		}
+		cache.Set(order)
+	}
+
+	cache.Resize(1)
+	if cache.Size() != 1 {
+		t.Errorf("Expected 1 order after shrinking, but got %d", cache.Size())
+	}
+	if _, exist := cache.Get("4321b7f1-c455-4300-bfdc-d339429c2099"); !exist {
+		t.Error("Failed to keep most recent order after shrinking")
+	}
+
+	cache.Resize(5)
+	if cache.Capacity() != 5 || cache.Size() != 1 {
+		t.Errorf("Unexpected capacity %d or size %d after growing", cache.Capacity(), cache.Size())
+	}
+}
+
+// Тестирование хэша содержимого, хранимого вместе с записью
+func TestCacheContentHash(t *testing.T) {
+	cache := NewCache(2)
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+
+	stored := cache.Set(order)
+	entry, exist := cache.GetEntry(order.OrderUID)
+	if !exist || entry.ETag == "" || entry.ETag != stored.ETag {
+		t.Fatalf("Expected stored hash %q, but got %q", stored.ETag, entry.ETag)
+	}
+
+	// Заказ из БД приходит в локальной зоне, но содержимое не меняется
+	local := *order
+	local.DateCreated = order.DateCreated.In(time.FixedZone("MSK", 3*60*60))
+	if hash := ContentHash(&local); hash != stored.ETag {
+		t.Errorf("Expected hash %q for the same order in another zone, but got %q", stored.ETag, hash)
+	}
+
+	changed := *order
+	changed.TrackNumber = "CHANGED"
+	if hash := cache.Set(&changed).ETag; hash == stored.ETag {
+		t.Error("Expected hash to change with order content")
+	}
+}
@@ -0,0 +1,263 @@
+// Пакет compress сжимает ответы по заголовку Accept-Encoding
+package compress
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+)
+
+// Поддерживаемые кодировки
+const (
+	Gzip   = "gzip"
+	Brotli = "br"
+	Zstd   = "zstd"
+)
+
+// Сжимающий поток, переиспользуемый между ответами
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Пулы сжимающих потоков по кодировкам
+var encoders = map[string]*sync.Pool{
+	Gzip: {New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	Brotli: {New: func() any {
+		// Уровень 4 заметно быстрее уровня по умолчанию при близкой степени сжатия
+		return brotli.NewWriterLevel(nil, 4)
+	}},
+	Zstd: {New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return w
+	}},
+}
+
+// Настройки сжатия
+type Options struct {
+	// Кодировки в порядке предпочтения сервера, пустой список отключает сжатие
+	Encodings []string
+	// Ответы меньше этого размера отправляются без сжатия
+	MinBytes int
+}
+
+// Разбор списка кодировок через запятую
+func ParseEncodings(raw string) ([]string, error) {
+	var encodings []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, known := encoders[name]; !known {
+			return nil, fmt.Errorf("Failed to parse encodings: unknown encoding %q", name)
+		}
+		encodings = append(encodings, name)
+	}
+	return encodings, nil
+}
+
+// Формирование настроек из конфигурации
+func OptionsFromConfig(cfg *config.Config) (Options, error) {
+	encodings, err := ParseEncodings(cfg.HTTPCompressEncodings)
+	if err != nil {
+		return Options{}, err
+	}
+	return Options{Encodings: encodings, MinBytes: cfg.HTTPCompressMinBytes}, nil
+}
+
+// Выбор кодировки по Accept-Encoding с учетом весов q.
+// При равных весах побеждает порядок предпочтения сервера
+func Negotiate(header string, offered []string) string {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		weights[name] = parseQuality(params)
+	}
+
+	best, bestWeight := "", 0.0
+	for _, name := range offered {
+		weight, listed := weights[name]
+		if !listed {
+			weight = weights["*"]
+		}
+		if weight > bestWeight {
+			best, bestWeight = name, weight
+		}
+	}
+	return best
+}
+
+// Разбор веса q из параметров элемента заголовка, по умолчанию 1
+func parseQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(key, "q") {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 {
+				return 0
+			}
+			return min(q, 1)
+		}
+	}
+	return 1
+}
+
+// Проверка, имеет ли смысл сжимать содержимое этого типа
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.Contains(mediaType, "json") ||
+		strings.Contains(mediaType, "xml") ||
+		strings.Contains(mediaType, "javascript")
+}
+
+// Middleware сжатия ответов
+func Middleware(opts Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(opts.Encodings) == 0 || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		encoding := Negotiate(c.GetHeader("Accept-Encoding"), opts.Encodings)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		w := &writer{ResponseWriter: c.Writer, encoding: encoding, minBytes: opts.MinBytes}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// Обертка ответа, решающая о сжатии по первым minBytes байтам тела
+type writer struct {
+	gin.ResponseWriter
+	encoding string
+	minBytes int
+	buf      []byte
+	decided  bool
+	enc      encoder
+}
+
+func (w *writer) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minBytes {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Потоковые ответы сжимаются независимо от размера, чтобы не копить их в буфере
+func (w *writer) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *writer) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Выбор между сжатием и передачей как есть с отправкой накопленного буфера
+func (w *writer) decide(large bool) error {
+	w.decided = true
+
+	header := w.Header()
+	status := w.Status()
+	eligible := !w.ResponseWriter.Written() &&
+		status != http.StatusPartialContent && status != http.StatusNoContent && status != http.StatusNotModified &&
+		header.Get("Content-Encoding") == "" && header.Get("Content-Range") == "" &&
+		compressible(header.Get("Content-Type"))
+	if eligible {
+		// Короткий ответ того же ресурса может быть сжат в другой раз
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if eligible && large {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		// Сжатое представление отличается побайтно, поэтому строгий тег становится слабым
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		w.enc = encoders[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// Завершение ответа: отправка короткого тела без сжатия и возврат потока в пул
+func (w *writer) close() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minBytes && len(w.buf) > 0)
+	}
+	if w.enc == nil {
+		return
+	}
+	w.enc.Close()
+	w.enc.Reset(nil)
+	encoders[w.encoding].Put(w.enc)
+	w.enc = nil
+}
@@ -0,0 +1,160 @@
+package compress
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Тестирование выбора кодировки по Accept-Encoding
+func TestNegotiate(t *testing.T) {
+	offered := []string{Zstd, Brotli, Gzip}
+
+	cases := map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip":                      Gzip,
+		"gzip, deflate, br":         Brotli,
+		"gzip, deflate, br, zstd":   Zstd,
+		"br;q=0.5, gzip":            Gzip,
+		"zstd;q=0, br;q=0.1":        Brotli,
+		"*":                         Zstd,
+		"*;q=0.5, zstd;q=0":         Brotli,
+		"GZIP;Q=1":                  Gzip,
+		"gzip;q=invalid, br;q=0.01": Brotli,
+	}
+
+	for header, expected := range cases {
+		if got := Negotiate(header, offered); got != expected {
+			t.Errorf("Negotiate(%q): expected %q, but got %q", header, expected, got)
+		}
+	}
+}
+
+// Тестирование разбора списка кодировок
+func TestParseEncodings(t *testing.T) {
+	encodings, err := ParseEncodings(" zstd, BR ,gzip,")
+	if err != nil || strings.Join(encodings, ",") != "zstd,br,gzip" {
+		t.Errorf("Unexpected encodings %v: %v", encodings, err)
+	}
+	if _, err := ParseEncodings("gzip,deflate"); err == nil {
+		t.Error("Expected error for unknown encoding")
+	}
+}
+
+// Распаковка тела ответа в указанной кодировке
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case Gzip:
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatalf("Failed to open gzip stream: %v", err)
+		}
+		r = gz
+	case Brotli:
+		r = brotli.NewReader(body)
+	case Zstd:
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatalf("Failed to open zstd stream: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		r = body
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decode %s body: %v", encoding, err)
+	}
+	return string(data)
+}
+
+// Тестирование сжатия ответов middleware
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	large := strings.Repeat(`{"order_uid":"b563feb7b2b84b6test"}`, 100)
+	router := gin.New()
+	router.Use(Middleware(Options{Encodings: []string{Zstd, Brotli, Gzip}, MinBytes: 256}))
+	router.GET("/large", func(c *gin.Context) {
+		c.Header("ETag", `"tag"`)
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(`{}`))
+	})
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(large))
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		for i := 0; i < 3; i++ {
+			c.Writer.WriteString("{}\n")
+			c.Writer.Flush()
+		}
+	})
+	router.GET("/not-modified", func(c *gin.Context) {
+		c.Status(http.StatusNotModified)
+	})
+
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, encoding := range []string{Zstd, Brotli, Gzip} {
+		t.Run(encoding, func(t *testing.T) {
+			w := request("/large", encoding)
+			if got := w.Header().Get("Content-Encoding"); got != encoding {
+				t.Fatalf("Expected Content-Encoding %s, but got %q", encoding, got)
+			}
+			if w.Body.Len() >= len(large) {
+				t.Errorf("Expected compressed body, but got %d bytes", w.Body.Len())
+			}
+			if body := decode(t, encoding, w.Body); body != large {
+				t.Errorf("Decoded body differs from original")
+			}
+			if got := w.Header().Get("ETag"); got != `W/"tag"` {
+				t.Errorf("Expected weak ETag, but got %q", got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Expected Vary Accept-Encoding, but got %q", got)
+			}
+		})
+	}
+
+	if w := request("/large", ""); w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+		t.Error("Expected uncompressed response without Accept-Encoding")
+	}
+	if w := request("/small", "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{}` {
+		t.Error("Expected small response to be sent as is")
+	} else if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Error("Expected Vary on small compressible response")
+	}
+	if w := request("/image", "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+		t.Error("Expected image to be sent as is")
+	}
+	if w := request("/stream", "gzip"); w.Header().Get("Content-Encoding") != Gzip || decode(t, Gzip, w.Body) != "{}\n{}\n{}\n" {
+		t.Error("Expected flushed stream to be compressed")
+	}
+	if w := request("/not-modified", "gzip"); w.Code != http.StatusNotModified || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected plain 304, but got %d with %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+}
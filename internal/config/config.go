@@ -1,50 +1,702 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-// Структура для определения всех конфигураций
+// Структура для определения всех конфигураций.
+// Каждое поле описывается тегами: yaml - ключ в файле конфигурации,
+// env - переменная окружения, flag - флаг командной строки,
+// secret - признак значения, которое маскируется при выводе,
+// file - значение можно прочитать из файла, путь к которому задан в <env>_FILE,
+// reload - значение применяется без перезапуска по SIGHUP или через админский эндпоинт
 type Config struct {
-	HTTPPort      string
-    CacheCapacity int
-    DBHost        string
-    DBPort        string
-    DBUser        string
-    DBPass        string
-    DBName        string
-    DBSSLMode     string
-    KafkaBrokers  string
-    KafkaTopic    string
-}
-
-func Load() (*Config, error) {
-	// Загрузка файла .env
-	if err := godotenv.Load(); err != nil {
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL" flag:"log-level" usage:"log level: debug, info, warn or error" reload:"true"`
+
+	HTTPPort         string        `yaml:"http_port" env:"HTTP_PORT" flag:"http-port" usage:"HTTP server port"`
+	HTTPReadTimeout  time.Duration `yaml:"http_read_timeout" env:"HTTP_READ_TIMEOUT" flag:"http-read-timeout" usage:"HTTP request read timeout"`
+	HTTPWriteTimeout time.Duration `yaml:"http_write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"http-write-timeout" usage:"HTTP response write timeout"`
+	HTTPIdleTimeout  time.Duration `yaml:"http_idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout" usage:"HTTP keep-alive idle timeout"`
+	ShutdownTimeout  time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"graceful shutdown timeout"`
+	HTTPMaxBodyBytes int64         `yaml:"http_max_body_bytes" env:"HTTP_MAX_BODY_BYTES" flag:"http-max-body-bytes" usage:"maximum size of a request body in bytes"`
+	BatchGetMaxUIDs  int           `yaml:"batch_get_max_uids" env:"BATCH_GET_MAX_UIDS" flag:"batch-get-max-uids" usage:"maximum number of UIDs in one orders:batchGet request"`
+	ImportBatchSize  int           `yaml:"import_batch_size" env:"IMPORT_BATCH_SIZE" flag:"import-batch-size" usage:"number of orders inserted in one import transaction"`
+	ImportMaxBytes   int64         `yaml:"import_max_bytes" env:"IMPORT_MAX_BYTES" flag:"import-max-bytes" usage:"maximum size of an orders:import request body in bytes"`
+	LegacyAPISunset  string        `yaml:"legacy_api_sunset" env:"LEGACY_API_SUNSET" flag:"legacy-api-sunset" usage:"date (YYYY-MM-DD) after which unversioned /api routes are removed"`
+
+	HTTPTrustedProxies string `yaml:"http_trusted_proxies" env:"HTTP_TRUSTED_PROXIES" flag:"http-trusted-proxies" usage:"comma separated proxy IPs or CIDRs whose X-Forwarded-For is trusted, empty trusts none"`
+
+	HTTPCompressEncodings string `yaml:"http_compress_encodings" env:"HTTP_COMPRESS_ENCODINGS" flag:"http-compress-encodings" usage:"response encodings in order of preference (zstd, br, gzip), empty disables compression"`
+	HTTPCompressMinBytes  int    `yaml:"http_compress_min_bytes" env:"HTTP_COMPRESS_MIN_BYTES" flag:"http-compress-min-bytes" usage:"minimum response size in bytes to compress"`
+
+	GRPCPort       string `yaml:"grpc_port" env:"GRPC_PORT" flag:"grpc-port" usage:"gRPC server port, empty disables the gRPC API"`
+	GRPCReflection bool   `yaml:"grpc_reflection" env:"GRPC_REFLECTION" flag:"grpc-reflection" usage:"enable gRPC server reflection"`
+
+	AuthMode        string        `yaml:"auth_mode" env:"AUTH_MODE" flag:"auth-mode" usage:"authentication modes: none, apikey, jwt or apikey,jwt"`
+	AuthAPIKeys     string        `yaml:"auth_api_keys" env:"AUTH_API_KEYS" flag:"auth-api-keys" usage:"comma separated name:sha256hex api key hashes" secret:"true" file:"true"`
+	AuthJWKSFile    string        `yaml:"auth_jwks_file" env:"AUTH_JWKS_FILE" flag:"auth-jwks-file" usage:"path to local JWKS file for JWT verification"`
+	AuthJWTIssuer   string        `yaml:"auth_jwt_issuer" env:"AUTH_JWT_ISSUER" flag:"auth-jwt-issuer" usage:"expected JWT issuer"`
+	AuthJWTAudience string        `yaml:"auth_jwt_audience" env:"AUTH_JWT_AUDIENCE" flag:"auth-jwt-audience" usage:"expected JWT audience"`
+	AuthJWTLeeway   time.Duration `yaml:"auth_jwt_leeway" env:"AUTH_JWT_LEEWAY" flag:"auth-jwt-leeway" usage:"allowed clock skew for JWT expiry checks"`
+	AuthDefaultRole string        `yaml:"auth_default_role" env:"AUTH_DEFAULT_ROLE" flag:"auth-default-role" usage:"role of authenticated clients without an explicit role"`
+	AuthAnonRole    string        `yaml:"auth_anonymous_role" env:"AUTH_ANONYMOUS_ROLE" flag:"auth-anonymous-role" usage:"role of all requests when auth_mode is none"`
+	AuditLogPath    string        `yaml:"audit_log_path" env:"AUDIT_LOG_PATH" flag:"audit-log-path" usage:"file for PII access audit records, empty writes to stdout"`
+
+	IdempotencyKeyTTL time.Duration `yaml:"idempotency_key_ttl" env:"IDEMPOTENCY_KEY_TTL" flag:"idempotency-key-ttl" usage:"how long Idempotency-Key results of order creation are kept"`
+
+	StreamBufferSize int           `yaml:"stream_buffer_size" env:"STREAM_BUFFER_SIZE" flag:"stream-buffer-size" usage:"events buffered per stream client before it is disconnected"`
+	StreamReplaySize int           `yaml:"stream_replay_size" env:"STREAM_REPLAY_SIZE" flag:"stream-replay-size" usage:"number of recent events kept for Last-Event-ID resume"`
+	StreamHeartbeat  time.Duration `yaml:"stream_heartbeat" env:"STREAM_HEARTBEAT" flag:"stream-heartbeat" usage:"interval between heartbeat comments on idle streams"`
+
+	RateLimitRPS    float64 `yaml:"rate_limit_rps" env:"RATE_LIMIT_RPS" flag:"rate-limit-rps" usage:"default requests per second per client, 0 disables limiting" reload:"true"`
+	RateLimitBurst  int     `yaml:"rate_limit_burst" env:"RATE_LIMIT_BURST" flag:"rate-limit-burst" usage:"default burst size per client" reload:"true"`
+	RateLimitRoutes string  `yaml:"rate_limit_routes" env:"RATE_LIMIT_ROUTES" flag:"rate-limit-routes" usage:"per-route limits: \"GET /api/v1/orders/:uid=5/10,...\"" reload:"true"`
+
+	RateLimitIPRPS   float64 `yaml:"rate_limit_ip_rps" env:"RATE_LIMIT_IP_RPS" flag:"rate-limit-ip-rps" usage:"requests per second per IP address checked before authentication, 0 disables limiting" reload:"true"`
+	RateLimitIPBurst int     `yaml:"rate_limit_ip_burst" env:"RATE_LIMIT_IP_BURST" flag:"rate-limit-ip-burst" usage:"burst size per IP address before authentication" reload:"true"`
+
+	CacheCapacity int           `yaml:"cache_capacity" env:"CACHE_CAPACITY" flag:"cache-capacity" usage:"maximum number of orders in cache" reload:"true"`
+	CacheTTL      time.Duration `yaml:"cache_ttl" env:"CACHE_TTL" flag:"cache-ttl" usage:"cache entry lifetime, 0 disables expiration" reload:"true"`
+
+	OrderCacheMaxAge time.Duration `yaml:"order_cache_max_age" env:"ORDER_CACHE_MAX_AGE" flag:"order-cache-max-age" usage:"Cache-Control max-age of order responses, 0 requires revalidation"`
+
+	DatabaseURL         string        `yaml:"database_url" env:"DATABASE_URL" flag:"database-url" usage:"full database DSN, overrides db_* connection settings" secret:"url" file:"true"`
+	DBHost              string        `yaml:"db_host" env:"DB_HOST" flag:"db-host" usage:"database host"`
+	DBPort              string        `yaml:"db_port" env:"DB_PORT" flag:"db-port" usage:"database port"`
+	DBUser              string        `yaml:"db_user" env:"DB_USER" flag:"db-user" usage:"database user" file:"true"`
+	DBPass              string        `yaml:"db_password" env:"DB_PASSWORD" flag:"db-password" usage:"database password" secret:"true" file:"true"`
+	DBName              string        `yaml:"db_name" env:"DB_NAME" flag:"db-name" usage:"database name"`
+	DBSSLMode           string        `yaml:"db_ssl_mode" env:"DB_SSL_MODE" flag:"db-ssl-mode" usage:"database SSL mode"`
+	DBMaxConns          int32         `yaml:"db_max_conns" env:"DB_MAX_CONNS" flag:"db-max-conns" usage:"maximum size of database pool"`
+	DBMinConns          int32         `yaml:"db_min_conns" env:"DB_MIN_CONNS" flag:"db-min-conns" usage:"minimum size of database pool"`
+	DBConnectTimeout    time.Duration `yaml:"db_connect_timeout" env:"DB_CONNECT_TIMEOUT" flag:"db-connect-timeout" usage:"database connect timeout"`
+	DBMaxConnLifetime   time.Duration `yaml:"db_max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" flag:"db-max-conn-lifetime" usage:"maximum lifetime of a database connection"`
+	DBMaxConnIdleTime   time.Duration `yaml:"db_max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" flag:"db-max-conn-idle-time" usage:"maximum idle time of a database connection"`
+	DBHealthCheckPeriod time.Duration `yaml:"db_health_check_period" env:"DB_HEALTH_CHECK_PERIOD" flag:"db-health-check-period" usage:"interval between idle connection health checks"`
+	DBStatementTimeout  time.Duration `yaml:"db_statement_timeout" env:"DB_STATEMENT_TIMEOUT" flag:"db-statement-timeout" usage:"server side statement timeout, 0 disables it"`
+
+	KafkaBrokers     string        `yaml:"kafka_brokers" env:"KAFKA_BROKERS" flag:"kafka-brokers" usage:"comma separated list of Kafka brokers"`
+	KafkaTopic       string        `yaml:"kafka_topic" env:"KAFKA_TOPIC" flag:"kafka-topic" usage:"Kafka topic with orders"`
+	KafkaGroupID     string        `yaml:"kafka_group_id" env:"KAFKA_GROUP_ID" flag:"kafka-group-id" usage:"Kafka consumer group"`
+	KafkaDialTimeout time.Duration `yaml:"kafka_dial_timeout" env:"KAFKA_DIAL_TIMEOUT" flag:"kafka-dial-timeout" usage:"Kafka dial timeout"`
+	KafkaMaxWait     time.Duration `yaml:"kafka_max_wait" env:"KAFKA_MAX_WAIT" flag:"kafka-max-wait" usage:"maximum wait for new Kafka data"`
+
+	KafkaMessageFormat  string `yaml:"kafka_message_format" env:"KAFKA_MESSAGE_FORMAT" flag:"kafka-message-format" usage:"format of Kafka messages without content-type header: json, protobuf or avro"`
+	KafkaSchemaRegistry string `yaml:"kafka_schema_registry" env:"KAFKA_SCHEMA_REGISTRY" flag:"kafka-schema-registry" usage:"schema registry URL or directory with schemas/ids/<id>.json files, required for protobuf and avro"`
+
+	KafkaDLQTopic string `yaml:"kafka_dlq_topic" env:"KAFKA_DLQ_TOPIC" flag:"kafka-dlq-topic" usage:"Kafka topic for dead-lettered messages, empty disables dead-lettering"`
+
+	KafkaConcurrency  int           `yaml:"kafka_concurrency" env:"KAFKA_CONCURRENCY" flag:"kafka-concurrency" usage:"number of consumer group members for the orders topic"`
+	KafkaMaxRetries   int           `yaml:"kafka_max_retries" env:"KAFKA_MAX_RETRIES" flag:"kafka-max-retries" usage:"retries of a failed order message before dead-lettering"`
+	KafkaRetryBackoff time.Duration `yaml:"kafka_retry_backoff" env:"KAFKA_RETRY_BACKOFF" flag:"kafka-retry-backoff" usage:"initial delay between retries of a failed message, doubled on each retry"`
+
+	KafkaOutboxTopic   string        `yaml:"kafka_outbox_topic" env:"KAFKA_OUTBOX_TOPIC" flag:"kafka-outbox-topic" usage:"Kafka topic for order stored events published from the outbox"`
+	OutboxPollInterval time.Duration `yaml:"outbox_poll_interval" env:"OUTBOX_POLL_INTERVAL" flag:"outbox-poll-interval" usage:"interval between outbox relay polls"`
+	OutboxBatchSize    int           `yaml:"outbox_batch_size" env:"OUTBOX_BATCH_SIZE" flag:"outbox-batch-size" usage:"maximum outbox events published in one batch"`
+	OutboxMaxBackoff   time.Duration `yaml:"outbox_max_backoff" env:"OUTBOX_MAX_BACKOFF" flag:"outbox-max-backoff" usage:"maximum delay between retries of a failed outbox event"`
+	OutboxWriteTimeout time.Duration `yaml:"outbox_write_timeout" env:"OUTBOX_WRITE_TIMEOUT" flag:"outbox-write-timeout" usage:"timeout of publishing one batch of outbox events"`
+	OutboxLease        time.Duration `yaml:"outbox_lease" env:"OUTBOX_LEASE" flag:"outbox-lease" usage:"time claimed outbox events stay hidden from other relays, must exceed outbox_write_timeout"`
+
+	KafkaReplayMaxMessages int `yaml:"kafka_replay_max_messages" env:"KAFKA_REPLAY_MAX_MESSAGES" flag:"kafka-replay-max-messages" usage:"maximum messages reprocessed by one replay request"`
+}
+
+// Значения конфигурации по умолчанию
+func Default() *Config {
+	return &Config{
+		LogLevel: "info",
+
+		HTTPPort:         "8080",
+		HTTPReadTimeout:  10 * time.Second,
+		HTTPWriteTimeout: 30 * time.Second,
+		HTTPIdleTimeout:  60 * time.Second,
+		ShutdownTimeout:  5 * time.Second,
+		HTTPMaxBodyBytes: 1 << 20,
+		BatchGetMaxUIDs:  500,
+		ImportBatchSize:  500,
+		ImportMaxBytes:   256 << 20,
+		LegacyAPISunset:  "2027-06-30",
+
+		HTTPCompressEncodings: "zstd,br,gzip",
+		HTTPCompressMinBytes:  1024,
+
+		GRPCPort:       "9090",
+		GRPCReflection: true,
+
+		AuthMode:        "none",
+		AuthJWTLeeway:   30 * time.Second,
+		AuthDefaultRole: "viewer",
+		AuthAnonRole:    "viewer",
+
+		IdempotencyKeyTTL: 24 * time.Hour,
+
+		StreamBufferSize: 64,
+		StreamReplaySize: 1000,
+		StreamHeartbeat:  15 * time.Second,
+
+		RateLimitRPS:   20,
+		RateLimitBurst: 40,
+
+		RateLimitIPRPS:   50,
+		RateLimitIPBurst: 100,
+
+		CacheCapacity: 100,
+
+		OrderCacheMaxAge: time.Minute,
+
+		DBPort:              "5432",
+		DBSSLMode:           "disable",
+		DBMaxConns:          10,
+		DBMinConns:          0,
+		DBConnectTimeout:    5 * time.Second,
+		DBMaxConnLifetime:   time.Hour,
+		DBMaxConnIdleTime:   30 * time.Minute,
+		DBHealthCheckPeriod: time.Minute,
+		DBStatementTimeout:  30 * time.Second,
+
+		KafkaGroupID:     "wbl0-orders-service",
+		KafkaDialTimeout: 10 * time.Second,
+		KafkaMaxWait:     time.Second,
+
+		KafkaMessageFormat: "json",
+
+		KafkaConcurrency:  1,
+		KafkaMaxRetries:   3,
+		KafkaRetryBackoff: time.Second,
+
+		KafkaOutboxTopic:   "wbl0_orders_stored",
+		OutboxPollInterval: time.Second,
+		OutboxBatchSize:    100,
+		OutboxMaxBackoff:   time.Minute,
+		OutboxWriteTimeout: 10 * time.Second,
+		OutboxLease:        time.Minute,
+
+		KafkaReplayMaxMessages: 1000,
+	}
+}
+
+// Загрузка конфигурации по слоям: значения по умолчанию, файл YAML,
+// переменные окружения и флаги командной строки из args
+func Load(args []string) (*Config, error) {
+	// Загрузка файла .env, если он есть
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("Failed to load .env: %v", err)
+	}
+
+	// Разбор флагов во временную структуру, чтобы применить их последними
+	flagValues, configPath, err := parseFlags(args)
+	if err != nil {
 		return nil, err
 	}
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_FILE")
+	}
 
-    // Преобразование емкости кэша
-    cacheCapacityRaw := os.Getenv("CACHE_CAPACITY")
-    cacheCapacity, err := strconv.Atoi(cacheCapacityRaw)
-    if err != nil {
-        cacheCapacity = 100
-    }
-	
-    // Чтение переменных файла .env
-	return &Config{
-		HTTPPort:      os.Getenv("HTTP_PORT"),
-        CacheCapacity: cacheCapacity,
-        DBHost:        os.Getenv("DB_HOST"),
-        DBPort:        os.Getenv("DB_PORT"),
-        DBUser:        os.Getenv("DB_USER"),
-        DBPass:        os.Getenv("DB_PASSWORD"),
-        DBName:        os.Getenv("DB_NAME"),
-        DBSSLMode:     os.Getenv("DB_SSL_MODE"),
-        KafkaBrokers:  os.Getenv("KAFKA_BROKERS"),
-        KafkaTopic:    os.Getenv("KAFKA_TOPIC"),
-	}, nil
-}
\ No newline at end of file
+	cfg := Default()
+
+	if configPath != "" {
+		if err := cfg.loadFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	for name, raw := range flagValues {
+		if err := cfg.setByTag("flag", name, raw); err != nil {
+			return nil, fmt.Errorf("Failed to apply flag -%s: %v", name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Разбор флагов командной строки.
+// Возвращает только явно заданные флаги и путь к файлу конфигурации
+func parseFlags(args []string) (map[string]string, string, error) {
+	fset := flag.NewFlagSet("orders-service", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+
+	var configPath string
+	fset.StringVar(&configPath, "config", "", "path to YAML config file")
+
+	tmp := Default()
+	forEachField(tmp, func(field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("flag")
+		if name == "" {
+			return
+		}
+		fset.Var(&fieldValue{value: value}, name, field.Tag.Get("usage"))
+	})
+
+	if err := fset.Parse(args); err != nil {
+		return nil, "", fmt.Errorf("Failed to parse flags: %v", err)
+	}
+
+	values := make(map[string]string)
+	fset.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			values[f.Name] = f.Value.String()
+		}
+	})
+
+	return values, configPath, nil
+}
+
+// Загрузка значений из файла YAML
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read config file %s: %v", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("Failed to parse config file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// Загрузка значений из переменных окружения
+func (c *Config) loadEnv() error {
+	var errs []error
+	forEachField(c, func(field reflect.StructField, value reflect.Value) {
+		// Пустые переменные считаются незаданными
+		name := field.Tag.Get("env")
+		if name == "" {
+			return
+		}
+		raw := os.Getenv(name)
+
+		// Чтение секрета из файла (Docker/Kubernetes secrets)
+		if path := os.Getenv(name + "_FILE"); path != "" && field.Tag.Get("file") == "true" {
+			if raw != "" {
+				errs = append(errs, fmt.Errorf("%s: both %s and %s_FILE are set", name, name, name))
+				return
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %v", name, err))
+				return
+			}
+			raw = strings.TrimRight(string(data), "\r\n")
+		}
+
+		if raw == "" {
+			return
+		}
+		if err := setValue(value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Failed to read environment: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Установка поля по значению тега
+func (c *Config) setByTag(tag, name, raw string) error {
+	found := false
+	var err error
+	forEachField(c, func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get(tag) == name {
+			found = true
+			err = setValue(value, raw)
+		}
+	})
+
+	if !found {
+		return fmt.Errorf("unknown %s %q", tag, name)
+	}
+	return err
+}
+
+// Ошибка валидации со списком всех найденных проблем
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Дата объявления неверсионированных маршрутов API устаревшими для заголовка Deprecation.
+// Совпадает с выпуском /api/v1 и не настраивается: она описывает прошлое событие,
+// а не срок, который может сдвинуться, как legacy_api_sunset
+var LegacyDeprecatedSince = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Дата отключения неверсионированных маршрутов API
+func (c *Config) LegacySunsetTime() (time.Time, error) {
+	return time.Parse(time.DateOnly, c.LegacyAPISunset)
+}
+
+// Проверка корректности конфигурации
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		add("log_level: must be one of debug, info, warn, error, got %q", c.LogLevel)
+	}
+	if port, err := strconv.Atoi(c.HTTPPort); err != nil || port < 1 || port > 65535 {
+		add("http_port: must be a number between 1 and 65535, got %q", c.HTTPPort)
+	}
+	if c.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+			add("grpc_port: must be a number between 1 and 65535, got %q", c.GRPCPort)
+		} else if c.GRPCPort == c.HTTPPort {
+			add("grpc_port: must differ from http_port, got %q", c.GRPCPort)
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("shutdown_timeout: must be positive, got %s", c.ShutdownTimeout)
+	}
+	if c.HTTPMaxBodyBytes < 1 {
+		add("http_max_body_bytes: must be positive, got %d", c.HTTPMaxBodyBytes)
+	}
+	for _, encoding := range strings.Split(c.HTTPCompressEncodings, ",") {
+		switch strings.ToLower(strings.TrimSpace(encoding)) {
+		case "", "zstd", "br", "gzip":
+		default:
+			add("http_compress_encodings: must list zstd, br or gzip, got %q", encoding)
+		}
+	}
+	if c.HTTPCompressMinBytes < 0 {
+		add("http_compress_min_bytes: must not be negative, got %d", c.HTTPCompressMinBytes)
+	}
+	if c.BatchGetMaxUIDs < 1 {
+		add("batch_get_max_uids: must be positive, got %d", c.BatchGetMaxUIDs)
+	}
+	if c.ImportBatchSize < 1 {
+		add("import_batch_size: must be positive, got %d", c.ImportBatchSize)
+	}
+	if c.ImportMaxBytes < 1 {
+		add("import_max_bytes: must be positive, got %d", c.ImportMaxBytes)
+	}
+	if c.IdempotencyKeyTTL <= 0 {
+		add("idempotency_key_ttl: must be positive, got %s", c.IdempotencyKeyTTL)
+	}
+	if c.StreamBufferSize < 1 {
+		add("stream_buffer_size: must be positive, got %d", c.StreamBufferSize)
+	}
+	if c.StreamReplaySize < 0 {
+		add("stream_replay_size: must not be negative, got %d", c.StreamReplaySize)
+	}
+	if c.StreamHeartbeat <= 0 {
+		add("stream_heartbeat: must be positive, got %s", c.StreamHeartbeat)
+	}
+	if _, err := c.LegacySunsetTime(); err != nil {
+		add("legacy_api_sunset: must be a date in YYYY-MM-DD format, got %q", c.LegacyAPISunset)
+	}
+	c.validateAuth(add)
+	if c.RateLimitRPS < 0 {
+		add("rate_limit_rps: must not be negative, got %v", c.RateLimitRPS)
+	}
+	if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+		add("rate_limit_burst: must be positive when rate limiting is enabled, got %d", c.RateLimitBurst)
+	}
+	if c.RateLimitIPRPS < 0 {
+		add("rate_limit_ip_rps: must not be negative, got %v", c.RateLimitIPRPS)
+	}
+	if c.RateLimitIPRPS > 0 && c.RateLimitIPBurst < 1 {
+		add("rate_limit_ip_burst: must be positive when IP rate limiting is enabled, got %d", c.RateLimitIPBurst)
+	}
+	for _, proxy := range c.TrustedProxies() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			add("http_trusted_proxies: %q is not an IP address or CIDR", proxy)
+		}
+	}
+	if c.CacheCapacity < 1 {
+		add("cache_capacity: must be positive, got %d", c.CacheCapacity)
+	}
+	if c.OrderCacheMaxAge < 0 {
+		add("order_cache_max_age: must not be negative, got %v", c.OrderCacheMaxAge)
+	}
+	// Отдельные параметры подключения нужны только без полного DSN
+	if c.DatabaseURL == "" {
+		if c.DBHost == "" {
+			add("db_host: must not be empty")
+		}
+		if port, err := strconv.Atoi(c.DBPort); err != nil || port < 1 || port > 65535 {
+			add("db_port: must be a number between 1 and 65535, got %q", c.DBPort)
+		}
+		if c.DBUser == "" {
+			add("db_user: must not be empty")
+		}
+		if c.DBName == "" {
+			add("db_name: must not be empty")
+		}
+	}
+	if c.DBConnectTimeout <= 0 {
+		add("db_connect_timeout: must be positive, got %s", c.DBConnectTimeout)
+	}
+	if c.DBMaxConns < 1 {
+		add("db_max_conns: must be positive, got %d", c.DBMaxConns)
+	}
+	if c.DBMinConns < 0 || c.DBMinConns > c.DBMaxConns {
+		add("db_min_conns: must be between 0 and db_max_conns, got %d", c.DBMinConns)
+	}
+	if len(c.Brokers()) == 0 {
+		add("kafka_brokers: must contain at least one broker")
+	}
+	if c.KafkaTopic == "" {
+		add("kafka_topic: must not be empty")
+	}
+	if c.KafkaGroupID == "" {
+		add("kafka_group_id: must not be empty")
+	}
+	if c.KafkaDLQTopic != "" && c.KafkaDLQTopic == c.KafkaTopic {
+		add("kafka_dlq_topic: must differ from kafka_topic, got %q", c.KafkaDLQTopic)
+	}
+	if c.KafkaConcurrency < 1 {
+		add("kafka_concurrency: must be positive, got %d", c.KafkaConcurrency)
+	}
+	if c.KafkaMaxRetries < 0 {
+		add("kafka_max_retries: must not be negative, got %d", c.KafkaMaxRetries)
+	}
+	if c.KafkaReplayMaxMessages < 1 {
+		add("kafka_replay_max_messages: must be positive, got %d", c.KafkaReplayMaxMessages)
+	}
+	if c.KafkaOutboxTopic == "" || c.KafkaOutboxTopic == c.KafkaTopic {
+		add("kafka_outbox_topic: must be set and differ from kafka_topic, got %q", c.KafkaOutboxTopic)
+	}
+	if c.OutboxPollInterval <= 0 {
+		add("outbox_poll_interval: must be positive, got %s", c.OutboxPollInterval)
+	}
+	if c.OutboxBatchSize < 1 {
+		add("outbox_batch_size: must be positive, got %d", c.OutboxBatchSize)
+	}
+	if c.OutboxMaxBackoff < c.OutboxPollInterval {
+		add("outbox_max_backoff: must not be less than outbox_poll_interval, got %s", c.OutboxMaxBackoff)
+	}
+	if c.OutboxWriteTimeout <= 0 {
+		add("outbox_write_timeout: must be positive, got %s", c.OutboxWriteTimeout)
+	}
+	// Аренда короче публикации позволила бы другому ретранслятору захватить события до ее завершения
+	if c.OutboxLease <= c.OutboxWriteTimeout {
+		add("outbox_lease: must be longer than outbox_write_timeout %s, got %s", c.OutboxWriteTimeout, c.OutboxLease)
+	}
+	switch c.KafkaMessageFormat {
+	case "json":
+	case "protobuf", "avro":
+		if c.KafkaSchemaRegistry == "" {
+			add("kafka_schema_registry: must be set for %s messages", c.KafkaMessageFormat)
+		}
+	default:
+		add("kafka_message_format: must be json, protobuf or avro, got %q", c.KafkaMessageFormat)
+	}
+
+	// Все длительности должны быть неотрицательными
+	forEachField(c, func(field reflect.StructField, value reflect.Value) {
+		if d, ok := value.Interface().(time.Duration); ok && d < 0 {
+			add("%s: must not be negative, got %s", field.Tag.Get("yaml"), d)
+		}
+	})
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Проверка настроек аутентификации
+func (c *Config) validateAuth(add func(format string, args ...any)) {
+	for key, role := range map[string]string{"auth_default_role": c.AuthDefaultRole, "auth_anonymous_role": c.AuthAnonRole} {
+		switch role {
+		case "viewer", "writer", "support", "finance", "admin":
+		default:
+			add("%s: must be one of viewer, writer, support, finance, admin, got %q", key, role)
+		}
+	}
+
+	for _, mode := range strings.Split(c.AuthMode, ",") {
+		switch strings.TrimSpace(mode) {
+		case "none":
+			if strings.Contains(c.AuthMode, ",") {
+				add("auth_mode: none can not be combined with other modes")
+			}
+		case "apikey":
+		case "jwt":
+			if c.AuthJWKSFile == "" {
+				add("auth_jwks_file: must be set for jwt auth mode")
+			}
+			if c.AuthJWTIssuer == "" {
+				add("auth_jwt_issuer: must be set for jwt auth mode")
+			}
+			if c.AuthJWTAudience == "" {
+				add("auth_jwt_audience: must be set for jwt auth mode")
+			}
+		default:
+			add("auth_mode: unknown mode %q, expected none, apikey or jwt", mode)
+		}
+	}
+}
+
+// Получение списка брокеров Kafka
+func (c *Config) Brokers() []string {
+	return splitList(c.KafkaBrokers)
+}
+
+// Список доверенных прокси; пустой список означает, что X-Forwarded-For не учитывается
+func (c *Config) TrustedProxies() []string {
+	return splitList(c.HTTPTrustedProxies)
+}
+
+// Разбор списка через запятую без пустых элементов
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Копия конфигурации со скрытыми секретами
+func (c *Config) Masked() *Config {
+	masked := *c
+	forEachField(&masked, func(field reflect.StructField, value reflect.Value) {
+		if value.Kind() != reflect.String || value.String() == "" {
+			return
+		}
+
+		switch field.Tag.Get("secret") {
+		case "true":
+			value.SetString("******")
+		case "url":
+			// В DSN скрываются только пароли, остальное полезно для отладки
+			if u, err := url.Parse(value.String()); err == nil && u.Scheme != "" {
+				value.SetString(redactURL(u))
+			} else {
+				value.SetString("******")
+			}
+		}
+	})
+	return &masked
+}
+
+// Скрытие пароля в userinfo и параметров запроса с паролями, например password и sslpassword
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for key := range query {
+		name := strings.ToLower(key)
+		if strings.Contains(name, "pass") || strings.Contains(name, "secret") || strings.Contains(name, "token") || name == "pwd" {
+			query.Set(key, "xxxxx")
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+// Вывод конфигурации в формате YAML
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("Failed to encode config: %v", err)
+	}
+	return encoder.Close()
+}
+
+// Обход всех полей конфигурации
+func forEachField(c *Config, fn func(field reflect.StructField, value reflect.Value)) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fn(t.Field(i), v.Field(i))
+	}
+}
+
+// Установка значения поля из строки
+func setValue(value reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+
+	return nil
+}
+
+// Обертка поля конфигурации для пакета flag
+type fieldValue struct {
+	value reflect.Value
+}
+
+func (f *fieldValue) String() string {
+	if !f.value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f *fieldValue) Set(raw string) error {
+	return setValue(f.value, raw)
+}
+
+func (f *fieldValue) IsBoolFlag() bool {
+	return f.value.Kind() == reflect.Bool
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Установка обязательных переменных окружения для тестов
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "user")
+	t.Setenv("DB_NAME", "orders")
+	t.Setenv("KAFKA_BROKERS", "localhost:9092")
+	t.Setenv("KAFKA_TOPIC", "orders")
+}
+
+// Тестирование порядка применения слоев конфигурации
+func TestLoadLayers(t *testing.T) {
+	setRequiredEnv(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "http_port: \"9000\"\ncache_capacity: 50\ncache_ttl: 2m\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("CACHE_CAPACITY", "60")
+
+	cfg, err := Load([]string{"-config", path, "-http-port", "9100"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.HTTPPort != "9100" {
+		t.Errorf("Expected flag to override port, but got %s", cfg.HTTPPort)
+	}
+	if cfg.CacheCapacity != 60 {
+		t.Errorf("Expected env to override cache capacity, but got %d", cfg.CacheCapacity)
+	}
+	if cfg.CacheTTL != 2*time.Minute {
+		t.Errorf("Expected cache TTL from file, but got %s", cfg.CacheTTL)
+	}
+	if cfg.KafkaGroupID != "wbl0-orders-service" {
+		t.Errorf("Expected default consumer group, but got %s", cfg.KafkaGroupID)
+	}
+}
+
+// Тестирование сбора всех ошибок валидации
+func TestValidateAggregatesErrors(t *testing.T) {
+	cfg := Default()
+	cfg.HTTPPort = ""
+
+	err := cfg.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected validation error, but got %v", err)
+	}
+
+	// Порт, хост БД, пользователь, имя БД, брокеры и топик
+	if len(validationErr.Problems) != 6 {
+		t.Errorf("Expected 6 problems, but got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+}
+
+// Тестирование проверки аренды событий outbox относительно времени публикации
+func TestValidateOutboxLease(t *testing.T) {
+	cfg := Default()
+	if cfg.OutboxLease <= cfg.OutboxWriteTimeout {
+		t.Errorf("Expected default lease %s longer than write timeout %s", cfg.OutboxLease, cfg.OutboxWriteTimeout)
+	}
+
+	cfg.OutboxLease = cfg.OutboxWriteTimeout
+	err := cfg.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected validation error, but got %v", err)
+	}
+	found := false
+	for _, problem := range validationErr.Problems {
+		found = found || strings.HasPrefix(problem, "outbox_lease:")
+	}
+	if !found {
+		t.Errorf("Expected outbox_lease problem, but got %v", validationErr.Problems)
+	}
+}
+
+// Тестирование проверки списка доверенных прокси
+func TestValidateTrustedProxies(t *testing.T) {
+	cfg := Default()
+	if proxies := cfg.TrustedProxies(); proxies != nil {
+		t.Errorf("Expected no trusted proxies by default, but got %v", proxies)
+	}
+
+	cfg.HTTPTrustedProxies = "10.0.0.0/8, 192.0.2.1,proxy.local"
+	if proxies := cfg.TrustedProxies(); len(proxies) != 3 || proxies[1] != "192.0.2.1" {
+		t.Errorf("Unexpected trusted proxies %v", proxies)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(cfg.Validate(), &validationErr) {
+		t.Fatal("Expected validation error")
+	}
+	var problems []string
+	for _, problem := range validationErr.Problems {
+		if strings.HasPrefix(problem, "http_trusted_proxies:") {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "proxy.local") {
+		t.Errorf("Expected one problem for proxy.local, but got %v", problems)
+	}
+}
+
+// Тестирование ролей по умолчанию: без аутентификации анонимный клиент не получает прав записи и администрирования
+func TestDefaultRoles(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.AuthMode != "none" || cfg.AuthAnonRole != "viewer" {
+		t.Errorf("Expected anonymous role viewer with auth_mode none, but got %q with %q", cfg.AuthAnonRole, cfg.AuthMode)
+	}
+	if cfg.AuthDefaultRole != "viewer" {
+		t.Errorf("Expected default role viewer, but got %q", cfg.AuthDefaultRole)
+	}
+}
+
+// Тестирование ошибки в значении переменной окружения
+func TestLoadInvalidDuration(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("CACHE_TTL", "forever")
+
+	if _, err := Load(nil); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
+
+// Тестирование скрытия секретов
+func TestMasked(t *testing.T) {
+	cfg := Default()
+	cfg.DBPass = "secret"
+
+	if masked := cfg.Masked(); masked.DBPass == "secret" {
+		t.Error("Failed to mask database password")
+	}
+	if cfg.DBPass != "secret" {
+		t.Error("Masking changed original config")
+	}
+}
+
+// Тестирование чтения секрета из файла
+func TestLoadSecretFromFile(t *testing.T) {
+	setRequiredEnv(t)
+
+	path := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(path, []byte("s3cr@t/pass\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	t.Setenv("DB_PASSWORD_FILE", path)
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.DBPass != "s3cr@t/pass" {
+		t.Errorf("Expected password from file, but got %q", cfg.DBPass)
+	}
+
+	t.Setenv("DB_PASSWORD", "other")
+	if _, err := Load(nil); err == nil {
+		t.Error("Expected error when both value and file are set")
+	}
+}
+
+// Тестирование полного DSN без отдельных параметров подключения
+func TestDatabaseURL(t *testing.T) {
+	cfg := Default()
+	cfg.DatabaseURL = "postgres://user:secret@db:5432/orders"
+	cfg.KafkaBrokers = "localhost:9092"
+	cfg.KafkaTopic = "orders"
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected DSN to replace db settings, but got %v", err)
+	}
+
+	masked := cfg.Masked()
+	if masked.DatabaseURL != "postgres://user:xxxxx@db:5432/orders" {
+		t.Errorf("Failed to mask DSN password: %s", masked.DatabaseURL)
+	}
+
+	// Пароли в параметрах запроса
+	dsns := map[string]string{
+		"postgres://db/orders?password=secret&sslmode=require":         "postgres://db/orders?password=xxxxx&sslmode=require",
+		"postgres://user@db/orders?sslpassword=secret":                 "postgres://user@db/orders?sslpassword=xxxxx",
+		"postgres://user:secret@db/orders?PASSWORD=secret&user=orders": "postgres://user:xxxxx@db/orders?PASSWORD=xxxxx&user=orders",
+	}
+	for dsn, expected := range dsns {
+		cfg.DatabaseURL = dsn
+		if masked := cfg.Masked().DatabaseURL; masked != expected {
+			t.Errorf("Expected %s masked as %s, but got %s", dsn, expected, masked)
+		}
+	}
+}
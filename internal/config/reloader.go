@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+)
+
+// Перезагрузчик конфигурации.
+// Повторно читает все слои конфигурации, но применяет только поля с тегом reload,
+// остальные изменения требуют перезапуска сервиса
+type Reloader struct {
+	mu          sync.Mutex
+	args        []string
+	current     *Config
+	subscribers []func(cfg *Config)
+}
+
+// Конструктор перезагрузчика
+func NewReloader(cfg *Config, args []string) *Reloader {
+	return &Reloader{
+		args:    args,
+		current: cfg,
+	}
+}
+
+// Получение текущей конфигурации
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Подписка на изменения конфигурации
+func (r *Reloader) Subscribe(fn func(cfg *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// Перечитывание конфигурации и уведомление подписчиков.
+// Возвращает имена примененных полей
+func (r *Reloader) Reload() ([]string, error) {
+	loaded, err := Load(r.args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to reload config: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Текущая структура не меняется, чтобы не гоняться с читателями
+	next := *r.current
+	var applied []string
+
+	nextValue := reflect.ValueOf(&next).Elem()
+	loadedValue := reflect.ValueOf(loaded).Elem()
+	for i := 0; i < nextValue.NumField(); i++ {
+		field := nextValue.Type().Field(i)
+		if reflect.DeepEqual(nextValue.Field(i).Interface(), loadedValue.Field(i).Interface()) {
+			continue
+		}
+
+		name := field.Tag.Get("yaml")
+		if field.Tag.Get("reload") != "true" {
+			log.Printf("Config field %s changed, restart required to apply it", name)
+			continue
+		}
+		nextValue.Field(i).Set(loadedValue.Field(i))
+		applied = append(applied, name)
+	}
+
+	if len(applied) == 0 {
+		return nil, nil
+	}
+
+	r.current = &next
+	for _, fn := range r.subscribers {
+		fn(r.current)
+	}
+
+	return applied, nil
+}
+
+// Перечитывание конфигурации по сигналу SIGHUP до отмены контекста
+func (r *Reloader) WatchSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			applied, err := r.Reload()
+			if err != nil {
+				slog.Error("Failed to reload config on SIGHUP", "err", err)
+				continue
+			}
+			log.Printf("Reloaded config on SIGHUP, applied fields: %v", applied)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// Тестирование применения только перезагружаемых полей
+func TestReloaderAppliesReloadableFields(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	reloader := NewReloader(cfg, nil)
+	var notified *Config
+	reloader.Subscribe(func(c *Config) {
+		notified = c
+	})
+
+	t.Setenv("CACHE_CAPACITY", "500")
+	t.Setenv("CACHE_TTL", "1m")
+	t.Setenv("HTTP_PORT", "9999")
+
+	applied, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("Expected 2 applied fields, but got %v", applied)
+	}
+
+	if notified == nil || notified.CacheCapacity != 500 || notified.CacheTTL != time.Minute {
+		t.Fatalf("Subscriber did not receive new values: %+v", notified)
+	}
+	if notified.HTTPPort != cfg.HTTPPort {
+		t.Errorf("Non-reloadable field changed to %s", notified.HTTPPort)
+	}
+	if cfg.CacheCapacity == 500 {
+		t.Error("Reload mutated previous config")
+	}
+	if reloader.Current() != notified {
+		t.Error("Current config differs from notified one")
+	}
+}
+
+// Тестирование отказа при невалидной новой конфигурации
+func TestReloaderRejectsInvalidConfig(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	reloader := NewReloader(cfg, nil)
+
+	t.Setenv("CACHE_CAPACITY", "-1")
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("Expected error for invalid cache capacity")
+	}
+	if reloader.Current() != cfg {
+		t.Error("Invalid reload replaced current config")
+	}
+}
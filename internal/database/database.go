@@ -3,16 +3,27 @@ package database
 import (
     "context"
     "fmt"
-    "time"
     "errors"
+    "net"
+    "net/url"
+    "strconv"
+    "time"
     
     "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgconn"
     "github.com/jackc/pgx/v5/pgxpool"
 
+    "github.com/venexene/wbl0-orders-service/internal/auth"
     "github.com/venexene/wbl0-orders-service/internal/config"
     "github.com/venexene/wbl0-orders-service/internal/models"
 )
 
+// Заказ с таким UID уже сохранен
+var ErrOrderExists = errors.New("Order already exists")
+
+// Код ошибки PostgreSQL о нарушении уникальности
+const uniqueViolationCode = "23505"
+
 // Структура для работы с БД
 type Storage struct {
     pool *pgxpool.Pool
@@ -21,11 +32,19 @@ type Storage struct {
 type StorageInterface interface {
     TestDB() (string, error)
     GetOrderByUID(ctx context.Context, orderUID string) (*models.Order, error)
+    GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error)
+    SearchOrders(ctx context.Context, filter OrderFilter) ([]*models.Order, error)
+    GetItemsByOrderUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.Item, error)
+    ExportOrders(ctx context.Context, filter OrderFilter, fn func(order *models.Order) error) error
     GetAllOrdersUID(ctx context.Context) ([]string, error)
     GetRecentOrdersUID(ctx context.Context, limit int) ([]string, error)
     OrderExists(ctx context.Context, orderUID string) (bool, error)
     AddOrder(ctx context.Context, order *models.Order) error 
     AddOrderIfNotExists(ctx context.Context, order *models.Order) error 
+    AddOrders(ctx context.Context, orders []*models.Order) ([]string, error)
+    ExistingOrderUIDs(ctx context.Context, orderUIDs []string) ([]string, error)
+    GetIdempotencyKey(ctx context.Context, subject, key string, notBefore time.Time) (*IdempotencyRecord, error)
+    AddOrderWithIdempotencyKey(ctx context.Context, order *models.Order, record *IdempotencyRecord, notBefore time.Time) error
 }
 
 // Конструктор структуры для БД
@@ -34,25 +53,68 @@ func NewStorage(pool *pgxpool.Pool) *Storage {
 }
 
 
+// Формирование строки подключения.
+// Полный DSN имеет приоритет, иначе URL собирается с экранированием
+// пользователя и пароля, чтобы символы вроде @, / и : не ломали разбор
+func ConnString(cfg *config.Config) string {
+    if cfg.DatabaseURL != "" {
+        return cfg.DatabaseURL
+    }
+
+    connURL := url.URL{
+        Scheme:   "postgres",
+        User:     url.UserPassword(cfg.DBUser, cfg.DBPass),
+        Host:     net.JoinHostPort(cfg.DBHost, cfg.DBPort),
+        Path:     "/" + cfg.DBName,
+        RawQuery: url.Values{"sslmode": {cfg.DBSSLMode}}.Encode(),
+    }
+    return connURL.String()
+}
+
+
+// Формирование настроек пула из конфигурации
+func PoolConfig(cfg *config.Config) (*pgxpool.Config, error) {
+    poolCfg, err := pgxpool.ParseConfig(ConnString(cfg))
+    if err != nil {
+        // Текст ошибки pgx может содержать DSN с паролем
+        return nil, errors.New("Failed to parse database connection string")
+    }
+
+    poolCfg.MaxConns = cfg.DBMaxConns
+    poolCfg.MinConns = cfg.DBMinConns
+    poolCfg.ConnConfig.ConnectTimeout = cfg.DBConnectTimeout
+    if cfg.DBMaxConnLifetime > 0 {
+        poolCfg.MaxConnLifetime = cfg.DBMaxConnLifetime
+    }
+    if cfg.DBMaxConnIdleTime > 0 {
+        poolCfg.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+    }
+    if cfg.DBHealthCheckPeriod > 0 {
+        poolCfg.HealthCheckPeriod = cfg.DBHealthCheckPeriod
+    }
+
+    // Таймаут запросов на стороне сервера задается параметром сессии
+    if cfg.DBStatementTimeout > 0 {
+        poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.DBStatementTimeout.Milliseconds(), 10)
+    }
+
+    return poolCfg, nil
+}
+
+
 // Создание пула соединений к БД
 func CreatePool(cfg *config.Config) (*pgxpool.Pool, error) {
-    // Формирование строки подключения
-    connectionStr := fmt.Sprintf(
-        "postgres://%s:%s@%s:%s/%s?sslmode=%s",
-        cfg.DBUser,
-        cfg.DBPass,
-        cfg.DBHost,
-        cfg.DBPort,
-        cfg.DBName,
-        cfg.DBSSLMode,
-    )
+    poolCfg, err := PoolConfig(cfg)
+    if err != nil {
+        return nil, err
+    }
 
     // Создание контекста с таймаутом для контроля времени выполнения и обработки отмены
-    context, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    context, cancel := context.WithTimeout(context.Background(), cfg.DBConnectTimeout)
     defer cancel()
 
     // Создание пула для соедиения
-    pool, err := pgxpool.New(context, connectionStr)
+    pool, err := pgxpool.NewWithConfig(context, poolCfg)
     if err != nil {
         return pool, fmt.Errorf("Failed to create pool: %v", err)
     }
@@ -95,7 +157,7 @@ func (s *Storage) GetOrderByUID(context context.Context, orderUID string) (*mode
 
     if err != nil {
         if errors.Is(err, pgx.ErrNoRows) {
-            return nil, fmt.Errorf("Failed to find order with UID %v", orderUID)
+            return nil, fmt.Errorf("Failed to find order with UID %v: %w", orderUID, err)
         }
         return nil, fmt.Errorf("Failed to query database: %v", err)
     }
@@ -181,6 +243,153 @@ func (s *Storage) GetOrderByUID(context context.Context, orderUID string) (*mode
 }
 
 
+// Столбцы заказа с доставкой и оплатой для выборки нескольких заказов
+const orderColumns = `
+        o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+        o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+        d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+        p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt,
+        p.bank, p.delivery_cost, p.goods_total, p.custom_fee`
+
+// Соединение таблиц заказа, доставки и оплаты
+const orderJoins = `
+    FROM orders o
+    JOIN delivery d ON d.order_uid = o.order_uid
+    JOIN payment p ON p.order_uid = o.order_uid`
+
+// Запрос товаров нескольких заказов
+const itemsByUIDsQuery = `
+    SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+    FROM item
+    WHERE order_uid = ANY($1::uuid[])
+    ORDER BY id`
+
+// Получение нескольких заказов по UID за один обмен с БД.
+// Заказы и товары запрашиваются одним пакетом, отсутствующие UID не попадают в результат
+func (s *Storage) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error) {
+    orders := make(map[string]*models.Order, len(orderUIDs))
+    if len(orderUIDs) == 0 {
+        return orders, nil
+    }
+
+    batch := &pgx.Batch{}
+    batch.Queue("SELECT "+orderColumns+orderJoins+" WHERE o.order_uid = ANY($1::uuid[])", orderUIDs)
+    batch.Queue(itemsByUIDsQuery, orderUIDs)
+    results := s.pool.SendBatch(ctx, batch)
+    defer results.Close()
+
+    // Основная информация, доставка и оплата
+    rows, err := results.Query()
+    if err != nil {
+        return nil, fmt.Errorf("Failed to query orders: %v", err)
+    }
+    list, err := scanOrders(rows)
+    if err != nil {
+        return nil, err
+    }
+    for _, order := range list {
+        orders[order.OrderUID] = order
+    }
+
+    // Товары всех найденных заказов
+    rows, err = results.Query()
+    if err != nil {
+        return nil, fmt.Errorf("Failed to query items: %v", err)
+    }
+    if err := scanItems(rows, orders); err != nil {
+        return nil, err
+    }
+
+    return orders, nil
+}
+
+
+// Чтение заказов со столбцами orderColumns. Строки закрываются
+func scanOrders(rows pgx.Rows) ([]*models.Order, error) {
+    defer rows.Close()
+
+    var orders []*models.Order
+    for rows.Next() {
+        var order models.Order
+        err := rows.Scan(
+            &order.OrderUID,
+            &order.TrackNumber,
+            &order.Entry,
+            &order.Locale,
+            &order.InternalSignature,
+            &order.CustomerID,
+            &order.DeliveryService,
+            &order.ShardKey,
+            &order.SMID,
+            &order.DateCreated,
+            &order.OOFShard,
+            &order.Delivery.Name,
+            &order.Delivery.Phone,
+            &order.Delivery.Zip,
+            &order.Delivery.City,
+            &order.Delivery.Address,
+            &order.Delivery.Region,
+            &order.Delivery.Email,
+            &order.Payment.Transaction,
+            &order.Payment.RequestID,
+            &order.Payment.Currency,
+            &order.Payment.Provider,
+            &order.Payment.Amount,
+            &order.Payment.PaymentDt,
+            &order.Payment.Bank,
+            &order.Payment.DeliveryCost,
+            &order.Payment.GoodsTotal,
+            &order.Payment.CustomFee,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("Failed to scan order: %v", err)
+        }
+        orders = append(orders, &order)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("Failed to iterate orders: %v", err)
+    }
+
+    return orders, nil
+}
+
+
+// Чтение товаров и добавление их к заказам по UID. Строки закрываются
+func scanItems(rows pgx.Rows, orders map[string]*models.Order) error {
+    defer rows.Close()
+
+    for rows.Next() {
+        var orderUID string
+        var item models.Item
+        err := rows.Scan(
+            &orderUID,
+            &item.ChrtID,
+            &item.TrackNumber,
+            &item.Price,
+            &item.Rid,
+            &item.Name,
+            &item.Sale,
+            &item.Size,
+            &item.TotalPrice,
+            &item.NmID,
+            &item.Brand,
+            &item.Status,
+        )
+        if err != nil {
+            return fmt.Errorf("Failed to scan item: %v", err)
+        }
+        if order, exists := orders[orderUID]; exists {
+            order.Items = append(order.Items, item)
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("Failed to iterate items: %v", err)
+    }
+
+    return nil
+}
+
+
 // Добавление заказа в БД
 func (s *Storage) AddOrder(ctx context.Context, order *models.Order) error {
     // Начало транзакции для атомарного добавления данных
@@ -190,107 +399,229 @@ func (s *Storage) AddOrder(ctx context.Context, order *models.Order) error {
     }
     defer tx.Rollback(ctx) // Откат транзакции в случае ошибки
 
-    // Добавление основной информации о заказе
-    orderQuery := `
+    if err := insertOrder(ctx, tx, order); err != nil {
+        return err
+    }
+
+    // Подтверждение транзакции
+    if err = tx.Commit(ctx); err != nil {
+        return fmt.Errorf("Failed to commit transaction: %v", err)
+    }
+
+    return nil
+}
+
+
+// Запросы добавления частей заказа
+const (
+    insertOrderQuery = `
         INSERT INTO orders (
             order_uid, track_number, entry, locale, internal_signature,
             customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
         ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
     `
-    _, err = tx.Exec(ctx, orderQuery,
-        order.OrderUID,
-        order.TrackNumber,
-        order.Entry,
-        order.Locale,
-        order.InternalSignature,
-        order.CustomerID,
-        order.DeliveryService,
-        order.ShardKey,
-        order.SMID,
-        order.DateCreated,
-        order.OOFShard,
-    )
-    if err != nil {
-        return fmt.Errorf("Failed to insert order: %v", err)
-    }
-
-    // Добавление информации о доставке
-    deliveryQuery := `
+    insertDeliveryQuery = `
         INSERT INTO delivery (
             order_uid, name, phone, zip, city, address, region, email
         ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
     `
-    _, err = tx.Exec(ctx, deliveryQuery,
-        order.OrderUID,
-        order.Delivery.Name,
-        order.Delivery.Phone,
-        order.Delivery.Zip,
-        order.Delivery.City,
-        order.Delivery.Address,
-        order.Delivery.Region,
-        order.Delivery.Email,
-    )
-    if err != nil {
-        return fmt.Errorf("Failed to insert delivery: %v", err)
-    }
-
-    // Добавление информации о платеже
-    paymentQuery := `
+    insertPaymentQuery = `
         INSERT INTO payment (
             order_uid, transaction, request_id, currency, provider, amount, 
             payment_dt, bank, delivery_cost, goods_total, custom_fee
         ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
     `
-    _, err = tx.Exec(ctx, paymentQuery,
-        order.OrderUID,
-        order.Payment.Transaction,
-        order.Payment.RequestID,
-        order.Payment.Currency,
-        order.Payment.Provider,
-        order.Payment.Amount,
-        order.Payment.PaymentDt,
-        order.Payment.Bank,
-        order.Payment.DeliveryCost,
-        order.Payment.GoodsTotal,
-        order.Payment.CustomFee,
-    )
-    if err != nil {
-        return fmt.Errorf("Failed to insert payment: %v", err)
-    }
-
-    // Добавление информации о товарах
-    itemQuery := `
+    insertItemQuery = `
         INSERT INTO item (
             order_uid, chrt_id, track_number, price, rid, name, 
             sale, size, total_price, nm_id, brand, status
         ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
     `
-    for _, item := range order.Items {
-        _, err = tx.Exec(ctx, itemQuery,
+)
+
+
+// Добавление всех частей заказа в рамках транзакции
+func insertOrder(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+    return insertOrders(ctx, tx, []*models.Order{order})
+}
+
+
+// Добавление нескольких заказов одним пакетом запросов в рамках транзакции.
+// Вместе с каждым заказом в outbox записывается событие о его сохранении
+func insertOrders(ctx context.Context, tx pgx.Tx, orders []*models.Order) error {
+    batch := &pgx.Batch{}
+    for _, order := range orders {
+        // Основная информация о заказе
+        batch.Queue(insertOrderQuery,
+            order.OrderUID,
+            order.TrackNumber,
+            order.Entry,
+            order.Locale,
+            order.InternalSignature,
+            order.CustomerID,
+            order.DeliveryService,
+            order.ShardKey,
+            order.SMID,
+            order.DateCreated,
+            order.OOFShard,
+        )
+
+        // Информация о доставке
+        batch.Queue(insertDeliveryQuery,
+            order.OrderUID,
+            order.Delivery.Name,
+            order.Delivery.Phone,
+            order.Delivery.Zip,
+            order.Delivery.City,
+            order.Delivery.Address,
+            order.Delivery.Region,
+            order.Delivery.Email,
+        )
+
+        // Информация о платеже
+        batch.Queue(insertPaymentQuery,
             order.OrderUID,
-            item.ChrtID,
-            item.TrackNumber,
-            item.Price,
-            item.Rid,
-            item.Name,
-            item.Sale,
-            item.Size,
-            item.TotalPrice,
-            item.NmID,
-            item.Brand,
-            item.Status,
+            order.Payment.Transaction,
+            order.Payment.RequestID,
+            order.Payment.Currency,
+            order.Payment.Provider,
+            order.Payment.Amount,
+            order.Payment.PaymentDt,
+            order.Payment.Bank,
+            order.Payment.DeliveryCost,
+            order.Payment.GoodsTotal,
+            order.Payment.CustomFee,
         )
+
+        // Информация о товарах
+        for _, item := range order.Items {
+            batch.Queue(insertItemQuery,
+                order.OrderUID,
+                item.ChrtID,
+                item.TrackNumber,
+                item.Price,
+                item.Rid,
+                item.Name,
+                item.Sale,
+                item.Size,
+                item.TotalPrice,
+                item.NmID,
+                item.Brand,
+                item.Status,
+            )
+        }
+
+        // Событие о сохранении заказа для публикации через outbox
+        payload, err := orderStoredPayload(order)
         if err != nil {
-            return fmt.Errorf("Failed to insert item: %v", err)
+            return err
         }
+        batch.Queue(insertOutboxQuery, order.OrderUID, OutboxOrderStored, payload)
     }
 
-    // Подтверждение транзакции
-    if err = tx.Commit(ctx); err != nil {
-        return fmt.Errorf("Failed to commit transaction: %v", err)
+    // Результаты читаются в порядке постановки запросов в пакет
+    results := tx.SendBatch(ctx, batch)
+    defer results.Close()
+
+    for _, order := range orders {
+        if _, err := results.Exec(); err != nil {
+            if isUniqueViolation(err) {
+                return fmt.Errorf("%w: %s", ErrOrderExists, order.OrderUID)
+            }
+            return fmt.Errorf("Failed to insert order: %v", err)
+        }
+        if _, err := results.Exec(); err != nil {
+            return fmt.Errorf("Failed to insert delivery: %v", err)
+        }
+        if _, err := results.Exec(); err != nil {
+            return fmt.Errorf("Failed to insert payment: %v", err)
+        }
+        for range order.Items {
+            if _, err := results.Exec(); err != nil {
+                return fmt.Errorf("Failed to insert item: %v", err)
+            }
+        }
+        if _, err := results.Exec(); err != nil {
+            return fmt.Errorf("Failed to insert outbox event: %v", err)
+        }
     }
 
-    return nil
+    return results.Close()
+}
+
+
+// Добавление нескольких заказов в одной транзакции.
+// Каждый заказ вставляется в своей точке сохранения: уже существующий заказ
+// (в том числе добавленный параллельно) пропускается, а его UID возвращается.
+// При другой ошибке не добавляется ни один заказ
+func (s *Storage) AddOrders(ctx context.Context, orders []*models.Order) ([]string, error) {
+    if len(orders) == 0 {
+        return nil, nil
+    }
+
+    tx, err := s.pool.Begin(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to begin transaction: %v", err)
+    }
+    defer tx.Rollback(ctx)
+
+    var existing []string
+    for _, order := range orders {
+        savepoint, err := tx.Begin(ctx)
+        if err != nil {
+            return nil, fmt.Errorf("Failed to create savepoint: %v", err)
+        }
+
+        err = insertOrders(ctx, savepoint, []*models.Order{order})
+        if errors.Is(err, ErrOrderExists) {
+            if err := savepoint.Rollback(ctx); err != nil {
+                return nil, fmt.Errorf("Failed to rollback to savepoint: %v", err)
+            }
+            existing = append(existing, order.OrderUID)
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+
+        if err := savepoint.Commit(ctx); err != nil {
+            return nil, fmt.Errorf("Failed to release savepoint: %v", err)
+        }
+    }
+
+    if err := tx.Commit(ctx); err != nil {
+        return nil, fmt.Errorf("Failed to commit transaction: %v", err)
+    }
+
+    return existing, nil
+}
+
+
+// Получение тех UID из списка, для которых заказ уже сохранен
+func (s *Storage) ExistingOrderUIDs(ctx context.Context, orderUIDs []string) ([]string, error) {
+    if len(orderUIDs) == 0 {
+        return nil, nil
+    }
+
+    rows, err := s.pool.Query(ctx, "SELECT order_uid FROM orders WHERE order_uid = ANY($1::uuid[])", orderUIDs)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to query existing orders: %v", err)
+    }
+    defer rows.Close()
+
+    var existing []string
+    for rows.Next() {
+        var uid string
+        if err := rows.Scan(&uid); err != nil {
+            return nil, fmt.Errorf("Failed to scan order_uid: %v", err)
+        }
+        existing = append(existing, uid)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("Failed to iterate order_uid: %v", err)
+    }
+
+    return existing, nil
 }
 
 
@@ -308,23 +639,17 @@ func (s *Storage) OrderExists(ctx context.Context, orderUID string) (bool, error
 }
 
 
-// Добавление заказа с проверкой на существование
+// Добавление заказа с проверкой на существование.
+// Существование определяется ограничением уникальности при вставке, а не отдельным запросом,
+// поэтому конкурирующие записи одного UID не проходят обе
 func (s *Storage) AddOrderIfNotExists(ctx context.Context, order *models.Order) error {
-    exists, err := s.OrderExists(ctx, order.OrderUID)
-    if err != nil {
-        return err
-    }
-    if exists {
-        return fmt.Errorf("Order with UID %v already exists", order.OrderUID)
-    }
-    
     return s.AddOrder(ctx, order)
 }
 
 
 // Получение UID всех заказов
 func (s *Storage) GetAllOrdersUID(ctx context.Context) ([]string, error) {
-    query := "SELECT order_uid FROM orders"
+    query := "SELECT order_uid FROM orders ORDER BY date_created DESC, order_uid DESC"
 
     // Получение всех uid из БД
     rows, err := s.pool.Query(ctx, query)
@@ -376,4 +701,28 @@ func (s *Storage) GetRecentOrdersUID(ctx context.Context, limit int) ([]string,
     }
 
     return uids, nil
-}
\ No newline at end of file
+}
+
+
+// Поиск действующего API ключа по хэшу
+func (s *Storage) LookupAPIKey(ctx context.Context, hash string) (*auth.APIKey, error) {
+    query := "SELECT name, key_hash, role FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL"
+
+    var key auth.APIKey
+    err := s.pool.QueryRow(ctx, query, hash).Scan(&key.Name, &key.Hash, &key.Role)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return nil, auth.ErrKeyNotFound
+        }
+        return nil, fmt.Errorf("Failed to query api key: %v", err)
+    }
+
+    return &key, nil
+}
+
+
+// Проверка, что ошибка вызвана нарушением уникальности
+func isUniqueViolation(err error) bool {
+    var pgErr *pgconn.PgError
+    return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
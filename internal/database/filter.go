@@ -0,0 +1,126 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Условия отбора заказов, общие для поиска и выгрузки
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	Locale          string
+	CreatedFrom     time.Time    // Нижняя граница date_created включительно
+	CreatedTo       time.Time    // Верхняя граница date_created не включительно
+	After           *OrderCursor // Продолжение после последнего заказа предыдущей страницы
+	Limit           int          // Ограничение числа заказов, 0 - без ограничения
+	SkipItems       bool         // Не загружать товары, они будут запрошены отдельно
+}
+
+// Позиция заказа в порядке выдачи: сначала новые
+type OrderCursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// Токен страницы не соответствует формату
+var ErrMalformedCursor = errors.New("Malformed page token")
+
+// Формат UID заказа в токене страницы
+var cursorUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Кодирование позиции в непрозрачный токен страницы
+func (c OrderCursor) Encode() string {
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Разбор позиции из токена страницы
+func ParseOrderCursor(token string) (*OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrMalformedCursor
+	}
+
+	value, uid, found := strings.Cut(string(raw), "|")
+	if !found || !cursorUIDPattern.MatchString(uid) {
+		return nil, ErrMalformedCursor
+	}
+	dateCreated, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, ErrMalformedCursor
+	}
+
+	return &OrderCursor{DateCreated: dateCreated, OrderUID: uid}, nil
+}
+
+// Порядок выдачи заказов для поиска и выгрузки
+const orderFilterOrder = " ORDER BY o.date_created DESC, o.order_uid DESC"
+
+// Формирование условия WHERE и аргументов запроса.
+// Типы параметров указаны явно, так как условие используется и в DECLARE CURSOR
+func (f OrderFilter) where() (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if f.CustomerID != "" {
+		add("o.customer_id = ?::text", f.CustomerID)
+	}
+	if f.TrackNumber != "" {
+		add("o.track_number = ?::text", f.TrackNumber)
+	}
+	if f.DeliveryService != "" {
+		add("o.delivery_service = ?::text", f.DeliveryService)
+	}
+	if f.Locale != "" {
+		add("o.locale = ?::text", f.Locale)
+	}
+	if !f.CreatedFrom.IsZero() {
+		add("o.date_created >= ?::timestamptz", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		add("o.date_created < ?::timestamptz", f.CreatedTo)
+	}
+	if f.After != nil {
+		args = append(args, f.After.DateCreated, f.After.OrderUID)
+		n := len(args)
+		conditions = append(conditions, "(o.date_created, o.order_uid) < ($"+strconv.Itoa(n-1)+"::timestamptz, $"+strconv.Itoa(n)+"::uuid)")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Проверка заказа на соответствие условиям отбора в памяти.
+// Позиция страницы и ограничение числа заказов не учитываются
+func (f OrderFilter) Matches(order *models.Order) bool {
+	switch {
+	case f.CustomerID != "" && order.CustomerID != f.CustomerID:
+		return false
+	case f.TrackNumber != "" && order.TrackNumber != f.TrackNumber:
+		return false
+	case f.DeliveryService != "" && order.DeliveryService != f.DeliveryService:
+		return false
+	case f.Locale != "" && order.Locale != f.Locale:
+		return false
+	case !f.CreatedFrom.IsZero() && order.DateCreated.Before(f.CreatedFrom):
+		return false
+	case !f.CreatedTo.IsZero() && !order.DateCreated.Before(f.CreatedTo):
+		return false
+	}
+	return true
+}
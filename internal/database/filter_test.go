@@ -0,0 +1,59 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование построения условия отбора заказов
+func TestOrderFilterWhere(t *testing.T) {
+	if where, args := (OrderFilter{}).where(); where != "" || len(args) != 0 {
+		t.Errorf("Expected empty condition, but got %q with %v", where, args)
+	}
+
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	filter := OrderFilter{
+		CustomerID:  "test",
+		CreatedFrom: from,
+		After:       &OrderCursor{DateCreated: from.Add(time.Hour), OrderUID: "b563feb7-b2b8-4b6c-9f5d-3b7a1c1d9e10"},
+	}
+
+	where, args := filter.where()
+	expected := " WHERE o.customer_id = $1::text AND o.date_created >= $2::timestamptz" +
+		" AND (o.date_created, o.order_uid) < ($3::timestamptz, $4::uuid)"
+	if where != expected {
+		t.Errorf("Expected %q, but got %q", expected, where)
+	}
+	if len(args) != 4 || args[0] != "test" || args[3] != filter.After.OrderUID {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+}
+
+// Тестирование проверки заказа на соответствие фильтру в памяти
+func TestOrderFilterMatches(t *testing.T) {
+	created := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	order := &models.Order{CustomerID: "test", DeliveryService: "meest", Locale: "en", DateCreated: created}
+
+	cases := map[string]struct {
+		filter   OrderFilter
+		expected bool
+	}{
+		"empty":             {OrderFilter{}, true},
+		"customer":          {OrderFilter{CustomerID: "test"}, true},
+		"other customer":    {OrderFilter{CustomerID: "other"}, false},
+		"delivery service":  {OrderFilter{DeliveryService: "meest", Locale: "en"}, true},
+		"other locale":      {OrderFilter{DeliveryService: "meest", Locale: "ru"}, false},
+		"from inclusive":    {OrderFilter{CreatedFrom: created}, true},
+		"to exclusive":      {OrderFilter{CreatedTo: created}, false},
+		"inside range":      {OrderFilter{CreatedFrom: created.Add(-time.Hour), CreatedTo: created.Add(time.Hour)}, true},
+		"cursor is ignored": {OrderFilter{After: &OrderCursor{DateCreated: created.Add(-time.Hour)}, Limit: 1}, true},
+	}
+
+	for name, tc := range cases {
+		if got := tc.filter.Matches(order); got != tc.expected {
+			t.Errorf("%s: expected %v, but got %v", name, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Ключ идемпотентности не найден или устарел
+var ErrIdempotencyKeyNotFound = errors.New("Idempotency key not found")
+
+// Ключ идемпотентности уже использован другим запросом
+var ErrIdempotencyKeyExists = errors.New("Idempotency key already used")
+
+// Сохраненный результат запроса с ключом идемпотентности
+type IdempotencyRecord struct {
+	Subject     string
+	Key         string
+	RequestHash string
+	OrderUID    string
+	CreatedAt   time.Time
+}
+
+// Получение записи по ключу идемпотентности клиента.
+// Записи старше notBefore считаются устаревшими
+func (s *Storage) GetIdempotencyKey(ctx context.Context, subject, key string, notBefore time.Time) (*IdempotencyRecord, error) {
+	query := `
+		SELECT subject, key, request_hash, order_uid, created_at
+		FROM idempotency_keys
+		WHERE subject = $1 AND key = $2 AND created_at >= $3
+	`
+
+	var record IdempotencyRecord
+	err := s.pool.QueryRow(ctx, query, subject, key, notBefore).Scan(
+		&record.Subject,
+		&record.Key,
+		&record.RequestHash,
+		&record.OrderUID,
+		&record.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, fmt.Errorf("Failed to query idempotency key: %v", err)
+	}
+
+	return &record, nil
+}
+
+// Добавление заказа вместе с ключом идемпотентности в одной транзакции.
+// Устаревшая запись с тем же ключом перезаписывается
+func (s *Storage) AddOrderWithIdempotencyKey(ctx context.Context, order *models.Order, record *IdempotencyRecord, notBefore time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	keyQuery := `
+		INSERT INTO idempotency_keys (subject, key, request_hash, order_uid)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (subject, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, order_uid = EXCLUDED.order_uid, created_at = NOW()
+		WHERE idempotency_keys.created_at < $5
+	`
+	tag, err := tx.Exec(ctx, keyQuery, record.Subject, record.Key, record.RequestHash, record.OrderUID, notBefore)
+	if err != nil {
+		return fmt.Errorf("Failed to insert idempotency key: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrIdempotencyKeyExists
+	}
+
+	if err := insertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// Удаление ключей идемпотентности старше указанного момента
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to purge idempotency keys: %v", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Периодическое удаление устаревших ключей идемпотентности до отмены контекста
+func (s *Storage) RunIdempotencyKeyPurge(ctx context.Context, period, ttl time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := s.PurgeIdempotencyKeys(ctx, now.Add(-ttl))
+			if err != nil {
+				slog.Error("Failed to purge idempotency keys", "err", err)
+			} else if deleted > 0 {
+				log.Printf("Purged %d idempotency keys", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Сообщение противоречит уже сохраненным данным: тот же UID или идентификатор
+// сообщения с другим содержимым
+var ErrMessageConflict = errors.New("Message conflicts with stored order")
+
+// Обработанное сообщение Kafka
+type ProcessedMessage struct {
+	MessageID   string
+	OrderUID    string
+	ContentHash string
+	Topic       string
+	Partition   int
+	Offset      int64
+}
+
+// Добавление заказа из сообщения вместе с отметкой об обработке в одной транзакции.
+// Повторная доставка того же содержимого возвращает duplicate без ошибки,
+// другое содержимое под тем же идентификатором сообщения или UID заказа возвращает ErrMessageConflict
+func (s *Storage) AddOrderFromMessage(ctx context.Context, message *ProcessedMessage, order *models.Order) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Вставка ждет завершения конкурирующей транзакции с тем же идентификатором
+	messageQuery := `
+		INSERT INTO processed_messages (message_id, order_uid, content_hash, topic, kafka_partition, kafka_offset)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (message_id) DO NOTHING
+	`
+	tag, err := tx.Exec(ctx, messageQuery,
+		message.MessageID,
+		message.OrderUID,
+		message.ContentHash,
+		message.Topic,
+		message.Partition,
+		message.Offset,
+	)
+	if err != nil {
+		return false, fmt.Errorf("Failed to insert processed message: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		var orderUID, contentHash string
+		err := tx.QueryRow(ctx, "SELECT order_uid, content_hash FROM processed_messages WHERE message_id = $1", message.MessageID).
+			Scan(&orderUID, &contentHash)
+		if err != nil {
+			return false, fmt.Errorf("Failed to query processed message: %v", err)
+		}
+		return classifyProcessed(message, orderUID, contentHash)
+	}
+
+	// Заказ вставляется в точке сохранения, чтобы после нарушения уникальности
+	// транзакция осталась пригодной для фиксации отметки
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Failed to create savepoint: %v", err)
+	}
+	err = insertOrder(ctx, savepoint, order)
+	if err == nil {
+		err = savepoint.Commit(ctx)
+	}
+	if errors.Is(err, ErrOrderExists) {
+		savepoint.Rollback(ctx)
+
+		existing, err := getOrder(ctx, tx, order.OrderUID)
+		if err != nil {
+			return false, err
+		}
+		if _, err := classifyExisting(message, existing); err != nil {
+			return false, err
+		}
+		// То же содержимое под новым идентификатором сообщения, например после повторной отправки продюсером
+		if err := tx.Commit(ctx); err != nil {
+			return false, fmt.Errorf("Failed to commit transaction: %v", err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("Failed to commit transaction: %v", err)
+	}
+	return false, nil
+}
+
+// Проверка результата добавления заказа из сообщения без изменений в БД.
+// Возвращает то же, что AddOrderFromMessage: duplicate для повторного содержимого или ErrMessageConflict
+func (s *Storage) CheckOrderFromMessage(ctx context.Context, message *ProcessedMessage, order *models.Order) (bool, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return false, fmt.Errorf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var orderUID, contentHash string
+	err = tx.QueryRow(ctx, "SELECT order_uid, content_hash FROM processed_messages WHERE message_id = $1", message.MessageID).
+		Scan(&orderUID, &contentHash)
+	switch {
+	case err == nil:
+		return classifyProcessed(message, orderUID, contentHash)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return false, fmt.Errorf("Failed to query processed message: %v", err)
+	}
+
+	existing, err := getOrder(ctx, tx, order.OrderUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return classifyExisting(message, existing)
+}
+
+// Сообщение с уже обработанным идентификатором: дубликат при том же заказе и содержимом
+func classifyProcessed(message *ProcessedMessage, orderUID, contentHash string) (bool, error) {
+	if orderUID != message.OrderUID || contentHash != message.ContentHash {
+		return false, fmt.Errorf("%w: message %s was processed with order %s", ErrMessageConflict, message.MessageID, orderUID)
+	}
+	return true, nil
+}
+
+// Сообщение с новым идентификатором для уже сохраненного заказа: дубликат при том же содержимом
+func classifyExisting(message *ProcessedMessage, existing *models.Order) (bool, error) {
+	if models.ContentHash(existing) != message.ContentHash {
+		return false, fmt.Errorf("%w: order %s is stored with different content", ErrMessageConflict, existing.OrderUID)
+	}
+	return true, nil
+}
+
+// Получение заказа в рамках транзакции
+func getOrder(ctx context.Context, tx pgx.Tx, orderUID string) (*models.Order, error) {
+	rows, err := tx.Query(ctx, "SELECT "+orderColumns+orderJoins+" WHERE o.order_uid = $1", orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query order: %v", err)
+	}
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("Failed to find order with UID %v: %w", orderUID, pgx.ErrNoRows)
+	}
+
+	rows, err = tx.Query(ctx, itemsByUIDsQuery, []string{orderUID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query items: %v", err)
+	}
+	if err := scanItems(rows, map[string]*models.Order{orderUID: orders[0]}); err != nil {
+		return nil, err
+	}
+	return orders[0], nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование классификации повторного сообщения: дубликат или конфликт
+func TestClassifyMessage(t *testing.T) {
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order: %v", err)
+	}
+	// Время из сообщения с наносекундами и в другом поясе
+	order.DateCreated = time.Date(2024, 3, 1, 15, 4, 5, 123456789, time.FixedZone("MSK", 3*60*60))
+	message := &ProcessedMessage{MessageID: "orders/0/5", OrderUID: order.OrderUID, ContentHash: models.ContentHash(order)}
+
+	// Заказ после сохранения в БД: timestamptz хранит микросекунды, pgx возвращает время в UTC
+	stored := *order
+	stored.DateCreated = order.DateCreated.UTC().Truncate(time.Microsecond)
+	changed := stored
+	changed.TrackNumber = "OTHER"
+
+	tests := []struct {
+		name      string
+		classify  func() (bool, error)
+		duplicate bool
+		conflict  bool
+	}{
+		{"same message", func() (bool, error) {
+			return classifyProcessed(message, order.OrderUID, message.ContentHash)
+		}, true, false},
+		{"message with other order", func() (bool, error) {
+			return classifyProcessed(message, "other", message.ContentHash)
+		}, false, true},
+		{"message with other content", func() (bool, error) {
+			return classifyProcessed(message, order.OrderUID, "other")
+		}, false, true},
+		{"stored order with same content", func() (bool, error) {
+			return classifyExisting(message, &stored)
+		}, true, false},
+		{"stored order with other content", func() (bool, error) {
+			return classifyExisting(message, &changed)
+		}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duplicate, err := tt.classify()
+			if duplicate != tt.duplicate {
+				t.Errorf("Expected duplicate %t, but got %t", tt.duplicate, duplicate)
+			}
+			if errors.Is(err, ErrMessageConflict) != tt.conflict {
+				t.Errorf("Expected conflict %t, but got %v", tt.conflict, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/envelope"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тип события о сохранении заказа для других сервисов
+const OutboxOrderStored = "order.stored"
+
+// Запрос добавления события в outbox
+const insertOutboxQuery = `
+    INSERT INTO outbox (aggregate_key, event_type, payload)
+    VALUES ($1, $2, $3)
+`
+
+// Событие outbox, ожидающее публикации
+type OutboxEvent struct {
+	ID        int64
+	Key       string
+	EventType string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Содержимое события в конверте с версией схемы
+type outboxEnvelope struct {
+	SchemaVersion int           `json:"schema_version"`
+	EventType     string        `json:"event_type"`
+	ProducedAt    time.Time     `json:"produced_at"`
+	Payload       *models.Order `json:"payload"`
+}
+
+// Сериализация события о сохранении заказа
+func orderStoredPayload(order *models.Order) ([]byte, error) {
+	payload, err := json.Marshal(outboxEnvelope{
+		SchemaVersion: envelope.CurrentVersion,
+		EventType:     OutboxOrderStored,
+		ProducedAt:    time.Now().UTC(),
+		Payload:       order,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal outbox event: %v", err)
+	}
+	return payload, nil
+}
+
+// Захват готовых к публикации событий на время аренды.
+// Для каждого ключа берется только самое раннее событие, поэтому события одного заказа
+// публикуются по порядку даже при нескольких экземплярах сервиса и повторах
+func (s *Storage) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]OutboxEvent, error) {
+	query := `
+		UPDATE outbox SET next_attempt_at = now() + $2 * interval '1 millisecond', attempts = attempts + 1
+		WHERE id IN (
+			SELECT o.id FROM outbox o
+			WHERE o.next_attempt_at <= now()
+				AND NOT EXISTS (SELECT 1 FROM outbox e WHERE e.aggregate_key = o.aggregate_key AND e.id < o.id)
+			ORDER BY o.id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, aggregate_key, event_type, payload, attempts, created_at
+	`
+
+	rows, err := s.pool.Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to claim outbox events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var event OutboxEvent
+		if err := rows.Scan(&event.ID, &event.Key, &event.EventType, &event.Payload, &event.Attempts, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("Failed to scan outbox event: %v", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to iterate outbox events: %v", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// Удаление опубликованных событий
+func (s *Storage) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := s.pool.Exec(ctx, "DELETE FROM outbox WHERE id = ANY($1)", ids); err != nil {
+		return fmt.Errorf("Failed to delete outbox events: %v", err)
+	}
+	return nil
+}
+
+// Перенос неудавшейся публикации на более позднее время
+func (s *Storage) RescheduleOutboxEvent(ctx context.Context, id int64, delay time.Duration, cause string) error {
+	query := `
+		UPDATE outbox SET next_attempt_at = now() + $2 * interval '1 millisecond', last_error = $3
+		WHERE id = $1
+	`
+	if _, err := s.pool.Exec(ctx, query, id, delay.Milliseconds(), cause); err != nil {
+		return fmt.Errorf("Failed to reschedule outbox event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Число заказов, читаемых из курсора за один раз
+const exportFetchSize = 500
+
+// Выполнитель запросов: пул соединений или транзакция
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Поиск заказов по фильтру, сначала новые
+func (s *Storage) SearchOrders(ctx context.Context, filter OrderFilter) ([]*models.Order, error) {
+	where, args := filter.where()
+	query := "SELECT " + orderColumns + orderJoins + where + orderFilterOrder
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to search orders: %v", err)
+	}
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, err
+	}
+	if filter.SkipItems {
+		return orders, nil
+	}
+
+	if err := loadItems(ctx, s.pool, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Выгрузка заказов по фильтру через серверный курсор.
+// Заказы читаются порциями в одной транзакции, в памяти находится только текущая порция
+func (s *Storage) ExportOrders(ctx context.Context, filter OrderFilter, fn func(order *models.Order) error) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	where, args := filter.where()
+	query := "SELECT " + orderColumns + orderJoins + where + orderFilterOrder
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+	if _, err := tx.Exec(ctx, "DECLARE orders_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return fmt.Errorf("Failed to declare cursor: %v", err)
+	}
+
+	fetch := "FETCH FORWARD " + strconv.Itoa(exportFetchSize) + " FROM orders_export"
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch orders: %v", err)
+		}
+		orders, err := scanOrders(rows)
+		if err != nil {
+			return err
+		}
+		if len(orders) == 0 {
+			return nil
+		}
+
+		if err := loadItems(ctx, tx, orders); err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Получение товаров нескольких заказов одним запросом.
+// Заказы без товаров не попадают в результат
+func (s *Storage) GetItemsByOrderUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.Item, error) {
+	orders := make([]*models.Order, 0, len(orderUIDs))
+	for _, uid := range orderUIDs {
+		orders = append(orders, &models.Order{OrderUID: uid})
+	}
+	if err := loadItems(ctx, s.pool, orders); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]models.Item, len(orders))
+	for _, order := range orders {
+		if len(order.Items) > 0 {
+			result[order.OrderUID] = order.Items
+		}
+	}
+	return result, nil
+}
+
+// Загрузка товаров для списка заказов одним запросом
+func loadItems(ctx context.Context, q querier, orders []*models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byUID := make(map[string]*models.Order, len(orders))
+	uids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		byUID[order.OrderUID] = order
+		uids = append(uids, order.OrderUID)
+	}
+
+	rows, err := q.Query(ctx, itemsByUIDsQuery, uids)
+	if err != nil {
+		return fmt.Errorf("Failed to query items: %v", err)
+	}
+	return scanItems(rows, byUID)
+}
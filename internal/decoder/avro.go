@@ -0,0 +1,43 @@
+package decoder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+)
+
+// Декодер Avro сообщений в Confluent wire format
+type avroDecoder struct {
+	schemas *schemaCache[avro.Schema]
+}
+
+// Конструктор декодера Avro
+func newAvroDecoder(registry Registry) *avroDecoder {
+	return &avroDecoder{schemas: newSchemaCache(registry, SchemaAvro, func(schema *Schema) (avro.Schema, error) {
+		return avro.Parse(schema.Schema)
+	})}
+}
+
+// Разбор Avro сообщения по схеме писателя из реестра.
+// Объединения с null разворачиваются в значение, timestamp-millis в время
+func (d *avroDecoder) Decode(ctx context.Context, value []byte) (map[string]any, error) {
+	id, payload, err := splitWireFormat(value)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := d.schemas.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+	if err := avro.Unmarshal(schema, payload, &decoded); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal avro: %v", err)
+	}
+	record, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema %d does not describe a record", id)
+	}
+	return record, nil
+}
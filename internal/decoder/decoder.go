@@ -0,0 +1,162 @@
+// Пакет decoder разбирает сообщения Kafka с заказами в форматах JSON, Protobuf и Avro
+package decoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/envelope"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Форматы сообщений
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+)
+
+// Заголовок сообщения с типом содержимого
+const ContentTypeHeader = "content-type"
+
+// Типы содержимого и соответствующие им форматы
+var contentTypes = map[string]string{
+	"application/json":                   FormatJSON,
+	"text/json":                          FormatJSON,
+	"application/x-protobuf":             FormatProtobuf,
+	"application/protobuf":               FormatProtobuf,
+	"application/vnd.google.protobuf":    FormatProtobuf,
+	"application/avro":                   FormatAvro,
+	"avro/binary":                        FormatAvro,
+	"application/vnd.apache.avro+binary": FormatAvro,
+}
+
+// Разбор значения сообщения в запись с полями по именам из схемы
+type Decoder interface {
+	Decode(ctx context.Context, value []byte) (map[string]any, error)
+}
+
+// Разобранное сообщение с заказом текущей версии
+type Message struct {
+	Format        string
+	SchemaVersion int
+	EventType     string
+	ProducedAt    time.Time
+	Order         *models.Order
+}
+
+// Набор декодеров с выбором по заголовку content-type
+type Decoders struct {
+	byFormat      map[string]Decoder
+	defaultFormat string
+	upcasters     *envelope.Upcasters
+}
+
+// Конструктор набора декодеров.
+// Protobuf и Avro доступны только при заданном реестре схем
+func New(defaultFormat string, registry Registry, upcasters *envelope.Upcasters) (*Decoders, error) {
+	d := &Decoders{
+		byFormat:      map[string]Decoder{FormatJSON: jsonDecoder{}},
+		defaultFormat: defaultFormat,
+		upcasters:     upcasters,
+	}
+	if registry != nil {
+		d.byFormat[FormatProtobuf] = newProtobufDecoder(registry)
+		d.byFormat[FormatAvro] = newAvroDecoder(registry)
+	}
+
+	if _, exists := d.byFormat[defaultFormat]; !exists {
+		return nil, fmt.Errorf("Failed to use message format %q: unknown format or no schema registry", defaultFormat)
+	}
+	return d, nil
+}
+
+// Создание набора декодеров по конфигурации
+func FromConfig(cfg *config.Config) (*Decoders, error) {
+	registry, err := NewRegistry(cfg.KafkaSchemaRegistry, cfg.KafkaDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg.KafkaMessageFormat, registry, envelope.Default())
+}
+
+// Определение формата сообщения по заголовку content-type.
+// Без заголовка используется формат по умолчанию
+func (d *Decoders) Format(msg kafka.Message) (string, error) {
+	for _, header := range msg.Headers {
+		if !strings.EqualFold(header.Key, ContentTypeHeader) {
+			continue
+		}
+		mediaType, _, err := mime.ParseMediaType(string(header.Value))
+		if err != nil {
+			return "", fmt.Errorf("malformed content-type %q: %v", header.Value, err)
+		}
+		format, exists := contentTypes[mediaType]
+		if !exists {
+			return "", fmt.Errorf("unsupported content-type %q", mediaType)
+		}
+		return format, nil
+	}
+	return d.defaultFormat, nil
+}
+
+// Разбор сообщения декодером его формата, извлечение содержимого из конверта
+// и миграция до текущей версии
+func (d *Decoders) Decode(ctx context.Context, msg kafka.Message) (*Message, error) {
+	format, err := d.Format(msg)
+	if err != nil {
+		return nil, err
+	}
+	decoder, exists := d.byFormat[format]
+	if !exists {
+		return nil, fmt.Errorf("no decoder for %s messages, schema registry is not configured", format)
+	}
+
+	record, err := decoder.Decode(ctx, msg.Value)
+	if err != nil {
+		return nil, err
+	}
+	unwrapped, err := envelope.Unwrap(record)
+	if err != nil {
+		return nil, err
+	}
+	message := &Message{
+		Format:        format,
+		SchemaVersion: unwrapped.SchemaVersion,
+		EventType:     unwrapped.EventType,
+		ProducedAt:    unwrapped.ProducedAt,
+	}
+	if err := d.upcasters.Upcast(unwrapped); err != nil {
+		return nil, err
+	}
+	if message.Order, err = unwrapped.Order(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// Декодер JSON
+type jsonDecoder struct{}
+
+// Разбор JSON сообщения с сохранением точности чисел
+func (jsonDecoder) Decode(ctx context.Context, value []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+
+	var record map[string]any
+	if err := decoder.Decode(&record); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal JSON: %v", err)
+	}
+	if record == nil {
+		return nil, fmt.Errorf("Failed to unmarshal JSON: message is not an object")
+	}
+	return record, nil
+}
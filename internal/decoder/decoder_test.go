@@ -0,0 +1,234 @@
+package decoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/venexene/wbl0-orders-service/internal/envelope"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+const registryDir = "../../testdata/schema-registry"
+
+// Набор декодеров с тестовым реестром
+func newTestDecoders(t *testing.T) *Decoders {
+	t.Helper()
+
+	registry, err := NewRegistry(registryDir, time.Second)
+	if err != nil {
+		t.Fatalf("Failed to open registry: %v", err)
+	}
+	decoders, err := New(FormatJSON, registry, envelope.Default())
+	if err != nil {
+		t.Fatalf("Failed to create decoders: %v", err)
+	}
+	return decoders
+}
+
+// Заголовок Confluent wire format
+func wireHeader(id byte) []byte {
+	return []byte{0, 0, 0, 0, id}
+}
+
+// Protobuf сообщение с заказом из JSON по схеме 1
+func protobufMessage(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	registry, _ := NewFileRegistry(registryDir)
+	schema, err := registry.SchemaByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	file, err := compileProto(schema)
+	if err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+	message := dynamicpb.NewMessage(file.Messages().ByName("Order"))
+	if err := protojson.Unmarshal(data, message); err != nil {
+		t.Fatalf("Failed to build protobuf message: %v", err)
+	}
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatalf("Failed to marshal protobuf: %v", err)
+	}
+	// Индексы типа: одиночный 0 означает первое сообщение схемы
+	return append(append(wireHeader(1), 0), payload...)
+}
+
+// Avro сообщение с заказом из JSON по схеме 2
+func avroMessage(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	registry, _ := NewFileRegistry(registryDir)
+	schema, err := registry.SchemaByID(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	parsed, err := avro.Parse(schema.Schema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var record map[string]any
+	if err := decoder.Decode(&record); err != nil {
+		t.Fatalf("Failed to parse order: %v", err)
+	}
+	record = avroRecord(record).(map[string]any)
+	created, _ := time.Parse(time.RFC3339, record["date_created"].(string))
+	record["date_created"] = created
+
+	payload, err := avro.Marshal(parsed, record)
+	if err != nil {
+		t.Fatalf("Failed to marshal avro: %v", err)
+	}
+	return append(wireHeader(2), payload...)
+}
+
+// Приведение чисел из JSON к типам Avro
+func avroRecord(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			v[key] = avroRecord(field)
+		}
+		if _, exists := v["sm_id"]; exists {
+			v["sm_id"] = int(v["sm_id"].(int64))
+		}
+		if _, exists := v["status"]; exists {
+			v["status"] = int(v["status"].(int64))
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = avroRecord(v[i])
+		}
+		return v
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	default:
+		return v
+	}
+}
+
+// Тестирование разбора заказа во всех форматах
+func TestDecodeFormats(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	expected, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order: %v", err)
+	}
+
+	decoders := newTestDecoders(t)
+	tests := []struct {
+		name        string
+		contentType string
+		value       []byte
+		format      string
+	}{
+		{"json by default", "", data, FormatJSON},
+		{"json", "application/json; charset=utf-8", data, FormatJSON},
+		{"protobuf", "application/x-protobuf", protobufMessage(t, data), FormatProtobuf},
+		{"avro", "application/avro", avroMessage(t, data), FormatAvro},
+		{"json envelope", "", []byte(`{"schema_version":1,"event_type":"order.created","produced_at":"2025-01-02T03:04:05Z","payload":` + string(data) + `}`), FormatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := kafka.Message{Value: tt.value}
+			if tt.contentType != "" {
+				msg.Headers = []kafka.Header{{Key: "Content-Type", Value: []byte(tt.contentType)}}
+			}
+
+			message, err := decoders.Decode(context.Background(), msg)
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			if message.Format != tt.format || message.SchemaVersion != 1 || message.EventType != events.OrderCreated {
+				t.Errorf("Unexpected message %+v", message)
+			}
+			order := message.Order
+			if !order.DateCreated.Equal(expected.DateCreated) {
+				t.Errorf("Expected date %v, but got %v", expected.DateCreated, order.DateCreated)
+			}
+			order.DateCreated = expected.DateCreated
+			if !reflect.DeepEqual(order, expected) {
+				t.Errorf("Expected %+v, but got %+v", expected, order)
+			}
+			if err := models.NewValidator().Struct(order); err != nil {
+				t.Errorf("Decoded order is invalid: %v", err)
+			}
+		})
+	}
+}
+
+// Тестирование ошибок разбора
+func TestDecodeErrors(t *testing.T) {
+	decoders := newTestDecoders(t)
+	header := func(contentType string) []kafka.Header {
+		return []kafka.Header{{Key: ContentTypeHeader, Value: []byte(contentType)}}
+	}
+
+	tests := []struct {
+		name string
+		msg  kafka.Message
+	}{
+		{"unsupported content type", kafka.Message{Value: []byte("{}"), Headers: header("text/plain")}},
+		{"no wire format header", kafka.Message{Value: []byte("{}"), Headers: header("avro/binary")}},
+		{"wrong schema type", kafka.Message{Value: append(wireHeader(1), 0), Headers: header("avro/binary")}},
+		{"message index out of range", kafka.Message{Value: append(wireHeader(1), 2, 6), Headers: header("application/x-protobuf")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decoders.Decode(context.Background(), tt.msg); err == nil {
+				t.Error("Expected error, but got nil")
+			}
+		})
+	}
+
+	_, err := decoders.Decode(context.Background(), kafka.Message{Value: append(wireHeader(9), 0), Headers: header("application/protobuf")})
+	if !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, but got %v", err)
+	}
+
+	if _, err := New(FormatAvro, nil, envelope.Default()); err == nil {
+		t.Error("Expected error for avro without registry")
+	}
+	withoutRegistry, _ := New(FormatJSON, nil, envelope.Default())
+	if _, err := withoutRegistry.Decode(context.Background(), kafka.Message{Headers: header("application/avro")}); err == nil {
+		t.Error("Expected error for avro message without registry")
+	}
+}
+
+// Тестирование чтения индексов типа сообщения
+func TestReadMessageIndexes(t *testing.T) {
+	indexes, rest, err := readMessageIndexes([]byte{4, 2, 4, 0xff})
+	if err != nil || !reflect.DeepEqual(indexes, []int{1, 2}) || !bytes.Equal(rest, []byte{0xff}) {
+		t.Errorf("Unexpected indexes %v, rest %v, err %v", indexes, rest, err)
+	}
+	indexes, _, err = readMessageIndexes([]byte{0})
+	if err != nil || !reflect.DeepEqual(indexes, []int{0}) {
+		t.Errorf("Unexpected indexes %v, err %v", indexes, err)
+	}
+	if _, _, err := readMessageIndexes([]byte{2}); err == nil {
+		t.Error("Expected error for truncated indexes")
+	}
+}
@@ -0,0 +1,167 @@
+package decoder
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Имя файла, под которым компилируется текст схемы из реестра
+const protoSchemaFile = "schema.proto"
+
+// Декодер Protobuf сообщений в Confluent wire format
+type protobufDecoder struct {
+	schemas *schemaCache[protoreflect.FileDescriptor]
+}
+
+// Конструктор декодера Protobuf
+func newProtobufDecoder(registry Registry) *protobufDecoder {
+	return &protobufDecoder{schemas: newSchemaCache(registry, SchemaProtobuf, compileProto)}
+}
+
+// Разбор Protobuf сообщения: заголовок, индексы типа сообщения в схеме и само сообщение
+func (d *protobufDecoder) Decode(ctx context.Context, value []byte) (map[string]any, error) {
+	id, payload, err := splitWireFormat(value)
+	if err != nil {
+		return nil, err
+	}
+	indexes, payload, err := readMessageIndexes(payload)
+	if err != nil {
+		return nil, err
+	}
+	file, err := d.schemas.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	descriptor, err := messageByIndexes(file, indexes)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %v", id, err)
+	}
+
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal protobuf: %v", err)
+	}
+	return messageToMap(message), nil
+}
+
+// Компиляция текста схемы со стандартными импортами google/protobuf
+func compileProto(schema *Schema) (protoreflect.FileDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{protoSchemaFile: schema.Schema}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), protoSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// Чтение индексов типа сообщения: число индексов и сами индексы в zigzag varint.
+// Одиночный нулевой байт означает первое сообщение файла
+func readMessageIndexes(payload []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, nil, errors.New("malformed protobuf message indexes")
+	}
+	payload = payload[n:]
+	if count == 0 {
+		return []int{0}, payload, nil
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(payload)
+		if n <= 0 || index < 0 {
+			return nil, nil, errors.New("malformed protobuf message indexes")
+		}
+		indexes[i] = int(index)
+		payload = payload[n:]
+	}
+	return indexes, payload, nil
+}
+
+// Поиск типа сообщения по индексам: сообщение верхнего уровня и вложенные в него
+func messageByIndexes(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	messages := file.Messages()
+	var descriptor protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index >= messages.Len() {
+			return nil, fmt.Errorf("message index %v out of range", indexes)
+		}
+		descriptor = messages.Get(index)
+		messages = descriptor.Messages()
+	}
+	return descriptor, nil
+}
+
+// Преобразование сообщения в запись с именами полей из схемы
+func messageToMap(message protoreflect.Message) map[string]any {
+	record := make(map[string]any)
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		record[string(field.Name())] = fieldValue(field, value)
+		return true
+	})
+	return record
+}
+
+// Значение поля с учетом повторяющихся полей и словарей
+func fieldValue(field protoreflect.FieldDescriptor, value protoreflect.Value) any {
+	switch {
+	case field.IsList():
+		list := value.List()
+		values := make([]any, list.Len())
+		for i := range values {
+			values[i] = singularValue(field, list.Get(i))
+		}
+		return values
+	case field.IsMap():
+		values := make(map[string]any)
+		value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			values[key.String()] = singularValue(field.MapValue(), value)
+			return true
+		})
+		return values
+	default:
+		return singularValue(field, value)
+	}
+}
+
+// Значение одиночного поля: Timestamp в RFC 3339, обертки в свое значение, перечисления по имени
+func singularValue(field protoreflect.FieldDescriptor, value protoreflect.Value) any {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		message := value.Message()
+		descriptor := message.Descriptor()
+		switch descriptor.FullName() {
+		case "google.protobuf.Timestamp":
+			fields := descriptor.Fields()
+			seconds := message.Get(fields.ByName("seconds")).Int()
+			nanos := message.Get(fields.ByName("nanos")).Int()
+			return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano)
+		case "google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+			"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+			"google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+			"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+			inner := descriptor.Fields().ByName("value")
+			return singularValue(inner, message.Get(inner))
+		}
+		return messageToMap(message)
+	case protoreflect.EnumKind:
+		if enum := field.Enum().Values().ByNumber(value.Enum()); enum != nil {
+			return string(enum.Name())
+		}
+		return int32(value.Enum())
+	default:
+		return value.Interface()
+	}
+}
@@ -0,0 +1,190 @@
+package decoder
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Типы схем в ответах реестра, пустой тип означает Avro
+const (
+	SchemaAvro     = "AVRO"
+	SchemaProtobuf = "PROTOBUF"
+)
+
+// Схема с заданным идентификатором отсутствует в реестре
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// Схема из реестра в формате ответа GET /schemas/ids/{id}
+type Schema struct {
+	ID     int    `json:"-"`
+	Type   string `json:"schemaType"`
+	Schema string `json:"schema"`
+}
+
+// Реестр схем, совместимый с Confluent Schema Registry
+type Registry interface {
+	SchemaByID(ctx context.Context, id int) (*Schema, error)
+}
+
+// Создание реестра по адресу: URL сервиса или каталог с файлами.
+// Пустой адрес означает отсутствие реестра
+func NewRegistry(location string, timeout time.Duration) (Registry, error) {
+	switch {
+	case location == "":
+		return nil, nil
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		return NewHTTPRegistry(location, timeout), nil
+	default:
+		return NewFileRegistry(location)
+	}
+}
+
+// Реестр в каталоге с файлами schemas/ids/<id>.json в формате ответа сервиса.
+// Используется для локальной разработки и тестов вместо сервиса
+type FileRegistry struct {
+	dir string
+}
+
+// Конструктор реестра в каталоге
+func NewFileRegistry(dir string) (*FileRegistry, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open schema registry %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("Failed to open schema registry %s: not a directory", dir)
+	}
+	return &FileRegistry{dir: dir}, nil
+}
+
+// Чтение схемы из файла
+func (r *FileRegistry) SchemaByID(ctx context.Context, id int) (*Schema, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, "schemas", "ids", strconv.Itoa(id)+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read schema %d: %v", id, err)
+	}
+	return parseSchema(id, data)
+}
+
+// Реестр, обращающийся к сервису по HTTP
+type HTTPRegistry struct {
+	url    string
+	client *http.Client
+}
+
+// Конструктор реестра с адресом сервиса
+func NewHTTPRegistry(url string, timeout time.Duration) *HTTPRegistry {
+	return &HTTPRegistry{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Запрос схемы у сервиса
+func (r *HTTPRegistry) SchemaByID(ctx context.Context, id int) (*Schema, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+"/schemas/ids/"+strconv.Itoa(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create schema request: %v", err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to request schema %d: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to request schema %d: status %d", id, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read schema %d: %v", id, err)
+	}
+	return parseSchema(id, data)
+}
+
+// Разбор ответа реестра
+func parseSchema(id int, data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("Failed to parse schema %d: %v", id, err)
+	}
+	if schema.Type == "" {
+		schema.Type = SchemaAvro
+	}
+	schema.ID = id
+	return &schema, nil
+}
+
+// Разбор заголовка Confluent wire format: нулевой байт и идентификатор схемы
+func splitWireFormat(value []byte) (int, []byte, error) {
+	if len(value) < 5 || value[0] != 0 {
+		return 0, nil, errors.New("message is not in schema registry wire format")
+	}
+	return int(binary.BigEndian.Uint32(value[1:5])), value[5:], nil
+}
+
+// Кэш разобранных схем по идентификатору
+type schemaCache[T any] struct {
+	registry Registry
+	kind     string
+	parse    func(schema *Schema) (T, error)
+
+	mu   sync.Mutex
+	byID map[int]T
+}
+
+// Конструктор кэша схем заданного типа
+func newSchemaCache[T any](registry Registry, kind string, parse func(schema *Schema) (T, error)) *schemaCache[T] {
+	return &schemaCache[T]{
+		registry: registry,
+		kind:     kind,
+		parse:    parse,
+		byID:     make(map[int]T),
+	}
+}
+
+// Получение разобранной схемы, при первом обращении схема запрашивается в реестре
+func (c *schemaCache[T]) get(ctx context.Context, id int) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if parsed, exists := c.byID[id]; exists {
+		return parsed, nil
+	}
+
+	var zero T
+	schema, err := c.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return zero, err
+	}
+	if schema.Type != c.kind {
+		return zero, fmt.Errorf("schema %d has type %s, expected %s", id, schema.Type, c.kind)
+	}
+	parsed, err := c.parse(schema)
+	if err != nil {
+		return zero, fmt.Errorf("Failed to parse schema %d: %v", id, err)
+	}
+
+	c.byID[id] = parsed
+	return parsed, nil
+}
@@ -0,0 +1,155 @@
+// Пакет dto описывает стабильные структуры ответов API, независимые от доменной модели
+package dto
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Заказ в ответе API
+type OrderResponse struct {
+	XMLName           xml.Name         `json:"-" xml:"order"`
+	OrderUID          string           `json:"order_uid" xml:"order_uid"`
+	TrackNumber       string           `json:"track_number" xml:"track_number"`
+	Entry             string           `json:"entry" xml:"entry"`
+	Locale            string           `json:"locale" xml:"locale"`
+	InternalSignature string           `json:"internal_signature" xml:"internal_signature"`
+	CustomerID        string           `json:"customer_id" xml:"customer_id"`
+	DeliveryService   string           `json:"delivery_service" xml:"delivery_service"`
+	ShardKey          string           `json:"shardkey" xml:"shardkey"`
+	SMID              uint             `json:"sm_id" xml:"sm_id"`
+	DateCreated       time.Time        `json:"date_created" xml:"date_created"`
+	OOFShard          string           `json:"oof_shard" xml:"oof_shard"`
+	Delivery          DeliveryResponse `json:"delivery" xml:"delivery"`
+	Payment           PaymentResponse  `json:"payment" xml:"payment"`
+	Items             []ItemResponse   `json:"items" xml:"items>item"`
+}
+
+// Доставка в ответе API. Контакты могут быть частично скрыты
+type DeliveryResponse struct {
+	Name    string `json:"name" xml:"name"`
+	Phone   string `json:"phone" xml:"phone"`
+	Zip     string `json:"zip" xml:"zip"`
+	City    string `json:"city" xml:"city"`
+	Address string `json:"address" xml:"address"`
+	Region  string `json:"region" xml:"region"`
+	Email   string `json:"email" xml:"email"`
+}
+
+// Оплата в ответе API. Скрытые для роли поля не выводятся
+type PaymentResponse struct {
+	Transaction  string `json:"transaction,omitempty" xml:"transaction,omitempty"`
+	RequestID    string `json:"request_id" xml:"request_id"`
+	Currency     string `json:"currency" xml:"currency"`
+	Provider     string `json:"provider" xml:"provider"`
+	Amount       *int   `json:"amount,omitempty" xml:"amount,omitempty"`
+	PaymentDt    uint64 `json:"payment_dt" xml:"payment_dt"`
+	Bank         string `json:"bank" xml:"bank"`
+	DeliveryCost *uint  `json:"delivery_cost,omitempty" xml:"delivery_cost,omitempty"`
+	GoodsTotal   *uint  `json:"goods_total,omitempty" xml:"goods_total,omitempty"`
+	CustomFee    *uint  `json:"custom_fee,omitempty" xml:"custom_fee,omitempty"`
+}
+
+// Товар в ответе API
+type ItemResponse struct {
+	ChrtID      uint   `json:"chrt_id" xml:"chrt_id"`
+	TrackNumber string `json:"track_number" xml:"track_number"`
+	Price       uint   `json:"price" xml:"price"`
+	Rid         string `json:"rid" xml:"rid"`
+	Name        string `json:"name" xml:"name"`
+	Sale        uint   `json:"sale" xml:"sale"`
+	Size        string `json:"size" xml:"size"`
+	TotalPrice  uint   `json:"total_price" xml:"total_price"`
+	NmID        uint   `json:"nm_id" xml:"nm_id"`
+	Brand       string `json:"brand" xml:"brand"`
+	Status      uint   `json:"status" xml:"status"`
+}
+
+// Список UID заказов в ответе API
+type OrderUIDsResponse struct {
+	OrderUIDs []string `json:"order_uids"`
+}
+
+// Запрос на получение нескольких заказов
+type BatchGetOrdersRequest struct {
+	OrderUIDs []string `json:"order_uids"`
+}
+
+// Найденные заказы и UID, которых нет в хранилище
+type BatchGetOrdersResponse struct {
+	Orders      []OrderResponse `json:"orders"`
+	MissingUIDs []string        `json:"missing_uids"`
+}
+
+// Страница результатов поиска заказов
+type OrderSearchResponse struct {
+	Orders        []OrderResponse `json:"orders"`
+	NextPageToken string          `json:"next_page_token,omitempty"`
+}
+
+// Преобразование доменного заказа в ответ API.
+// Нулевые суммы считаются скрытыми, так как валидный заказ их не содержит
+func FromOrder(order *models.Order) OrderResponse {
+	items := make([]ItemResponse, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, ItemResponse{
+			ChrtID:      item.ChrtID,
+			TrackNumber: item.TrackNumber,
+			Price:       item.Price,
+			Rid:         item.Rid,
+			Name:        item.Name,
+			Sale:        item.Sale,
+			Size:        item.Size,
+			TotalPrice:  item.TotalPrice,
+			NmID:        item.NmID,
+			Brand:       item.Brand,
+			Status:      item.Status,
+		})
+	}
+
+	return OrderResponse{
+		OrderUID:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerID:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		ShardKey:          order.ShardKey,
+		SMID:              order.SMID,
+		DateCreated:       order.DateCreated,
+		OOFShard:          order.OOFShard,
+		Delivery: DeliveryResponse{
+			Name:    order.Delivery.Name,
+			Phone:   order.Delivery.Phone,
+			Zip:     order.Delivery.Zip,
+			City:    order.Delivery.City,
+			Address: order.Delivery.Address,
+			Region:  order.Delivery.Region,
+			Email:   order.Delivery.Email,
+		},
+		Payment: PaymentResponse{
+			Transaction:  order.Payment.Transaction,
+			RequestID:    order.Payment.RequestID,
+			Currency:     order.Payment.Currency,
+			Provider:     order.Payment.Provider,
+			Amount:       nonZero(order.Payment.Amount),
+			PaymentDt:    order.Payment.PaymentDt,
+			Bank:         order.Payment.Bank,
+			DeliveryCost: nonZero(order.Payment.DeliveryCost),
+			GoodsTotal:   nonZero(order.Payment.GoodsTotal),
+			CustomFee:    nonZero(order.Payment.CustomFee),
+		},
+		Items: items,
+	}
+}
+
+// Указатель на значение или nil для нулевого значения
+func nonZero[T int | uint](value T) *T {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
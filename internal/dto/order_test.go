@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование преобразования заказа в ответ API
+func TestFromOrder(t *testing.T) {
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+
+	response := FromOrder(order)
+	if response.OrderUID != order.OrderUID || len(response.Items) != len(order.Items) {
+		t.Fatalf("Expected order %s with %d items, but got %s with %d", order.OrderUID, len(order.Items), response.OrderUID, len(response.Items))
+	}
+	if response.Payment.Amount == nil || *response.Payment.Amount != order.Payment.Amount {
+		t.Errorf("Expected amount %d, but got %v", order.Payment.Amount, response.Payment.Amount)
+	}
+
+	// Скрытые маскированием поля не выводятся
+	order.Payment.Transaction = ""
+	order.Payment.Amount = 0
+	order.Payment.DeliveryCost = 0
+	order.Payment.GoodsTotal = 0
+	order.Payment.CustomFee = 0
+
+	body, err := json.Marshal(FromOrder(order))
+	if err != nil {
+		t.Fatalf("Failed to marshal response: %v", err)
+	}
+	for _, field := range []string{"transaction", "amount", "delivery_cost", "goods_total", "custom_fee"} {
+		if strings.Contains(string(body), `"`+field+`"`) {
+			t.Errorf("Expected hidden field %s to be omitted", field)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Пакет envelope описывает версионированный конверт сообщений с заказами
+// и миграцию содержимого старых версий в текущую models.Order
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Текущая версия содержимого, соответствующая models.Order.
+// Заказы без конверта считаются версией 1
+const CurrentVersion = 1
+
+// Ошибки разбора конверта и миграции версий
+var (
+	ErrMalformedEnvelope  = errors.New("malformed message envelope")
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+)
+
+// Конверт сообщения
+type Envelope struct {
+	SchemaVersion int            `json:"schema_version"`
+	EventType     string         `json:"event_type"`
+	ProducedAt    time.Time      `json:"produced_at"`
+	Payload       map[string]any `json:"payload"`
+}
+
+// Разбор записи сообщения в конверт.
+// Запись без полей schema_version и payload считается заказом версии 1 без конверта
+func Unwrap(record map[string]any) (*Envelope, error) {
+	_, hasVersion := record["schema_version"]
+	_, hasPayload := record["payload"]
+	if !hasVersion && !hasPayload {
+		return &Envelope{SchemaVersion: 1, EventType: events.OrderCreated, Payload: record}, nil
+	}
+
+	var envelope Envelope
+	if err := convert(record, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedEnvelope, err)
+	}
+	if envelope.SchemaVersion < 1 {
+		return nil, fmt.Errorf("%w: schema_version must be positive", ErrMalformedEnvelope)
+	}
+	if envelope.Payload == nil {
+		return nil, fmt.Errorf("%w: payload must be an object", ErrMalformedEnvelope)
+	}
+	if envelope.EventType == "" {
+		envelope.EventType = events.OrderCreated
+	}
+	return &envelope, nil
+}
+
+// Преобразование содержимого в заказ, вызывается после миграции до текущей версии
+func (e *Envelope) Order() (*models.Order, error) {
+	var order models.Order
+	if err := convert(e.Payload, &order); err != nil {
+		return nil, fmt.Errorf("Failed to convert payload: %v", err)
+	}
+	return &order, nil
+}
+
+// Преобразование значения через JSON с сохранением точности чисел
+func convert(value any, target any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
@@ -0,0 +1,113 @@
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Загрузка заказа из тестового файла в виде записи
+func loadRecord(t *testing.T) map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Failed to parse order: %v", err)
+	}
+	return record
+}
+
+// Тестирование заказа без конверта
+func TestUnwrapRawOrder(t *testing.T) {
+	envelope, err := Unwrap(loadRecord(t))
+	if err != nil {
+		t.Fatalf("Failed to unwrap: %v", err)
+	}
+	if envelope.SchemaVersion != 1 || envelope.EventType != events.OrderCreated {
+		t.Errorf("Unexpected envelope %+v", envelope)
+	}
+
+	order, err := envelope.Order()
+	if err != nil {
+		t.Fatalf("Failed to convert order: %v", err)
+	}
+	expected, _ := models.LoadOrderFromFile("../../testdata/order1.json")
+	if order.OrderUID != expected.OrderUID || order.Payment.Amount != expected.Payment.Amount || len(order.Items) != len(expected.Items) {
+		t.Errorf("Expected %+v, but got %+v", expected, order)
+	}
+}
+
+// Тестирование разбора конверта
+func TestUnwrapEnvelope(t *testing.T) {
+	producedAt := "2025-01-02T03:04:05Z"
+	envelope, err := Unwrap(map[string]any{
+		"schema_version": json.Number("2"),
+		"event_type":     "order.updated",
+		"produced_at":    producedAt,
+		"payload":        loadRecord(t),
+	})
+	if err != nil {
+		t.Fatalf("Failed to unwrap: %v", err)
+	}
+	expected, _ := time.Parse(time.RFC3339, producedAt)
+	if envelope.SchemaVersion != 2 || envelope.EventType != "order.updated" || !envelope.ProducedAt.Equal(expected) {
+		t.Errorf("Unexpected envelope %+v", envelope)
+	}
+
+	malformed := []map[string]any{
+		{"schema_version": 1},
+		{"schema_version": 0, "payload": map[string]any{}},
+		{"schema_version": "one", "payload": map[string]any{}},
+		{"schema_version": 1, "payload": []any{}},
+	}
+	for _, record := range malformed {
+		if _, err := Unwrap(record); !errors.Is(err, ErrMalformedEnvelope) {
+			t.Errorf("Expected ErrMalformedEnvelope for %v, but got %v", record, err)
+		}
+	}
+}
+
+// Тестирование цепочки миграций: переименование поля и переход к нескольким оплатам
+func TestUpcast(t *testing.T) {
+	upcasters := NewUpcasters(3)
+	upcasters.Register(1, func(payload map[string]any) (map[string]any, error) {
+		payload["customer"] = payload["customer_id"]
+		delete(payload, "customer_id")
+		return payload, nil
+	})
+	upcasters.Register(2, func(payload map[string]any) (map[string]any, error) {
+		payload["payments"] = []any{payload["payment"]}
+		delete(payload, "payment")
+		return payload, nil
+	})
+
+	envelope, _ := Unwrap(loadRecord(t))
+	if err := upcasters.Upcast(envelope); err != nil {
+		t.Fatalf("Failed to upcast: %v", err)
+	}
+	if envelope.SchemaVersion != 3 || envelope.Payload["customer"] != "test" || len(envelope.Payload["payments"].([]any)) != 1 {
+		t.Errorf("Unexpected payload after upcast %+v", envelope)
+	}
+
+	// Текущая версия не мигрирует
+	current := &Envelope{SchemaVersion: 3, Payload: map[string]any{}}
+	if err := upcasters.Upcast(current); err != nil || current.SchemaVersion != 3 {
+		t.Errorf("Unexpected upcast of current version: %v", err)
+	}
+
+	if err := upcasters.Upcast(&Envelope{SchemaVersion: 4, Payload: map[string]any{}}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion for newer version, but got %v", err)
+	}
+	if err := NewUpcasters(2).Upcast(&Envelope{SchemaVersion: 1, Payload: map[string]any{}}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion for missing upcaster, but got %v", err)
+	}
+}
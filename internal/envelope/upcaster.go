@@ -0,0 +1,61 @@
+package envelope
+
+import (
+	"fmt"
+)
+
+// Миграция содержимого версии N в версию N+1
+type Upcaster func(payload map[string]any) (map[string]any, error)
+
+// Реестр миграций по исходной версии
+type Upcasters struct {
+	current int
+	steps   map[int]Upcaster
+}
+
+// Конструктор реестра миграций до заданной текущей версии
+func NewUpcasters(current int) *Upcasters {
+	return &Upcasters{
+		current: current,
+		steps:   make(map[int]Upcaster),
+	}
+}
+
+// Реестр миграций сервиса.
+// При изменении формата содержимого CurrentVersion увеличивается,
+// а миграция из предыдущей версии регистрируется здесь
+func Default() *Upcasters {
+	return NewUpcasters(CurrentVersion)
+}
+
+// Регистрация миграции из версии from в версию from+1
+func (u *Upcasters) Register(from int, upcaster Upcaster) {
+	if from < 1 || from >= u.current {
+		panic(fmt.Sprintf("upcaster from version %d outside of 1..%d", from, u.current-1))
+	}
+	if _, exists := u.steps[from]; exists {
+		panic(fmt.Sprintf("upcaster from version %d already registered", from))
+	}
+	u.steps[from] = upcaster
+}
+
+// Последовательная миграция содержимого конверта до текущей версии
+func (u *Upcasters) Upcast(envelope *Envelope) error {
+	if envelope.SchemaVersion > u.current {
+		return fmt.Errorf("%w: version %d is newer than %d", ErrUnsupportedVersion, envelope.SchemaVersion, u.current)
+	}
+
+	for envelope.SchemaVersion < u.current {
+		upcaster, exists := u.steps[envelope.SchemaVersion]
+		if !exists {
+			return fmt.Errorf("%w: no upcaster from version %d", ErrUnsupportedVersion, envelope.SchemaVersion)
+		}
+		payload, err := upcaster(envelope.Payload)
+		if err != nil {
+			return fmt.Errorf("Failed to upcast from version %d: %v", envelope.SchemaVersion, err)
+		}
+		envelope.Payload = payload
+		envelope.SchemaVersion++
+	}
+	return nil
+}
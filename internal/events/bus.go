@@ -0,0 +1,161 @@
+// Пакет events раздает события о заказах подписчикам внутри процесса
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Типы событий
+const (
+	OrderCreated = "order.created"
+)
+
+// Событие о заказе
+type Event struct {
+	ID    uint64
+	Type  string
+	Time  time.Time
+	Order *models.Order
+}
+
+// Шина событий с кольцом последних событий для повторной отправки
+type Bus struct {
+	mu     sync.Mutex
+	nextID uint64
+	ring   []Event
+	start  int // Индекс самого старого события в кольце
+	count  int
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Конструктор шины, хранящей replaySize последних событий.
+// Нумерация начинается с текущего времени в микросекундах, поэтому
+// идентификаторы после перезапуска больше прежних, и разрыв виден клиенту
+func NewBus(replaySize int) *Bus {
+	return &Bus{
+		nextID: uint64(time.Now().UnixMicro()),
+		ring:   make([]Event, replaySize),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Публикация события без ожидания подписчиков.
+// Подписка с заполненным буфером закрывается, клиент продолжит с Last-Event-ID.
+// Публикация в отсутствующую шину ничего не делает
+func (b *Bus) Publish(eventType string, order *models.Order) Event {
+	if b == nil {
+		return Event{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, Time: time.Now().UTC(), Order: order}
+	if b.closed {
+		return event
+	}
+
+	if len(b.ring) > 0 {
+		if b.count < len(b.ring) {
+			b.ring[(b.start+b.count)%len(b.ring)] = event
+			b.count++
+		} else {
+			b.ring[b.start] = event
+			b.start = (b.start + 1) % len(b.ring)
+		}
+	}
+
+	for sub := range b.subs {
+		if sub.match != nil && !sub.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.unsubscribe(sub, ErrOverflow)
+		}
+	}
+	return event
+}
+
+// Подписка на события, подходящие под match (nil - все события).
+// При lastID > 0 сначала возвращаются пропущенные события из кольца;
+// gap означает, что часть событий после lastID уже вытеснена из кольца
+func (b *Bus) Subscribe(match func(Event) bool, buffer int, lastID uint64) (sub *Subscription, replay []Event, gap bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{bus: b, ch: make(chan Event, buffer), match: match}
+	if b.closed {
+		sub.err = ErrClosed
+		close(sub.ch)
+		return sub, nil, false
+	}
+	b.subs[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, false
+	}
+	// Идентификатор из будущего остался от другого процесса
+	if lastID > b.nextID {
+		return sub, nil, true
+	}
+
+	gap = lastID < b.nextID
+	for i := 0; i < b.count; i++ {
+		event := b.ring[(b.start+i)%len(b.ring)]
+		if event.ID <= lastID {
+			continue
+		}
+		if event.ID == lastID+1 {
+			gap = false
+		}
+		if match == nil || match(event) {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, gap
+}
+
+// Закрытие всех подписок при остановке сервера
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.unsubscribe(sub, ErrClosed)
+	}
+}
+
+// Идентификатор последнего опубликованного события.
+// Страница, отрисованная с ним, продолжает поток без пропусков
+func (b *Bus) LastID() uint64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nextID
+}
+
+// Число активных подписок
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Удаление подписки, вызывается под блокировкой шины
+func (b *Bus) unsubscribe(sub *Subscription, err error) {
+	if _, exists := b.subs[sub]; !exists {
+		return
+	}
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование доставки событий с фильтром
+func TestBusPublishSubscribe(t *testing.T) {
+	bus := NewBus(10)
+
+	all, _, _ := bus.Subscribe(nil, 10, 0)
+	meest, _, _ := bus.Subscribe(func(e Event) bool { return e.Order.DeliveryService == "meest" }, 10, 0)
+
+	first := bus.Publish(OrderCreated, &models.Order{OrderUID: "1", DeliveryService: "meest"})
+	second := bus.Publish(OrderCreated, &models.Order{OrderUID: "2", DeliveryService: "dhl"})
+	if second.ID != first.ID+1 {
+		t.Errorf("Expected sequential IDs, but got %d and %d", first.ID, second.ID)
+	}
+
+	if len(all.Events()) != 2 {
+		t.Errorf("Expected 2 events for unfiltered subscriber, but got %d", len(all.Events()))
+	}
+	if len(meest.Events()) != 1 || (<-meest.Events()).Order.OrderUID != "1" {
+		t.Error("Expected only matching event for filtered subscriber")
+	}
+
+	// Канал закрывается после уже полученных событий
+	all.Close()
+	for range all.Events() {
+	}
+	if all.Err() != nil {
+		t.Errorf("Expected closed subscription without error, but got %v", all.Err())
+	}
+	if bus.Subscribers() != 1 {
+		t.Errorf("Expected 1 subscriber, but got %d", bus.Subscribers())
+	}
+}
+
+// Тестирование закрытия медленного подписчика при переполнении буфера
+func TestBusOverflow(t *testing.T) {
+	bus := NewBus(10)
+	slow, _, _ := bus.Subscribe(nil, 1, 0)
+
+	bus.Publish(OrderCreated, &models.Order{OrderUID: "1"})
+	bus.Publish(OrderCreated, &models.Order{OrderUID: "2"})
+
+	<-slow.Events()
+	if _, ok := <-slow.Events(); ok {
+		t.Fatal("Expected subscription to be closed after overflow")
+	}
+	if !errors.Is(slow.Err(), ErrOverflow) {
+		t.Errorf("Expected overflow error, but got %v", slow.Err())
+	}
+}
+
+// Тестирование повторной отправки событий после Last-Event-ID
+func TestBusReplay(t *testing.T) {
+	bus := NewBus(3)
+
+	var ids []uint64
+	for _, uid := range []string{"1", "2", "3", "4", "5"} {
+		ids = append(ids, bus.Publish(OrderCreated, &models.Order{OrderUID: uid}).ID)
+	}
+
+	cases := []struct {
+		name   string
+		lastID uint64
+		replay []string
+		gap    bool
+	}{
+		{"no resume", 0, nil, false},
+		{"inside ring", ids[2], []string{"4", "5"}, false},
+		{"ring start", ids[1], []string{"3", "4", "5"}, false},
+		{"evicted", ids[0], []string{"3", "4", "5"}, true},
+		{"up to date", ids[4], nil, false},
+		{"other process", ids[4] + 100, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub, replay, gap := bus.Subscribe(nil, 1, tc.lastID)
+			defer sub.Close()
+
+			var uids []string
+			for _, event := range replay {
+				uids = append(uids, event.Order.OrderUID)
+			}
+			if len(uids) != len(tc.replay) || gap != tc.gap {
+				t.Fatalf("Expected replay %v with gap %v, but got %v with gap %v", tc.replay, tc.gap, uids, gap)
+			}
+			for i := range uids {
+				if uids[i] != tc.replay[i] {
+					t.Errorf("Expected replay %v, but got %v", tc.replay, uids)
+				}
+			}
+		})
+	}
+}
+
+// Тестирование закрытия подписок при остановке шины
+func TestBusClose(t *testing.T) {
+	bus := NewBus(1)
+	sub, _, _ := bus.Subscribe(nil, 1, 0)
+
+	bus.Close()
+	if _, ok := <-sub.Events(); ok || !errors.Is(sub.Err(), ErrClosed) {
+		t.Errorf("Expected subscription closed with ErrClosed, but got %v", sub.Err())
+	}
+
+	late, _, _ := bus.Subscribe(nil, 1, 0)
+	if _, ok := <-late.Events(); ok {
+		t.Error("Expected subscription to closed bus to be closed")
+	}
+	bus.Publish(OrderCreated, &models.Order{OrderUID: "1"})
+}
@@ -0,0 +1,34 @@
+package events
+
+import "errors"
+
+// Причины закрытия подписки
+var (
+	ErrOverflow = errors.New("Subscriber buffer overflow")
+	ErrClosed   = errors.New("Event bus closed")
+)
+
+// Подписка на события шины с ограниченным буфером
+type Subscription struct {
+	bus   *Bus
+	ch    chan Event
+	match func(Event) bool
+	err   error
+}
+
+// Канал событий, закрывается при отписке, переполнении или остановке шины
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Причина закрытия подписки, доступна после закрытия канала событий
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Отписка от событий
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.unsubscribe(s, nil)
+}
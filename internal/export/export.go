@@ -0,0 +1,69 @@
+// Пакет export записывает заказы в форматах выгрузки NDJSON, CSV и Parquet
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+)
+
+// Формат выгрузки
+type Format string
+
+const (
+	FormatNDJSON  Format = "ndjson"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// Разбор формата выгрузки, пустое значение означает NDJSON
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatNDJSON:
+		return FormatNDJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatParquet:
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("Unknown export format %q, expected ndjson, csv or parquet", s)
+	}
+}
+
+// Тип содержимого ответа
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// Расширение файла выгрузки
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Запись заказов в формате выгрузки
+type Writer interface {
+	Write(order *dto.OrderResponse) error
+	Close() error
+}
+
+// Создание записи в выбранном формате
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, fmt.Errorf("Unknown export format %q", format)
+	}
+}
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Загрузка тестового заказа с двумя товарами и скрытой суммой
+func testOrder(t *testing.T) *dto.OrderResponse {
+	t.Helper()
+
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+	second := order.Items[0]
+	second.ChrtID++
+	order.Items = append(order.Items, second)
+	order.Payment.Amount = 0
+
+	response := dto.FromOrder(order)
+	return &response
+}
+
+// Запись заказа в выбранном формате
+func write(t *testing.T, format Format, order *dto.OrderResponse) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("Failed to create %s writer: %v", format, err)
+	}
+	if err := writer.Write(order); err != nil {
+		t.Fatalf("Failed to write %s: %v", format, err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close %s writer: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+// Тестирование разбора формата
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat(""); err != nil || format != FormatNDJSON {
+		t.Errorf("Expected default format ndjson, but got %q, %v", format, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+// Тестирование выгрузки NDJSON
+func TestNDJSONWriter(t *testing.T) {
+	order := testOrder(t)
+	lines := strings.Split(strings.TrimSpace(string(write(t, FormatNDJSON, order))), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line per order, but got %d", len(lines))
+	}
+
+	var decoded dto.OrderResponse
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal line: %v", err)
+	}
+	if decoded.OrderUID != order.OrderUID || decoded.Payment.Amount != nil {
+		t.Errorf("Unexpected decoded order %+v", decoded)
+	}
+}
+
+// Тестирование выгрузки CSV со строкой на каждый товар
+func TestCSVWriter(t *testing.T) {
+	order := testOrder(t)
+	records, err := csv.NewReader(bytes.NewReader(write(t, FormatCSV, order))).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 item rows, but got %d records", len(records))
+	}
+
+	header := records[0]
+	index := func(name string) int {
+		for i, column := range header {
+			if column == name {
+				return i
+			}
+		}
+		t.Fatalf("No column %s in header", name)
+		return -1
+	}
+
+	if records[1][index("order_uid")] != order.OrderUID || records[2][index("order_uid")] != order.OrderUID {
+		t.Error("Expected order UID repeated on every item row")
+	}
+	if records[1][index("item_chrt_id")] == records[2][index("item_chrt_id")] {
+		t.Error("Expected different items on item rows")
+	}
+	if records[1][index("payment_amount")] != "" {
+		t.Errorf("Expected hidden amount to be empty, but got %q", records[1][index("payment_amount")])
+	}
+}
+
+// Тестирование выгрузки Parquet
+func TestParquetWriter(t *testing.T) {
+	order := testOrder(t)
+	data := write(t, FormatParquet, order)
+
+	rows, err := parquet.Read[Row](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Failed to read Parquet: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, but got %d", len(rows))
+	}
+	if rows[0].OrderUID != order.OrderUID || !rows[0].DateCreated.Equal(order.DateCreated) {
+		t.Errorf("Unexpected first row %+v", rows[0])
+	}
+	if rows[0].PaymentAmount != nil || rows[0].PaymentGoodsTotal == nil {
+		t.Error("Expected hidden amount to be null and goods total to be present")
+	}
+}
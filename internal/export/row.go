@@ -0,0 +1,168 @@
+package export
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+)
+
+// Плоская строка выгрузки: заказ с доставкой и оплатой и один товар.
+// Скрытые для роли суммы остаются пустыми
+type Row struct {
+	OrderUID          string    `parquet:"order_uid"`
+	TrackNumber       string    `parquet:"track_number"`
+	Entry             string    `parquet:"entry"`
+	Locale            string    `parquet:"locale"`
+	InternalSignature string    `parquet:"internal_signature"`
+	CustomerID        string    `parquet:"customer_id"`
+	DeliveryService   string    `parquet:"delivery_service"`
+	ShardKey          string    `parquet:"shardkey"`
+	SMID              uint64    `parquet:"sm_id"`
+	DateCreated       time.Time `parquet:"date_created,timestamp(millisecond)"`
+	OOFShard          string    `parquet:"oof_shard"`
+
+	DeliveryName    string `parquet:"delivery_name"`
+	DeliveryPhone   string `parquet:"delivery_phone"`
+	DeliveryZip     string `parquet:"delivery_zip"`
+	DeliveryCity    string `parquet:"delivery_city"`
+	DeliveryAddress string `parquet:"delivery_address"`
+	DeliveryRegion  string `parquet:"delivery_region"`
+	DeliveryEmail   string `parquet:"delivery_email"`
+
+	PaymentTransaction  string  `parquet:"payment_transaction"`
+	PaymentRequestID    string  `parquet:"payment_request_id"`
+	PaymentCurrency     string  `parquet:"payment_currency"`
+	PaymentProvider     string  `parquet:"payment_provider"`
+	PaymentAmount       *int64  `parquet:"payment_amount,optional"`
+	PaymentDt           uint64  `parquet:"payment_dt"`
+	PaymentBank         string  `parquet:"payment_bank"`
+	PaymentDeliveryCost *uint64 `parquet:"payment_delivery_cost,optional"`
+	PaymentGoodsTotal   *uint64 `parquet:"payment_goods_total,optional"`
+	PaymentCustomFee    *uint64 `parquet:"payment_custom_fee,optional"`
+
+	ItemChrtID      uint64 `parquet:"item_chrt_id"`
+	ItemTrackNumber string `parquet:"item_track_number"`
+	ItemPrice       uint64 `parquet:"item_price"`
+	ItemRid         string `parquet:"item_rid"`
+	ItemName        string `parquet:"item_name"`
+	ItemSale        uint64 `parquet:"item_sale"`
+	ItemSize        string `parquet:"item_size"`
+	ItemTotalPrice  uint64 `parquet:"item_total_price"`
+	ItemNmID        uint64 `parquet:"item_nm_id"`
+	ItemBrand       string `parquet:"item_brand"`
+	ItemStatus      uint64 `parquet:"item_status"`
+}
+
+// Разворачивание заказа в строки, по одной на товар.
+// Заказ без товаров дает одну строку с пустыми полями товара
+func Rows(order *dto.OrderResponse) []Row {
+	base := Row{
+		OrderUID:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerID:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		ShardKey:          order.ShardKey,
+		SMID:              uint64(order.SMID),
+		DateCreated:       order.DateCreated,
+		OOFShard:          order.OOFShard,
+
+		DeliveryName:    order.Delivery.Name,
+		DeliveryPhone:   order.Delivery.Phone,
+		DeliveryZip:     order.Delivery.Zip,
+		DeliveryCity:    order.Delivery.City,
+		DeliveryAddress: order.Delivery.Address,
+		DeliveryRegion:  order.Delivery.Region,
+		DeliveryEmail:   order.Delivery.Email,
+
+		PaymentTransaction:  order.Payment.Transaction,
+		PaymentRequestID:    order.Payment.RequestID,
+		PaymentCurrency:     order.Payment.Currency,
+		PaymentProvider:     order.Payment.Provider,
+		PaymentAmount:       widen(order.Payment.Amount, func(v int) int64 { return int64(v) }),
+		PaymentDt:           order.Payment.PaymentDt,
+		PaymentBank:         order.Payment.Bank,
+		PaymentDeliveryCost: widen(order.Payment.DeliveryCost, func(v uint) uint64 { return uint64(v) }),
+		PaymentGoodsTotal:   widen(order.Payment.GoodsTotal, func(v uint) uint64 { return uint64(v) }),
+		PaymentCustomFee:    widen(order.Payment.CustomFee, func(v uint) uint64 { return uint64(v) }),
+	}
+
+	if len(order.Items) == 0 {
+		return []Row{base}
+	}
+
+	rows := make([]Row, 0, len(order.Items))
+	for _, item := range order.Items {
+		row := base
+		row.ItemChrtID = uint64(item.ChrtID)
+		row.ItemTrackNumber = item.TrackNumber
+		row.ItemPrice = uint64(item.Price)
+		row.ItemRid = item.Rid
+		row.ItemName = item.Name
+		row.ItemSale = uint64(item.Sale)
+		row.ItemSize = item.Size
+		row.ItemTotalPrice = uint64(item.TotalPrice)
+		row.ItemNmID = uint64(item.NmID)
+		row.ItemBrand = item.Brand
+		row.ItemStatus = uint64(item.Status)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Приведение необязательного значения к типу столбца
+func widen[T, R any](value *T, convert func(T) R) *R {
+	if value == nil {
+		return nil
+	}
+	result := convert(*value)
+	return &result
+}
+
+// Имена столбцов в порядке полей строки
+func columns() []string {
+	typ := reflect.TypeOf(Row{})
+	names := make([]string, typ.NumField())
+	for i := range names {
+		names[i], _, _ = strings.Cut(typ.Field(i).Tag.Get("parquet"), ",")
+	}
+	return names
+}
+
+// Значения строки в текстовом виде для CSV
+func (r *Row) values() []string {
+	value := reflect.ValueOf(r).Elem()
+	result := make([]string, value.NumField())
+	for i := range result {
+		result[i] = formatValue(value.Field(i))
+	}
+	return result
+}
+
+// Текстовое представление значения поля
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
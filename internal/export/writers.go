@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+)
+
+// Число строк в группе Parquet. Группа держится в памяти до записи
+const parquetRowGroupSize = 10000
+
+// Выгрузка NDJSON: один заказ в строке
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{encoder: json.NewEncoder(w)}
+}
+
+func (w *ndjsonWriter) Write(order *dto.OrderResponse) error {
+	if err := w.encoder.Encode(order); err != nil {
+		return fmt.Errorf("Failed to write NDJSON: %v", err)
+	}
+	return nil
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// Выгрузка CSV: заголовок и строка на каждый товар
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns()); err != nil {
+		return nil, fmt.Errorf("Failed to write CSV header: %v", err)
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (w *csvWriter) Write(order *dto.OrderResponse) error {
+	for _, row := range Rows(order) {
+		if err := w.writer.Write(row.values()); err != nil {
+			return fmt.Errorf("Failed to write CSV: %v", err)
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("Failed to flush CSV: %v", err)
+	}
+	return nil
+}
+
+// Выгрузка Parquet со строкой на каждый товар
+type parquetWriter struct {
+	writer *parquet.GenericWriter[Row]
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		writer: parquet.NewGenericWriter[Row](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+	}
+}
+
+func (w *parquetWriter) Write(order *dto.OrderResponse) error {
+	if _, err := w.writer.Write(Rows(order)); err != nil {
+		return fmt.Errorf("Failed to write Parquet: %v", err)
+	}
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("Failed to close Parquet: %v", err)
+	}
+	return nil
+}
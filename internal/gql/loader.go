@@ -0,0 +1,69 @@
+package gql
+
+import (
+	"context"
+	"sync"
+)
+
+// Загрузчик значений по ключу с объединением запросов.
+// Load только запоминает ключ и возвращает отложенное значение; graphql-go вычисляет
+// отложенные значения после обхода всех полей уровня, поэтому первое вычисление
+// загружает сразу все накопленные ключи одним вызовом fetch
+type loader[V any] struct {
+	fetch func(ctx context.Context, keys []string) (map[string]V, error)
+
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	results map[string]V
+	errs    map[string]error
+}
+
+// Конструктор загрузчика, живущего в пределах одного запроса
+func newLoader[V any](fetch func(ctx context.Context, keys []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{
+		fetch:   fetch,
+		queued:  make(map[string]bool),
+		results: make(map[string]V),
+		errs:    make(map[string]error),
+	}
+}
+
+// Постановка ключа в очередь и получение отложенного значения.
+// Для отсутствующего ключа возвращается нулевое значение
+func (l *loader[V]) Load(ctx context.Context, key string) func() (V, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.dispatch(ctx)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.results[key], l.errs[key]
+	}
+}
+
+// Загрузка всех накопленных ключей одним вызовом
+func (l *loader[V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) == 0 {
+		return
+	}
+	keys := l.pending
+	l.pending = nil
+
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+		} else if value, exists := values[key]; exists {
+			l.results[key] = value
+		}
+	}
+}
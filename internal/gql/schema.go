@@ -0,0 +1,255 @@
+// Пакет gql реализует GraphQL схему заказов поверх хранилища и кэша
+package gql
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/masking"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+	"github.com/venexene/wbl0-orders-service/internal/orders"
+)
+
+// Размер страницы по умолчанию и максимальный, как в поиске REST API
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// Ошибка, которая отдается клиенту вместо внутренних подробностей
+var errInternal = errors.New("Internal server error")
+
+// Запрос GraphQL в формате POST тела или параметров GET
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Субъект запроса: роль для маскирования и запись в аудит открытых полей
+type Viewer struct {
+	Role   auth.Role
+	Expose func(orderUID string, exposed []string)
+}
+
+// Схема заказов с источниками данных
+type Schema struct {
+	schema  graphql.Schema
+	storage database.StorageInterface
+	cache   *cache.Cache
+}
+
+// Ключ состояния запроса в контексте
+type stateKey struct{}
+
+// Состояние одного запроса: субъект и загрузчики с объединением обращений к БД
+type requestState struct {
+	viewer Viewer
+	orders *loader[*models.Order]
+	items  *loader[[]models.Item]
+}
+
+// Узел заказа, уже замаскированного по роли субъекта.
+// Заказы страницы загружаются без товаров, товары запрашиваются загрузчиком
+type orderNode struct {
+	order       *models.Order
+	itemsLoaded bool
+}
+
+// Ребро соединения заказов
+type orderEdge struct {
+	cursor string
+	node   *orderNode
+}
+
+// Страница соединения заказов
+type orderConnection struct {
+	edges       []orderEdge
+	hasNextPage bool
+}
+
+// Конструктор схемы
+func NewSchema(storage database.StorageInterface, cache *cache.Cache) (*Schema, error) {
+	s := &Schema{storage: storage, cache: cache}
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: s.queryType()})
+	if err != nil {
+		return nil, err
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Выполнение запроса от имени субъекта
+func (s *Schema) Execute(ctx context.Context, req Request, viewer Viewer) *graphql.Result {
+	if viewer.Role == "" {
+		viewer.Role = auth.RoleViewer
+	}
+	state := &requestState{
+		viewer: viewer,
+		orders: newLoader(s.fetchOrders),
+		items:  newLoader(s.fetchItems),
+	}
+
+	return graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(ctx, stateKey{}, state),
+	})
+}
+
+// Загрузка заказов по UID одним запросом с сохранением в кэш
+func (s *Schema) fetchOrders(ctx context.Context, uids []string) (map[string]*models.Order, error) {
+	orders, err := s.storage.GetOrdersByUIDs(ctx, uids)
+	if err != nil {
+		slog.Error("Failed to get orders by UIDs", "err", err)
+		return nil, errInternal
+	}
+	for _, order := range orders {
+		s.cache.Set(order)
+	}
+	return orders, nil
+}
+
+// Загрузка товаров нескольких заказов одним запросом
+func (s *Schema) fetchItems(ctx context.Context, uids []string) (map[string][]models.Item, error) {
+	items, err := s.storage.GetItemsByOrderUIDs(ctx, uids)
+	if err != nil {
+		slog.Error("Failed to get items by order UIDs", "err", err)
+		return nil, errInternal
+	}
+	return items, nil
+}
+
+// Получение состояния запроса из контекста
+func stateFromContext(ctx context.Context) *requestState {
+	state, _ := ctx.Value(stateKey{}).(*requestState)
+	return state
+}
+
+// Маскирование заказа по роли субъекта с записью открытых полей в аудит
+func (st *requestState) node(order *models.Order, itemsLoaded bool) *orderNode {
+	masked, exposed := masking.Order(order, st.viewer.Role)
+	if len(exposed) > 0 && st.viewer.Expose != nil {
+		st.viewer.Expose(order.OrderUID, exposed)
+	}
+	return &orderNode{order: masked, itemsLoaded: itemsLoaded}
+}
+
+// Корневой тип запросов
+func (s *Schema) queryType() *graphql.Object {
+	order := orderType()
+	connection := orderConnectionType(order)
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"order": &graphql.Field{
+				Type:        order,
+				Description: "Заказ по UID, null если заказ не найден",
+				Args: graphql.FieldConfigArgument{
+					"orderUid": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: s.resolveOrder,
+			},
+			"orders": &graphql.Field{
+				Type:        graphql.NewNonNull(connection),
+				Description: "Заказы по фильтру поиска, сначала новые",
+				Args: graphql.FieldConfigArgument{
+					"customerId":      &graphql.ArgumentConfig{Type: graphql.String},
+					"trackNumber":     &graphql.ArgumentConfig{Type: graphql.String},
+					"deliveryService": &graphql.ArgumentConfig{Type: graphql.String},
+					"locale":          &graphql.ArgumentConfig{Type: graphql.String},
+					"createdFrom":     &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Нижняя граница даты создания включительно"},
+					"createdTo":       &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Верхняя граница даты создания не включительно"},
+					"first":           &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize, Description: "Размер страницы от 1 до " + strconv.Itoa(maxPageSize)},
+					"after":           &graphql.ArgumentConfig{Type: graphql.String, Description: "Курсор последнего заказа предыдущей страницы"},
+				},
+				Resolve: s.resolveOrders,
+			},
+		},
+	})
+}
+
+// Получение заказа по UID из кэша или через загрузчик
+func (s *Schema) resolveOrder(p graphql.ResolveParams) (any, error) {
+	state := stateFromContext(p.Context)
+	uid := strings.ToLower(strings.TrimSpace(p.Args["orderUid"].(string)))
+	if !orders.ValidUID(uid) {
+		return nil, errors.New("orderUid must be a UUID")
+	}
+
+	if order, exists := s.cache.Get(uid); exists {
+		return state.node(order, true), nil
+	}
+
+	load := state.orders.Load(p.Context, uid)
+	return func() (any, error) {
+		order, err := load()
+		if err != nil || order == nil {
+			return nil, err
+		}
+		return state.node(order, true), nil
+	}, nil
+}
+
+// Поиск страницы заказов без товаров
+func (s *Schema) resolveOrders(p graphql.ResolveParams) (any, error) {
+	state := stateFromContext(p.Context)
+
+	filter := database.OrderFilter{SkipItems: true}
+	filter.CustomerID, _ = p.Args["customerId"].(string)
+	filter.TrackNumber, _ = p.Args["trackNumber"].(string)
+	filter.DeliveryService, _ = p.Args["deliveryService"].(string)
+	filter.Locale, _ = p.Args["locale"].(string)
+	if from, ok := p.Args["createdFrom"].(time.Time); ok {
+		filter.CreatedFrom = from
+	}
+	if to, ok := p.Args["createdTo"].(time.Time); ok {
+		filter.CreatedTo = to
+	}
+
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxPageSize {
+		return nil, errors.New("first must be between 1 and " + strconv.Itoa(maxPageSize))
+	}
+	if after, ok := p.Args["after"].(string); ok && after != "" {
+		cursor, err := database.ParseOrderCursor(after)
+		if err != nil {
+			return nil, errors.New("after is not a valid cursor")
+		}
+		filter.After = cursor
+	}
+
+	// Лишний заказ показывает, что есть следующая страница
+	filter.Limit = first + 1
+	orders, err := s.storage.SearchOrders(p.Context, filter)
+	if err != nil {
+		slog.Error("Failed to search orders", "err", err)
+		return nil, errInternal
+	}
+
+	connection := &orderConnection{edges: make([]orderEdge, 0, len(orders))}
+	if len(orders) > first {
+		orders = orders[:first]
+		connection.hasNextPage = true
+	}
+	for _, order := range orders {
+		connection.edges = append(connection.edges, orderEdge{
+			cursor: database.OrderCursor{DateCreated: order.DateCreated, OrderUID: order.OrderUID}.Encode(),
+			node:   state.node(order, false),
+		})
+	}
+	return connection, nil
+}
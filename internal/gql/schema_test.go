@@ -0,0 +1,220 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Хранилище в памяти с подсчетом обращений, остальные методы интерфейса не используются
+type countingStorage struct {
+	database.StorageInterface
+	orders []*models.Order
+
+	searches     []database.OrderFilter
+	orderBatches [][]string
+	itemBatches  [][]string
+}
+
+func (m *countingStorage) SearchOrders(ctx context.Context, filter database.OrderFilter) ([]*models.Order, error) {
+	m.searches = append(m.searches, filter)
+
+	var result []*models.Order
+	for _, order := range m.orders {
+		if !filter.Matches(order) {
+			continue
+		}
+		if filter.After != nil && !before(order, filter.After) {
+			continue
+		}
+		copied := *order
+		if filter.SkipItems {
+			copied.Items = nil
+		}
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return !before(result[i], &database.OrderCursor{DateCreated: result[j].DateCreated, OrderUID: result[j].OrderUID})
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (m *countingStorage) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error) {
+	m.orderBatches = append(m.orderBatches, orderUIDs)
+	result := make(map[string]*models.Order)
+	for _, order := range m.orders {
+		for _, uid := range orderUIDs {
+			if order.OrderUID == uid {
+				result[uid] = order
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *countingStorage) GetItemsByOrderUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.Item, error) {
+	m.itemBatches = append(m.itemBatches, orderUIDs)
+	result := make(map[string][]models.Item)
+	for _, order := range m.orders {
+		for _, uid := range orderUIDs {
+			if order.OrderUID == uid {
+				result[uid] = order.Items
+			}
+		}
+	}
+	return result, nil
+}
+
+// Заказ идет после позиции в порядке выдачи
+func before(order *models.Order, cursor *database.OrderCursor) bool {
+	if order.DateCreated.Equal(cursor.DateCreated) {
+		return order.OrderUID < cursor.OrderUID
+	}
+	return order.DateCreated.Before(cursor.DateCreated)
+}
+
+// Загрузка заказов из тестовых файлов
+func loadOrders(t *testing.T, names ...string) []*models.Order {
+	t.Helper()
+
+	var orders []*models.Order
+	for _, name := range names {
+		order, err := models.LoadOrderFromFile("../../testdata/" + name)
+		if err != nil {
+			t.Fatalf("Failed to load order from file: %v", err)
+		}
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// Выполнение запроса с разбором данных ответа
+func execute(t *testing.T, schema *Schema, viewer Viewer, query string, variables map[string]any) map[string]any {
+	t.Helper()
+
+	result := schema.Execute(context.Background(), Request{Query: query, Variables: variables}, viewer)
+	if result.HasErrors() {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	data, _ := json.Marshal(result.Data)
+	var parsed map[string]any
+	json.Unmarshal(data, &parsed)
+	return parsed
+}
+
+// Тестирование страницы заказов: товары загружаются одним запросом на страницу
+func TestOrdersConnection(t *testing.T) {
+	orders := loadOrders(t, "order1.json", "order2.json", "order3.json")
+	storage := &countingStorage{orders: orders}
+	schema, err := NewSchema(storage, cache.NewCache(10))
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+
+	query := `query($after: String) {
+		orders(first: 2, after: $after) {
+			edges { cursor node { orderUid items { totalPrice } payment { amount } } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	data := execute(t, schema, Viewer{Role: auth.RoleViewer}, query, nil)
+	connection := data["orders"].(map[string]any)
+	edges := connection["edges"].([]any)
+	pageInfo := connection["pageInfo"].(map[string]any)
+	if len(edges) != 2 || pageInfo["hasNextPage"] != true || pageInfo["endCursor"] == nil {
+		t.Fatalf("Unexpected first page %v", connection)
+	}
+	for _, edge := range edges {
+		node := edge.(map[string]any)["node"].(map[string]any)
+		if len(node["items"].([]any)) == 0 {
+			t.Errorf("Expected items for order %v", node["orderUid"])
+		}
+		if node["payment"].(map[string]any)["amount"] != nil {
+			t.Errorf("Expected amount hidden for viewer, but got %v", node["payment"])
+		}
+	}
+	if len(storage.searches) != 1 || !storage.searches[0].SkipItems || storage.searches[0].Limit != 3 {
+		t.Errorf("Expected one search without items, but got %+v", storage.searches)
+	}
+	if len(storage.itemBatches) != 1 || len(storage.itemBatches[0]) != 2 {
+		t.Errorf("Expected items for the page in one batch, but got %v", storage.itemBatches)
+	}
+
+	// Вторая страница по курсору
+	data = execute(t, schema, Viewer{Role: auth.RoleViewer}, query, map[string]any{"after": pageInfo["endCursor"]})
+	connection = data["orders"].(map[string]any)
+	if edges := connection["edges"].([]any); len(edges) != 1 || connection["pageInfo"].(map[string]any)["hasNextPage"] != false {
+		t.Errorf("Unexpected second page %v", connection)
+	}
+
+	// Проекция без товаров не обращается к ним
+	storage.itemBatches = nil
+	execute(t, schema, Viewer{}, `{ orders { nodes { payment { currency } } } }`, nil)
+	if len(storage.itemBatches) != 0 {
+		t.Errorf("Expected no item queries, but got %v", storage.itemBatches)
+	}
+
+	result := schema.Execute(context.Background(), Request{Query: `{ orders(first: 0) { nodes { orderUid } } }`}, Viewer{})
+	if !result.HasErrors() {
+		t.Error("Expected error for invalid page size")
+	}
+}
+
+// Тестирование получения заказов по UID с объединением запросов и маскированием
+func TestOrderByUID(t *testing.T) {
+	orders := loadOrders(t, "order1.json", "order2.json")
+	storage := &countingStorage{orders: orders}
+	schema, err := NewSchema(storage, cache.NewCache(10))
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+
+	var exposed []string
+	viewer := Viewer{Role: auth.RoleAdmin, Expose: func(orderUID string, fields []string) {
+		exposed = append(exposed, orderUID)
+	}}
+	query := `query($a: String!, $b: String!, $missing: String!) {
+		a: order(orderUid: $a) { orderUid delivery { phone } payment { amount } }
+		b: order(orderUid: $b) { orderUid items { name } }
+		missing: order(orderUid: $missing) { orderUid }
+	}`
+	variables := map[string]any{
+		"a":       orders[0].OrderUID,
+		"b":       orders[1].OrderUID,
+		"missing": "00000000-0000-4000-8000-000000000000",
+	}
+
+	data := execute(t, schema, viewer, query, variables)
+	a := data["a"].(map[string]any)
+	if a["delivery"].(map[string]any)["phone"] != orders[0].Delivery.Phone || a["payment"].(map[string]any)["amount"] == nil {
+		t.Errorf("Expected unmasked order for admin, but got %v", a)
+	}
+	if b := data["b"].(map[string]any); len(b["items"].([]any)) != len(orders[1].Items) {
+		t.Errorf("Unexpected items %v", b)
+	}
+	if data["missing"] != nil {
+		t.Errorf("Expected null for missing order, but got %v", data["missing"])
+	}
+	if len(storage.orderBatches) != 1 || len(storage.orderBatches[0]) != 3 {
+		t.Errorf("Expected one batch of three UIDs, but got %v", storage.orderBatches)
+	}
+	if len(exposed) != 2 {
+		t.Errorf("Expected exposure of two orders, but got %v", exposed)
+	}
+
+	// Повторный запрос обслуживается кэшем
+	execute(t, schema, viewer, query, variables)
+	if len(storage.orderBatches) != 2 || len(storage.orderBatches[1]) != 1 {
+		t.Errorf("Expected only missing order to be requested again, but got %v", storage.orderBatches)
+	}
+}
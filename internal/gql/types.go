@@ -0,0 +1,192 @@
+package gql
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Обработчик поля по значению родителя заданного типа
+func field[T any](t graphql.Output, fn func(T) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			source, ok := p.Source.(T)
+			if !ok {
+				return nil, nil
+			}
+			return fn(source), nil
+		},
+	}
+}
+
+// Значение или null для нуля, замаскированные суммы отдаются как null
+func nullable(value uint) any {
+	if value == 0 {
+		return nil
+	}
+	return int(value)
+}
+
+// Непустая строка
+var nonNullString = graphql.NewNonNull(graphql.String)
+
+// Непустое целое
+var nonNullInt = graphql.NewNonNull(graphql.Int)
+
+// Тип заказа
+func orderType() *graphql.Object {
+	delivery := deliveryType()
+	payment := paymentType()
+	item := itemType()
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Order",
+		Description: "Заказ, персональные данные маскируются по роли субъекта",
+		Fields: graphql.Fields{
+			"orderUid":          field(nonNullString, func(n *orderNode) any { return n.order.OrderUID }),
+			"trackNumber":       field(nonNullString, func(n *orderNode) any { return n.order.TrackNumber }),
+			"entry":             field(nonNullString, func(n *orderNode) any { return n.order.Entry }),
+			"locale":            field(nonNullString, func(n *orderNode) any { return n.order.Locale }),
+			"internalSignature": field(nonNullString, func(n *orderNode) any { return n.order.InternalSignature }),
+			"customerId":        field(nonNullString, func(n *orderNode) any { return n.order.CustomerID }),
+			"deliveryService":   field(nonNullString, func(n *orderNode) any { return n.order.DeliveryService }),
+			"shardkey":          field(nonNullString, func(n *orderNode) any { return n.order.ShardKey }),
+			"smId":              field(nonNullInt, func(n *orderNode) any { return int(n.order.SMID) }),
+			"dateCreated":       field(graphql.NewNonNull(graphql.DateTime), func(n *orderNode) any { return n.order.DateCreated }),
+			"oofShard":          field(nonNullString, func(n *orderNode) any { return n.order.OOFShard }),
+			"delivery":          field(graphql.NewNonNull(delivery), func(n *orderNode) any { return &n.order.Delivery }),
+			"payment":           field(graphql.NewNonNull(payment), func(n *orderNode) any { return &n.order.Payment }),
+			"items": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(item))),
+				Description: "Товары заказа, для страницы заказов загружаются одним запросом",
+				Resolve:     resolveItems,
+			},
+		},
+	})
+}
+
+// Товары заказа: уже загруженные или через загрузчик
+func resolveItems(p graphql.ResolveParams) (any, error) {
+	node, ok := p.Source.(*orderNode)
+	if !ok {
+		return nil, nil
+	}
+	if node.itemsLoaded {
+		return itemPointers(node.order.Items), nil
+	}
+
+	load := stateFromContext(p.Context).items.Load(p.Context, node.order.OrderUID)
+	return func() (any, error) {
+		items, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return itemPointers(items), nil
+	}, nil
+}
+
+// Указатели на товары для обработчиков полей
+func itemPointers(items []models.Item) []*models.Item {
+	result := make([]*models.Item, 0, len(items))
+	for i := range items {
+		result = append(result, &items[i])
+	}
+	return result
+}
+
+// Тип доставки
+func deliveryType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Delivery",
+		Fields: graphql.Fields{
+			"name":    field(nonNullString, func(d *models.Delivery) any { return d.Name }),
+			"phone":   field(nonNullString, func(d *models.Delivery) any { return d.Phone }),
+			"zip":     field(nonNullString, func(d *models.Delivery) any { return d.Zip }),
+			"city":    field(nonNullString, func(d *models.Delivery) any { return d.City }),
+			"address": field(nonNullString, func(d *models.Delivery) any { return d.Address }),
+			"region":  field(nonNullString, func(d *models.Delivery) any { return d.Region }),
+			"email":   field(nonNullString, func(d *models.Delivery) any { return d.Email }),
+		},
+	})
+}
+
+// Тип оплаты, суммы равны null, если роль не позволяет их видеть
+func paymentType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Payment",
+		Fields: graphql.Fields{
+			"transaction":  field(nonNullString, func(p *models.Payment) any { return p.Transaction }),
+			"requestId":    field(nonNullString, func(p *models.Payment) any { return p.RequestID }),
+			"currency":     field(nonNullString, func(p *models.Payment) any { return p.Currency }),
+			"provider":     field(nonNullString, func(p *models.Payment) any { return p.Provider }),
+			"amount":       field(graphql.Int, func(p *models.Payment) any { return nullable(uint(max(p.Amount, 0))) }),
+			"paymentDt":    field(graphql.NewNonNull(graphql.DateTime), func(p *models.Payment) any { return time.Unix(int64(p.PaymentDt), 0).UTC() }),
+			"bank":         field(nonNullString, func(p *models.Payment) any { return p.Bank }),
+			"deliveryCost": field(graphql.Int, func(p *models.Payment) any { return nullable(p.DeliveryCost) }),
+			"goodsTotal":   field(graphql.Int, func(p *models.Payment) any { return nullable(p.GoodsTotal) }),
+			"customFee":    field(graphql.Int, func(p *models.Payment) any { return nullable(p.CustomFee) }),
+		},
+	})
+}
+
+// Тип товара
+func itemType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"chrtId":      field(nonNullInt, func(i *models.Item) any { return int(i.ChrtID) }),
+			"trackNumber": field(nonNullString, func(i *models.Item) any { return i.TrackNumber }),
+			"price":       field(nonNullInt, func(i *models.Item) any { return int(i.Price) }),
+			"rid":         field(nonNullString, func(i *models.Item) any { return i.Rid }),
+			"name":        field(nonNullString, func(i *models.Item) any { return i.Name }),
+			"sale":        field(nonNullInt, func(i *models.Item) any { return int(i.Sale) }),
+			"size":        field(nonNullString, func(i *models.Item) any { return i.Size }),
+			"totalPrice":  field(nonNullInt, func(i *models.Item) any { return int(i.TotalPrice) }),
+			"nmId":        field(nonNullInt, func(i *models.Item) any { return int(i.NmID) }),
+			"brand":       field(nonNullString, func(i *models.Item) any { return i.Brand }),
+			"status":      field(nonNullInt, func(i *models.Item) any { return int(i.Status) }),
+		},
+	})
+}
+
+// Тип соединения заказов с курсорами
+func orderConnectionType(order *graphql.Object) *graphql.Object {
+	pageInfo := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": field(graphql.NewNonNull(graphql.Boolean), func(c *orderConnection) any { return c.hasNextPage }),
+			"endCursor": field(graphql.String, func(c *orderConnection) any {
+				if len(c.edges) == 0 {
+					return nil
+				}
+				return c.edges[len(c.edges)-1].cursor
+			}),
+		},
+	})
+
+	edge := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderEdge",
+		Fields: graphql.Fields{
+			"cursor": field(nonNullString, func(e orderEdge) any { return e.cursor }),
+			"node":   field(graphql.NewNonNull(order), func(e orderEdge) any { return e.node }),
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderConnection",
+		Fields: graphql.Fields{
+			"edges": field(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge))), func(c *orderConnection) any { return c.edges }),
+			"nodes": field(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(order))), func(c *orderConnection) any {
+				nodes := make([]*orderNode, 0, len(c.edges))
+				for _, edge := range c.edges {
+					nodes = append(nodes, edge.node)
+				}
+				return nodes
+			}),
+			"pageInfo": field(graphql.NewNonNull(pageInfo), func(c *orderConnection) any { return c }),
+		},
+	})
+}
@@ -0,0 +1,126 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+)
+
+// Ключ субъекта в контексте вызова
+type principalKey struct{}
+
+// Служебные сервисы, доступные без аутентификации, как эндпоинты проверки состояния REST API
+var exemptServices = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+// Аутентификация вызовов теми же способами, что и в REST API
+type authenticator struct {
+	authenticators []auth.Authenticator
+	defaultRole    auth.Role
+	anonymousRole  auth.Role
+}
+
+// Проверка учетных данных из метаданных вызова.
+// Метаданные передаются способам аутентификации как заголовки HTTP запроса
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if len(a.authenticators) == 0 {
+		return withPrincipal(ctx, &auth.Principal{Subject: auth.MethodAnonymous, Method: auth.MethodAnonymous, Role: a.anonymousRole}), nil
+	}
+	for _, prefix := range exemptServices {
+		if strings.HasPrefix(method, prefix) {
+			return withPrincipal(ctx, &auth.Principal{Subject: auth.MethodAnonymous, Method: auth.MethodAnonymous, Role: auth.RoleViewer}), nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to authenticate request")
+	}
+	for key, values := range md {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+
+	for _, authenticator := range a.authenticators {
+		principal, err := authenticator.Authenticate(request)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			slog.Warn("Rejected credentials", "method", method, "err", err)
+			return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		}
+		if err != nil {
+			slog.Error("Failed to authenticate request", "method", method, "err", err)
+			return nil, status.Error(codes.Internal, "Failed to authenticate request")
+		}
+
+		if principal.Role == "" {
+			principal.Role = a.defaultRole
+		}
+		return withPrincipal(ctx, principal), nil
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "Authentication required")
+}
+
+// Перехватчик аутентификации обычных вызовов
+func (a *authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Перехватчик аутентификации потоковых вызовов
+func (a *authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+}
+
+// Поток с контекстом, содержащим субъекта
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// Сохранение субъекта в контексте вызова
+func withPrincipal(ctx context.Context, principal *auth.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Получение субъекта из контекста вызова
+func principalFromContext(ctx context.Context) (*auth.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*auth.Principal)
+	return principal, ok
+}
+
+// Роль субъекта вызова, без субъекта - роль с наименьшими правами
+func roleFromContext(ctx context.Context) auth.Role {
+	if principal, ok := principalFromContext(ctx); ok && principal.Role != "" {
+		return principal.Role
+	}
+	return auth.RoleViewer
+}
@@ -0,0 +1,95 @@
+package grpcserver
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ordersv1 "github.com/venexene/wbl0-orders-service/api/proto/orders/v1"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Преобразование заказа в сообщение protobuf.
+// Нулевые суммы оплаты после маскирования не передаются, как и в REST API
+func orderToProto(order *models.Order) *ordersv1.Order {
+	items := make([]*ordersv1.Item, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, &ordersv1.Item{
+			ChrtId:      uint64(item.ChrtID),
+			TrackNumber: item.TrackNumber,
+			Price:       uint64(item.Price),
+			Rid:         item.Rid,
+			Name:        item.Name,
+			Sale:        uint64(item.Sale),
+			Size:        item.Size,
+			TotalPrice:  uint64(item.TotalPrice),
+			NmId:        uint64(item.NmID),
+			Brand:       item.Brand,
+			Status:      uint32(item.Status),
+		})
+	}
+
+	return &ordersv1.Order{
+		OrderUid:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerId:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.ShardKey,
+		SmId:              uint32(order.SMID),
+		DateCreated:       timestamppb.New(order.DateCreated),
+		OofShard:          order.OOFShard,
+		Delivery: &ordersv1.Delivery{
+			Name:    order.Delivery.Name,
+			Phone:   order.Delivery.Phone,
+			Zip:     order.Delivery.Zip,
+			City:    order.Delivery.City,
+			Address: order.Delivery.Address,
+			Region:  order.Delivery.Region,
+			Email:   order.Delivery.Email,
+		},
+		Payment: &ordersv1.Payment{
+			Transaction:  order.Payment.Transaction,
+			RequestId:    order.Payment.RequestID,
+			Currency:     order.Payment.Currency,
+			Provider:     order.Payment.Provider,
+			Amount:       nonZero(int64(order.Payment.Amount)),
+			PaymentDt:    order.Payment.PaymentDt,
+			Bank:         order.Payment.Bank,
+			DeliveryCost: nonZero(uint64(order.Payment.DeliveryCost)),
+			GoodsTotal:   nonZero(uint64(order.Payment.GoodsTotal)),
+			CustomFee:    nonZero(uint64(order.Payment.CustomFee)),
+		},
+		Items: items,
+	}
+}
+
+// Указатель на значение или nil для нуля
+func nonZero[T int64 | uint64](value T) *T {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+// Преобразование фильтра запроса в условия отбора БД
+func filterFromProto(filter *ordersv1.OrderFilter) database.OrderFilter {
+	if filter == nil {
+		return database.OrderFilter{}
+	}
+
+	result := database.OrderFilter{
+		CustomerID:      filter.GetCustomerId(),
+		TrackNumber:     filter.GetTrackNumber(),
+		DeliveryService: filter.GetDeliveryService(),
+		Locale:          filter.GetLocale(),
+	}
+	if filter.CreatedFrom != nil {
+		result.CreatedFrom = filter.CreatedFrom.AsTime()
+	}
+	if filter.CreatedTo != nil {
+		result.CreatedTo = filter.CreatedTo.AsTime()
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	ordersv1 "github.com/venexene/wbl0-orders-service/api/proto/orders/v1"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+)
+
+// Настройки gRPC сервера
+type Options struct {
+	// Способы аутентификации, проверяемые по порядку
+	Authenticators []auth.Authenticator
+	// Роль субъектов, у которых она не указана
+	DefaultRole auth.Role
+	// Роль вызовов без аутентификации, когда способы не настроены
+	AnonymousRole auth.Role
+	// Регистрация сервиса reflection для grpcurl и подобных клиентов
+	Reflection bool
+}
+
+// gRPC сервер с сервисом заказов и сервисом проверки состояния
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+}
+
+// Конструктор gRPC сервера
+func NewServer(service *OrderService, opts Options) *Server {
+	if opts.DefaultRole == "" {
+		opts.DefaultRole = auth.RoleViewer
+	}
+	if opts.AnonymousRole == "" {
+		opts.AnonymousRole = auth.RoleViewer
+	}
+	authenticator := &authenticator{
+		authenticators: opts.Authenticators,
+		defaultRole:    opts.DefaultRole,
+		anonymousRole:  opts.AnonymousRole,
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.unary),
+		grpc.ChainStreamInterceptor(authenticator.stream),
+	)
+	ordersv1.RegisterOrderServiceServer(server, service)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(ordersv1.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	if opts.Reflection {
+		reflection.Register(server)
+	}
+
+	return &Server{grpc: server, health: healthServer}
+}
+
+// Прием соединений до остановки сервера
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// Остановка с ожиданием текущих вызовов.
+// Сервис проверки состояния сразу отвечает NOT_SERVING, по истечении ctx вызовы прерываются.
+// Подписки WatchOrders завершаются закрытием шины событий
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
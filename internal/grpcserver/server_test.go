@@ -0,0 +1,365 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	ordersv1 "github.com/venexene/wbl0-orders-service/api/proto/orders/v1"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Хранилище заказов в памяти, остальные методы интерфейса не используются
+type memoryStorage struct {
+	database.StorageInterface
+	orders []*models.Order
+}
+
+func (m *memoryStorage) GetOrderByUID(ctx context.Context, orderUID string) (*models.Order, error) {
+	for _, order := range m.orders {
+		if order.OrderUID == orderUID {
+			return order, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (m *memoryStorage) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error) {
+	result := make(map[string]*models.Order)
+	for _, uid := range orderUIDs {
+		if order, err := m.GetOrderByUID(ctx, uid); err == nil {
+			result[uid] = order
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryStorage) ExportOrders(ctx context.Context, filter database.OrderFilter, fn func(order *models.Order) error) error {
+	sent := 0
+	for _, order := range m.orders {
+		if filter.Limit > 0 && sent == filter.Limit {
+			break
+		}
+		if !filter.Matches(order) {
+			continue
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+		sent++
+	}
+	return nil
+}
+
+// Тестовое окружение: сервер на bufconn и клиент к нему
+type testEnv struct {
+	bus    *events.Bus
+	conn   *grpc.ClientConn
+	client ordersv1.OrderServiceClient
+}
+
+// Запуск сервера в памяти процесса
+func newTestEnv(t *testing.T, opts Options, orders ...*models.Order) *testEnv {
+	t.Helper()
+
+	bus := events.NewBus(10)
+	cfg := &config.Config{BatchGetMaxUIDs: 3, StreamBufferSize: 2}
+	service := NewOrderService(&memoryStorage{orders: orders}, cfg, cache.NewCache(10), nil, bus)
+	server := NewServer(service, opts)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		bus.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+	return &testEnv{bus: bus, conn: conn, client: ordersv1.NewOrderServiceClient(conn)}
+}
+
+// Загрузка заказов из тестовых файлов
+func loadOrders(t *testing.T, names ...string) []*models.Order {
+	t.Helper()
+
+	var orders []*models.Order
+	for _, name := range names {
+		order, err := models.LoadOrderFromFile("../../testdata/" + name)
+		if err != nil {
+			t.Fatalf("Failed to load order from file: %v", err)
+		}
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// Проверка кода ошибки вызова
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("Expected code %s, but got %v", code, err)
+	}
+}
+
+// Тестирование получения заказа по UID с маскированием
+func TestGetOrder(t *testing.T) {
+	orders := loadOrders(t, "order1.json")
+	env := newTestEnv(t, Options{}, orders...)
+	ctx := context.Background()
+
+	got, err := env.client.GetOrder(ctx, &ordersv1.GetOrderRequest{OrderUid: orders[0].OrderUID})
+	if err != nil {
+		t.Fatalf("Failed to get order: %v", err)
+	}
+	if got.OrderUid != orders[0].OrderUID || len(got.Items) != len(orders[0].Items) {
+		t.Errorf("Unexpected order %v", got)
+	}
+	if !got.DateCreated.AsTime().Equal(orders[0].DateCreated) {
+		t.Errorf("Expected date_created %s, but got %s", orders[0].DateCreated, got.DateCreated.AsTime())
+	}
+	if got.Delivery.Phone == orders[0].Delivery.Phone || got.Payment.Amount != nil {
+		t.Errorf("Expected order masked for viewer, but got %v", got)
+	}
+
+	_, err = env.client.GetOrder(ctx, &ordersv1.GetOrderRequest{OrderUid: "00000000-0000-4000-8000-000000000000"})
+	expectCode(t, err, codes.NotFound)
+
+	_, err = env.client.GetOrder(ctx, &ordersv1.GetOrderRequest{OrderUid: "not-a-uuid"})
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+// Тестирование пакетного получения заказов
+func TestBatchGetOrders(t *testing.T) {
+	orders := loadOrders(t, "order1.json", "order2.json")
+	env := newTestEnv(t, Options{}, orders...)
+	ctx := context.Background()
+
+	missing := "00000000-0000-4000-8000-000000000000"
+	response, err := env.client.BatchGetOrders(ctx, &ordersv1.BatchGetOrdersRequest{
+		OrderUids: []string{orders[1].OrderUID, missing, orders[0].OrderUID, orders[1].OrderUID},
+	})
+	if err != nil {
+		t.Fatalf("Failed to batch get orders: %v", err)
+	}
+	if len(response.Orders) != 2 || response.Orders[0].OrderUid != orders[1].OrderUID || response.Orders[1].OrderUid != orders[0].OrderUID {
+		t.Errorf("Expected orders in request order, but got %v", response.Orders)
+	}
+	if len(response.MissingUids) != 1 || response.MissingUids[0] != missing {
+		t.Errorf("Expected missing %s, but got %v", missing, response.MissingUids)
+	}
+
+	_, err = env.client.BatchGetOrders(ctx, &ordersv1.BatchGetOrdersRequest{})
+	expectCode(t, err, codes.InvalidArgument)
+
+	_, err = env.client.BatchGetOrders(ctx, &ordersv1.BatchGetOrdersRequest{OrderUids: []string{"a", "b", "c", "d"}})
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+// Тестирование потоковой выдачи заказов по фильтру
+func TestListOrders(t *testing.T) {
+	orders := loadOrders(t, "order1.json", "order2.json", "order3.json")
+	env := newTestEnv(t, Options{}, orders...)
+	ctx := context.Background()
+
+	collect := func(req *ordersv1.ListOrdersRequest) []*ordersv1.Order {
+		t.Helper()
+		stream, err := env.client.ListOrders(ctx, req)
+		if err != nil {
+			t.Fatalf("Failed to list orders: %v", err)
+		}
+		var result []*ordersv1.Order
+		for {
+			order, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return result
+			}
+			if err != nil {
+				t.Fatalf("Failed to receive order: %v", err)
+			}
+			result = append(result, order)
+		}
+	}
+
+	if got := collect(&ordersv1.ListOrdersRequest{}); len(got) != len(orders) {
+		t.Errorf("Expected %d orders, but got %d", len(orders), len(got))
+	}
+	if got := collect(&ordersv1.ListOrdersRequest{Limit: 2}); len(got) != 2 {
+		t.Errorf("Expected 2 orders with limit, but got %d", len(got))
+	}
+
+	filter := &ordersv1.OrderFilter{CustomerId: orders[0].CustomerID}
+	for _, order := range collect(&ordersv1.ListOrdersRequest{Filter: filter}) {
+		if order.CustomerId != orders[0].CustomerID {
+			t.Errorf("Expected only customer %s, but got %s", orders[0].CustomerID, order.CustomerId)
+		}
+	}
+
+	stream, err := env.client.ListOrders(ctx, &ordersv1.ListOrdersRequest{Limit: -1})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+// Тестирование подписки на новые заказы: фильтр, продолжение и сброс
+func TestWatchOrders(t *testing.T) {
+	orders := loadOrders(t, "order1.json")
+	env := newTestEnv(t, Options{})
+	order := orders[0]
+	other := *order
+	other.OrderUID = "b563feb7-b2b8-4b6c-9f5d-3b7a1c1d9e10"
+	other.DeliveryService = "other"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	waitSubscribers := func(n int) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); env.bus.Subscribers() != n; {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d subscribers, but got %d", n, env.bus.Subscribers())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	stream, err := env.client.WatchOrders(ctx, &ordersv1.WatchOrdersRequest{
+		Filter: &ordersv1.OrderFilter{DeliveryService: order.DeliveryService},
+	})
+	if err != nil {
+		t.Fatalf("Failed to watch orders: %v", err)
+	}
+	waitSubscribers(1)
+
+	env.bus.Publish(events.OrderCreated, &other)
+	published := env.bus.Publish(events.OrderCreated, order)
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive event: %v", err)
+	}
+	if event.Id != published.ID || event.Type != events.OrderCreated || event.Order.GetOrderUid() != order.OrderUID {
+		t.Errorf("Unexpected event %v", event)
+	}
+	cancel()
+	waitSubscribers(0)
+
+	// Продолжение после пропущенного события
+	resumed, err := env.client.WatchOrders(context.Background(), &ordersv1.WatchOrdersRequest{LastEventId: published.ID - 1})
+	if err != nil {
+		t.Fatalf("Failed to resume watch: %v", err)
+	}
+	if event, err := resumed.Recv(); err != nil || event.Id != published.ID {
+		t.Errorf("Expected replay of event %d, but got %v, %v", published.ID, event, err)
+	}
+
+	// Слишком старый идентификатор приводит к событию сброса
+	reset, err := env.client.WatchOrders(context.Background(), &ordersv1.WatchOrdersRequest{LastEventId: 1})
+	if err != nil {
+		t.Fatalf("Failed to open watch: %v", err)
+	}
+	if event, err := reset.Recv(); err != nil || event.Type != streamResetEvent || event.Order != nil {
+		t.Errorf("Expected reset event, but got %v, %v", event, err)
+	}
+
+	// Закрытие шины завершает подписки с UNAVAILABLE
+	env.bus.Close()
+	for {
+		if _, err = resumed.Recv(); err != nil {
+			break
+		}
+	}
+	expectCode(t, err, codes.Unavailable)
+}
+
+// Тестирование аутентификации по метаданным и открытых служебных сервисов
+func TestAuthentication(t *testing.T) {
+	orders := loadOrders(t, "order1.json")
+	store, err := auth.ParseStaticKeys("ops:" + auth.HashAPIKey("secret") + ":admin")
+	if err != nil {
+		t.Fatalf("Failed to parse keys: %v", err)
+	}
+	env := newTestEnv(t, Options{
+		Authenticators: []auth.Authenticator{auth.NewAPIKeyAuthenticator(store)},
+		Reflection:     true,
+	}, orders...)
+	request := &ordersv1.GetOrderRequest{OrderUid: orders[0].OrderUID}
+
+	_, err = env.client.GetOrder(context.Background(), request)
+	expectCode(t, err, codes.Unauthenticated)
+
+	invalid := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wrong")
+	_, err = env.client.GetOrder(invalid, request)
+	expectCode(t, err, codes.Unauthenticated)
+
+	// Администратор видит контакты без маскирования
+	admin := metadata.AppendToOutgoingContext(context.Background(), "authorization", "ApiKey secret")
+	got, err := env.client.GetOrder(admin, request)
+	if err != nil {
+		t.Fatalf("Failed to get order as admin: %v", err)
+	}
+	if got.Delivery.Phone != orders[0].Delivery.Phone || got.Payment.Transaction != orders[0].Payment.Transaction {
+		t.Errorf("Expected unmasked order for admin, but got %v", got)
+	}
+
+	// Проверка состояния и reflection не требуют учетных данных
+	health, err := healthpb.NewHealthClient(env.conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: ordersv1.OrderService_ServiceDesc.ServiceName,
+	})
+	if err != nil || health.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected SERVING, but got %v, %v", health, err)
+	}
+
+	reflectionStream, err := reflectionpb.NewServerReflectionClient(env.conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open reflection stream: %v", err)
+	}
+	defer reflectionStream.CloseSend()
+	if err := reflectionStream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Failed to send reflection request: %v", err)
+	}
+	response, err := reflectionStream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive reflection response: %v", err)
+	}
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	if !strings.Contains(strings.Join(services, ","), ordersv1.OrderService_ServiceDesc.ServiceName) {
+		t.Errorf("Expected reflection to list order service, but got %v", services)
+	}
+}
@@ -0,0 +1,228 @@
+// Пакет grpcserver реализует gRPC API заказов поверх того же хранилища и кэша, что и REST API
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ordersv1 "github.com/venexene/wbl0-orders-service/api/proto/orders/v1"
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/masking"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+	"github.com/venexene/wbl0-orders-service/internal/orders"
+)
+
+// Тип события о пропуске части потока, как в SSE потоке REST API
+const streamResetEvent = "stream.reset"
+
+// Реализация сервиса заказов
+type OrderService struct {
+	ordersv1.UnimplementedOrderServiceServer
+
+	storage database.StorageInterface
+	cfg     *config.Config
+	cache   *cache.Cache
+	audit   *audit.Logger
+	events  *events.Bus
+}
+
+// Конструктор сервиса заказов
+func NewOrderService(storage database.StorageInterface, cfg *config.Config, cache *cache.Cache, auditLog *audit.Logger, bus *events.Bus) *OrderService {
+	return &OrderService{
+		storage: storage,
+		cfg:     cfg,
+		cache:   cache,
+		audit:   auditLog,
+		events:  bus,
+	}
+}
+
+// Получение заказа по UID из кэша или БД
+func (s *OrderService) GetOrder(ctx context.Context, req *ordersv1.GetOrderRequest) (*ordersv1.Order, error) {
+	orderUID := strings.TrimSpace(req.GetOrderUid())
+	if !orders.ValidUID(orderUID) {
+		return nil, status.Error(codes.InvalidArgument, "order_uid must be a UUID")
+	}
+
+	if order, exists := s.cache.Get(orderUID); exists {
+		return s.maskOrder(ctx, "order.read", order), nil
+	}
+
+	order, err := s.storage.GetOrderByUID(ctx, orderUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "No order with UID "+orderUID)
+		}
+		slog.Error("Failed to get info by UID", "err", err)
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+	s.cache.Set(order)
+
+	return s.maskOrder(ctx, "order.read", order), nil
+}
+
+// Получение нескольких заказов одним вызовом.
+// Сначала используется кэш, промахи загружаются из БД одним пакетом
+func (s *OrderService) BatchGetOrders(ctx context.Context, req *ordersv1.BatchGetOrdersRequest) (*ordersv1.BatchGetOrdersResponse, error) {
+	batch, err := orders.BatchGet(ctx, s.storage, s.cache, req.GetOrderUids(), s.cfg.BatchGetMaxUIDs)
+	switch {
+	case errors.Is(err, orders.ErrNoUIDs), errors.Is(err, orders.ErrTooManyUIDs):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		slog.Error("Failed to batch get orders", "err", err)
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	response := &ordersv1.BatchGetOrdersResponse{MissingUids: batch.MissingUIDs}
+	for _, order := range batch.Orders {
+		response.Orders = append(response.Orders, s.maskOrder(ctx, "order.read", order))
+	}
+	return response, nil
+}
+
+// Потоковая выдача заказов по фильтру через серверный курсор БД
+func (s *OrderService) ListOrders(req *ordersv1.ListOrdersRequest, stream grpc.ServerStreamingServer[ordersv1.Order]) error {
+	filter, err := parseFilter(req.GetFilter())
+	if err != nil {
+		return err
+	}
+	if req.GetLimit() < 0 {
+		return status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	filter.Limit = int(req.GetLimit())
+
+	ctx := stream.Context()
+	err = s.storage.ExportOrders(ctx, filter, func(order *models.Order) error {
+		return stream.Send(s.maskOrder(ctx, "order.list", order))
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		slog.Error("Failed to list orders", "err", err)
+		return status.Error(codes.Internal, "Internal server error")
+	}
+	return nil
+}
+
+// Подписка на новые заказы с продолжением после last_event_id.
+// Отстающий клиент отключается с RESOURCE_EXHAUSTED и продолжает с последнего полученного события
+func (s *OrderService) WatchOrders(req *ordersv1.WatchOrdersRequest, stream grpc.ServerStreamingServer[ordersv1.OrderEvent]) error {
+	filter, err := parseFilter(req.GetFilter())
+	if err != nil {
+		return err
+	}
+
+	sub, replay, gap := s.events.Subscribe(func(event events.Event) bool {
+		return filter.Matches(event.Order)
+	}, s.cfg.StreamBufferSize, req.GetLastEventId())
+	defer sub.Close()
+
+	ctx := stream.Context()
+	if gap {
+		if err := stream.Send(&ordersv1.OrderEvent{Type: streamResetEvent, Time: timestamppb.Now()}); err != nil {
+			return err
+		}
+	}
+	for _, event := range replay {
+		if err := s.sendEvent(ctx, stream, event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				if errors.Is(sub.Err(), events.ErrOverflow) {
+					slog.Warn("Closed order watch", "client", clientAddr(ctx), "err", sub.Err())
+					return status.Error(codes.ResourceExhausted, "Client is too slow, resume with last_event_id")
+				}
+				return status.Error(codes.Unavailable, "Server is shutting down")
+			}
+			if err := s.sendEvent(ctx, stream, event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Отправка события с заказом, замаскированным по роли субъекта
+func (s *OrderService) sendEvent(ctx context.Context, stream grpc.ServerStreamingServer[ordersv1.OrderEvent], event events.Event) error {
+	return stream.Send(&ordersv1.OrderEvent{
+		Id:    event.ID,
+		Type:  event.Type,
+		Time:  timestamppb.New(event.Time),
+		Order: s.maskOrder(ctx, "order.watch", event.Order),
+	})
+}
+
+// Маскирование заказа по роли субъекта с записью в аудит открытых персональных данных
+func (s *OrderService) maskOrder(ctx context.Context, action string, order *models.Order) *ordersv1.Order {
+	masked, exposed := masking.Order(order, roleFromContext(ctx))
+	if len(exposed) > 0 {
+		record := audit.Record{
+			Role:     string(roleFromContext(ctx)),
+			Action:   action,
+			OrderUID: order.OrderUID,
+			Fields:   exposed,
+			ClientIP: clientAddr(ctx),
+		}
+		if method, ok := grpc.Method(ctx); ok {
+			record.Path = method
+		}
+		if principal, ok := principalFromContext(ctx); ok {
+			record.Subject = principal.Subject
+			record.Method = principal.Method
+		}
+		s.audit.Log(record)
+	}
+	return orderToProto(masked)
+}
+
+// Проверка и преобразование фильтра запроса
+func parseFilter(filter *ordersv1.OrderFilter) (database.OrderFilter, error) {
+	if from := filter.GetCreatedFrom(); from != nil {
+		if err := from.CheckValid(); err != nil {
+			return database.OrderFilter{}, status.Error(codes.InvalidArgument, "filter.created_from: "+err.Error())
+		}
+	}
+	if to := filter.GetCreatedTo(); to != nil {
+		if err := to.CheckValid(); err != nil {
+			return database.OrderFilter{}, status.Error(codes.InvalidArgument, "filter.created_to: "+err.Error())
+		}
+	}
+	return filterFromProto(filter), nil
+}
+
+// Адрес клиента вызова без порта
+func clientAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware журнала запросов через slog вместо gin.Logger.
+// Уровень зависит от статуса: 5xx - ERROR, 4xx - WARN, остальные - INFO,
+// поэтому уровень логирования из конфигурации действует и на журнал запросов
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if id := RequestIDFromContext(c); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "HTTP request", attrs...)
+	}
+}
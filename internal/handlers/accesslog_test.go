@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тестирование журнала запросов: уровень по статусу ответа и порог уровня логирования
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer slog.SetDefault(slog.Default())
+
+	var out bytes.Buffer
+	level := new(slog.LevelVar)
+	slog.SetDefault(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: level})))
+
+	router := gin.New()
+	router.Use(AccessLog(), RequestID())
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/missing", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/broken", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	get := func(path string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	get("/ok")
+	get("/missing")
+	get("/broken")
+	expected := []string{
+		`level=INFO msg="HTTP request" method=GET path=/ok status=200`,
+		`level=WARN msg="HTTP request" method=GET path=/missing status=404`,
+		`level=ERROR msg="HTTP request" method=GET path=/broken status=500`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %q in access log: %s", line, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "request_id=req-1") {
+		t.Errorf("Expected request ID in access log: %s", out.String())
+	}
+
+	// Успешные запросы не пишутся при уровне warn
+	out.Reset()
+	level.Set(slog.LevelWarn)
+	get("/ok")
+	get("/missing")
+	if strings.Contains(out.String(), "path=/ok") || !strings.Contains(out.String(), "path=/missing") {
+		t.Errorf("Unexpected access log at warn level: %s", out.String())
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	consumer "github.com/venexene/wbl0-orders-service/internal/kafka"
+)
+
+// Повтор сообщений Kafka с заданного смещения или времени
+type Replayer interface {
+	Replay(ctx context.Context, request consumer.ReplayRequest) (*consumer.ReplayReport, error)
+}
+
+// Структура хендлера административных операций
+type AdminHandler struct {
+	reloader *config.Reloader
+	cfg      *config.Config
+	metrics  *consumer.Metrics
+	replayer Replayer
+}
+
+// Конструктор хендлера административных операций
+func NewAdminHandler(reloader *config.Reloader, cfg *config.Config, metrics *consumer.Metrics, replayer Replayer) *AdminHandler {
+	return &AdminHandler{reloader: reloader, cfg: cfg, metrics: metrics, replayer: replayer}
+}
+
+// Хендлер для перезагрузки конфигурации без перезапуска
+func (h *AdminHandler) ReloadConfigHandle(c *gin.Context) {
+	applied, err := h.reloader.Reload()
+	if err != nil {
+		slog.Error("Failed to reload config", "err", err)
+		WriteProblem(c, ProblemInvalidConfig, err.Error())
+		return
+	}
+
+	if applied == nil {
+		applied = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "Config reloaded",
+		"applied": applied,
+	})
+}
+
+// Хендлер для получения счетчиков обработки сообщений по топикам
+func (h *AdminHandler) ConsumerStatsHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"topics": h.metrics.Snapshot(),
+	})
+}
+
+// Хендлер для повтора сообщений топика через обычную обработку.
+// Повтор выполняется в рамках запроса и ограничен kafka_replay_max_messages
+// и временем ожидания новых сообщений, но не общим таймаутом записи ответа
+func (h *AdminHandler) ReplayHandle(c *gin.Context) {
+	var request consumer.ReplayRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.HTTPMaxBodyBytes))
+	if err := decoder.Decode(&request); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			WriteProblem(c, ProblemPayloadTooLarge, "Request body exceeds "+formatBytes(maxErr.Limit))
+		} else {
+			WriteProblem(c, ProblemInvalidRequest, "Malformed request JSON: "+err.Error())
+		}
+		return
+	}
+
+	// Без отчета клиент не узнает next_offset, поэтому соединение не должно оборваться по таймауту записи
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("Failed to reset write deadline for replay", "err", err)
+	}
+
+	report, err := h.replayer.Replay(c.Request.Context(), request)
+	switch {
+	case errors.Is(err, consumer.ErrInvalidReplay):
+		WriteProblem(c, ProblemInvalidRequest, err.Error())
+	case errors.Is(err, consumer.ErrNoRoute):
+		WriteProblem(c, ProblemNotFound, err.Error())
+	case errors.Is(err, consumer.ErrReplayRunning):
+		WriteProblem(c, ProblemReplayRunning, err.Error())
+	case err != nil:
+		// Повтор идемпотентен, поэтому прерванный запрос можно выполнить заново
+		slog.Error("Failed to replay messages", "err", err)
+		WriteProblem(c, ProblemUnavailable, err.Error())
+	default:
+		c.JSON(http.StatusOK, report)
+	}
+}
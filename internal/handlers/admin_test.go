@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	consumer "github.com/venexene/wbl0-orders-service/internal/kafka"
+)
+
+// Повтор с заданным результатом
+type fakeReplayer struct {
+	request consumer.ReplayRequest
+	report  *consumer.ReplayReport
+	err     error
+	delay   time.Duration
+}
+
+func (r *fakeReplayer) Replay(ctx context.Context, request consumer.ReplayRequest) (*consumer.ReplayReport, error) {
+	time.Sleep(r.delay)
+	r.request = request
+	return r.report, r.err
+}
+
+// Тестирование эндпоинта повтора сообщений
+func TestReplayHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	report := &consumer.ReplayReport{
+		Topic:      "orders",
+		DryRun:     true,
+		Outcomes:   map[string]int{consumer.OutcomeCreated: 1},
+		Partitions: []consumer.ReplayPartition{{Partition: 0, StartOffset: 5, EndOffset: 6, NextOffset: 6}},
+		Entries:    []consumer.ReplayEntry{{Partition: 0, Offset: 5, Outcome: consumer.OutcomeCreated}},
+	}
+	replayReport := compileSchema(t, "ReplayReport")
+
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantCode int
+		wantType string
+	}{
+		{"report", `{"partition":0,"offset":5,"dry_run":true}`, nil, http.StatusOK, ""},
+		{"malformed", `{"offset":`, nil, http.StatusBadRequest, ProblemInvalidRequest.Type},
+		{"invalid", `{}`, fmt.Errorf("%w: exactly one of offset and since must be set", consumer.ErrInvalidReplay), http.StatusBadRequest, ProblemInvalidRequest.Type},
+		{"no route", `{"topic":"payments","offset":0,"partition":0}`, consumer.ErrNoRoute, http.StatusNotFound, ProblemNotFound.Type},
+		{"running", `{"offset":0,"partition":0}`, consumer.ErrReplayRunning, http.StatusConflict, ProblemReplayRunning.Type},
+		{"kafka down", `{"offset":0,"partition":0}`, errors.New("Failed to connect Kafka"), http.StatusServiceUnavailable, ProblemUnavailable.Type},
+		{"too large", `{"topic":"` + strings.Repeat("x", 100) + `","offset":0,"partition":0}`, nil, http.StatusRequestEntityTooLarge, ProblemPayloadTooLarge.Type},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayer := &fakeReplayer{report: report, err: tt.err}
+			if tt.err != nil {
+				replayer.report = nil
+			}
+			cfg := config.Default()
+			cfg.HTTPMaxBodyBytes = 64
+			handler := NewAdminHandler(nil, cfg, consumer.NewMetrics(), replayer)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/admin/consumer/replay", strings.NewReader(tt.body))
+			handler.ReplayHandle(c)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantType == "" {
+				if err := validateBody(t, replayReport, w.Body.Bytes()); err != nil {
+					t.Errorf("Response does not match ReplayReport: %v", err)
+				}
+				if replayer.request.Offset == nil || *replayer.request.Offset != 5 || !replayer.request.DryRun {
+					t.Errorf("Unexpected request %+v", replayer.request)
+				}
+				return
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to decode problem: %v", err)
+			}
+			if problem.Type != tt.wantType {
+				t.Errorf("Expected problem %s, but got %s", tt.wantType, problem.Type)
+			}
+		})
+	}
+}
+
+// Тестирование повтора, который длится дольше таймаута записи сервера
+func TestReplayHandleOutlivesWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	report := &consumer.ReplayReport{
+		Topic:      "orders",
+		Outcomes:   map[string]int{consumer.OutcomeCreated: 1},
+		Partitions: []consumer.ReplayPartition{{Partition: 0, StartOffset: 0, EndOffset: 1, NextOffset: 1}},
+		Entries:    []consumer.ReplayEntry{{Partition: 0, Offset: 0, Outcome: consumer.OutcomeCreated}},
+	}
+	handler := NewAdminHandler(nil, config.Default(), consumer.NewMetrics(), &fakeReplayer{report: report, delay: 300 * time.Millisecond})
+
+	router := gin.New()
+	router.POST("/admin/consumer/replay", handler.ReplayHandle)
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/admin/consumer/replay", "application/json", strings.NewReader(`{"partition":0,"offset":0}`))
+	if err != nil {
+		t.Fatalf("Expected report after write timeout, but got %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got consumer.ReplayReport
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(got.Partitions) != 1 || got.Partitions[0].NextOffset != 1 {
+		t.Errorf("Unexpected response %d: %+v", resp.StatusCode, got)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/orders"
+)
+
+// Хендлер для получения нескольких заказов одним запросом.
+// Сначала используется кэш, промахи загружаются из БД одним пакетом
+func (h *Handler) BatchGetOrdersHandle(c *gin.Context) {
+	var request dto.BatchGetOrdersRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.HTTPMaxBodyBytes))
+	if err := decoder.Decode(&request); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			WriteProblem(c, ProblemPayloadTooLarge, "Request body exceeds "+formatBytes(maxErr.Limit))
+		} else {
+			WriteProblem(c, ProblemInvalidRequest, "Malformed request JSON: "+err.Error())
+		}
+		return
+	}
+
+	batch, err := orders.BatchGet(c.Request.Context(), h.storage, h.cache, request.OrderUIDs, h.cfg.BatchGetMaxUIDs)
+	switch {
+	case errors.Is(err, orders.ErrNoUIDs):
+		WriteValidationProblem(c, "No order UIDs received", []FieldError{
+			{Field: "order_uids", Rule: "min", Message: "failed on rule min=1"},
+		})
+		return
+	case errors.Is(err, orders.ErrTooManyUIDs):
+		WriteValidationProblem(c, "Too many order UIDs in one request", []FieldError{
+			{Field: "order_uids", Rule: "max", Message: "failed on rule max=" + strconv.Itoa(h.cfg.BatchGetMaxUIDs)},
+		})
+		return
+	case err != nil:
+		slog.Error("Failed to batch get orders", "err", err)
+		WriteProblem(c, ProblemInternal, "")
+		return
+	}
+
+	// Ответ в порядке запроса
+	response := dto.BatchGetOrdersResponse{
+		Orders:      make([]dto.OrderResponse, 0, len(batch.Orders)),
+		MissingUIDs: batch.MissingUIDs,
+	}
+	for _, order := range batch.Orders {
+		response.Orders = append(response.Orders, dto.FromOrder(h.maskOrder(c, order)))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
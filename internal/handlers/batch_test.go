@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Мок хранилища с подсчетом пакетных запросов
+type batchStorage struct {
+	mockStorage
+	orders  map[string]*models.Order
+	calls   int
+	queried []string
+}
+
+func (s *batchStorage) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error) {
+	s.calls++
+	s.queried = append(s.queried, orderUIDs...)
+
+	result := make(map[string]*models.Order)
+	for _, uid := range orderUIDs {
+		if order, exists := s.orders[uid]; exists {
+			result[uid] = order
+		}
+	}
+	return result, nil
+}
+
+// Тестирование пакетного получения заказов
+func TestBatchGetOrdersHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	load := func(uid string) *models.Order {
+		order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+		if err != nil {
+			t.Fatalf("Failed to load order from file: %v", err)
+		}
+		order.OrderUID = uid
+		return order
+	}
+	cached := load("11111111-1111-4111-8111-111111111111")
+	stored := load("22222222-2222-4222-8222-222222222222")
+	missing := "33333333-3333-4333-8333-333333333333"
+
+	storage := &batchStorage{orders: map[string]*models.Order{stored.OrderUID: stored}}
+	cache := cache.NewCache(10)
+	cache.Set(cached)
+	handler := NewHandler(storage, &config.Config{HTTPMaxBodyBytes: 1 << 20, BatchGetMaxUIDs: 3}, cache, nil, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Subject: "reconciler", Role: auth.RoleViewer})
+	})
+	router.POST("/api/v1/orders:method", CustomMethods(map[string]gin.HandlersChain{
+		"batchGet": {handler.BatchGetOrdersHandle},
+	}))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return w
+	}
+
+	body := `{"order_uids": ["` + stored.OrderUID + `", "` + cached.OrderUID + `", "` + missing + `", "` + stored.OrderUID + `", "not-a-uuid"]}`
+
+	// Пять UID с повтором дают четыре уникальных, что больше лимита
+	if w := post("/api/v1/orders:batchGet", body); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422 for too many UIDs, but got %d", w.Code)
+	}
+
+	body = `{"order_uids": ["` + stored.OrderUID + `", "` + cached.OrderUID + `", "` + missing + `", "` + stored.OrderUID + `"]}`
+	w := post("/api/v1/orders:batchGet", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response dto.BatchGetOrdersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Orders) != 2 || response.Orders[0].OrderUID != stored.OrderUID || response.Orders[1].OrderUID != cached.OrderUID {
+		t.Errorf("Expected stored and cached orders in request order, but got %+v", response.Orders)
+	}
+	if len(response.MissingUIDs) != 1 || response.MissingUIDs[0] != missing {
+		t.Errorf("Expected missing UID %s, but got %v", missing, response.MissingUIDs)
+	}
+	if storage.calls != 1 || len(storage.queried) != 2 {
+		t.Errorf("Expected one batched query for 2 misses, but got %d calls for %v", storage.calls, storage.queried)
+	}
+	if _, exists := cache.Get(stored.OrderUID); !exists {
+		t.Error("Expected loaded order to be cached")
+	}
+	if err := validateBody(t, compileSchema(t, "BatchGetOrdersResponse"), w.Body.Bytes()); err != nil {
+		t.Errorf("Response does not match BatchGetOrdersResponse: %v", err)
+	}
+
+	// UID не в формате UUID не запрашивается из БД
+	storage.calls = 0
+	w = post("/api/v1/orders:batchGet", `{"order_uids": ["not-a-uuid"]}`)
+	if w.Code != http.StatusOK || storage.calls != 0 {
+		t.Errorf("Expected 200 without DB query, but got %d with %d calls", w.Code, storage.calls)
+	}
+
+	if w := post("/api/v1/orders:batchGet", `{"order_uids": []}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for empty list, but got %d", w.Code)
+	}
+	if w := post("/api/v1/orders:unknown", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown method, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+)
+
+// Заголовки запроса, от которых зависит представление заказа
+const varyOrder = "Accept, Authorization, X-API-Key"
+
+// Получение заказа из кэша или БД вместе с хэшем содержимого.
+// При ошибке пишет ответ с проблемой и возвращает false
+func (h *Handler) loadOrder(c *gin.Context) (cache.Entry, bool) {
+	orderUID := c.Param("uid")
+	if orderUID == "" {
+		WriteProblem(c, ProblemInvalidRequest, "No UID received")
+		return cache.Entry{}, false
+	}
+
+	if entry, exists := h.cache.GetEntry(orderUID); exists {
+		return entry, true
+	}
+
+	order, err := h.storage.GetOrderByUID(c.Request.Context(), orderUID)
+	if err != nil {
+		slog.Error("Failed to get info by UID", "err", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			WriteProblem(c, ProblemOrderNotFound, "No order with UID "+orderUID)
+		} else {
+			WriteProblem(c, ProblemInternal, "")
+		}
+		return cache.Entry{}, false
+	}
+
+	return h.cache.Set(order), true
+}
+
+// Строгий ETag представления заказа.
+// Маскирование зависит от роли, поэтому роль и вид представления входят в тег.
+// Без хэша содержимого тег не формируется
+func orderETag(hash string, role auth.Role, variant string) string {
+	if hash == "" {
+		return ""
+	}
+	tag := hash + "-" + string(role)
+	if variant != "" {
+		tag += "-" + variant
+	}
+	return `"` + tag + `"`
+}
+
+// Установка заголовков кэширования и проверка условий запроса.
+// Возвращает true, если клиенту отправлен ответ 304 Not Modified
+func (h *Handler) notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if maxAge := h.cfg.OrderCacheMaxAge; maxAge > 0 {
+		c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+	} else {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+	c.Header("Vary", varyOrder)
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+
+	// If-Modified-Since учитывается только без If-None-Match (RFC 9110, 13.1.3)
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		if etag == "" || !etagMatches(header, etag) {
+			return false
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err != nil || lastModified.IsZero() || lastModified.After(since) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	return true
+}
+
+// Слабое сравнение тега со списком из If-None-Match (RFC 9110, 13.1.2)
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование условных запросов заказа по ETag и Last-Modified
+func TestGetOrderByUIDConditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+	cache := cache.NewCache(10)
+	cache.Set(order)
+	handler := NewHandler(&mockStorage{}, &config.Config{OrderCacheMaxAge: time.Minute}, cache, nil, nil)
+
+	call := func(handle gin.HandlerFunc, role auth.Role, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1/orders/"+order.OrderUID, nil)
+		for name, value := range headers {
+			c.Request.Header.Set(name, value)
+		}
+		c.Params = []gin.Param{{Key: "uid", Value: order.OrderUID}}
+		auth.SetPrincipal(c, &auth.Principal{Subject: "tester", Role: role})
+		handle(c)
+		return w
+	}
+
+	first := call(handler.GetOrderByUIDHandle, auth.RoleViewer, nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected status 200 with ETag, but got %d and %q", first.Code, etag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "private, max-age=60" {
+		t.Errorf("Unexpected Cache-Control %q", got)
+	}
+	lastModified := first.Header().Get("Last-Modified")
+	if lastModified != order.DateCreated.UTC().Format(http.TimeFormat) {
+		t.Errorf("Unexpected Last-Modified %q", lastModified)
+	}
+
+	cases := []struct {
+		name    string
+		role    auth.Role
+		headers map[string]string
+		status  int
+	}{
+		{"matching etag", auth.RoleViewer, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"etag in list", auth.RoleViewer, map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"wildcard", auth.RoleViewer, map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"stale etag", auth.RoleViewer, map[string]string{"If-None-Match": `"stale"`}, http.StatusOK},
+		{"other role", auth.RoleAdmin, map[string]string{"If-None-Match": etag}, http.StatusOK},
+		{"not modified since", auth.RoleViewer, map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"modified since", auth.RoleViewer, map[string]string{"If-Modified-Since": order.DateCreated.Add(-time.Hour).UTC().Format(http.TimeFormat)}, http.StatusOK},
+		{"etag takes precedence", auth.RoleViewer, map[string]string{"If-None-Match": `"stale"`, "If-Modified-Since": lastModified}, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := call(handler.GetOrderByUIDHandle, tc.role, tc.headers)
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, but got %d", tc.status, w.Code)
+			}
+			if tc.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("Expected empty body for 304, but got %s", w.Body.String())
+			}
+		})
+	}
+
+	// Страница заказа получает собственный тег и отвечает 304 на него
+	page := orderETag(cache.Set(order).ETag, auth.RoleViewer, "html")
+	if page == etag {
+		t.Errorf("Expected order page tag to differ from JSON tag %s", etag)
+	}
+	if w := call(handler.OrderPageHandle, auth.RoleViewer, map[string]string{"If-None-Match": page}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304 for order page, but got %d", w.Code)
+	}
+}
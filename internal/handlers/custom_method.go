@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Имя параметра маршрута с пользовательским методом
+const customMethodParam = "method"
+
+// Хендлер пользовательских методов ресурса вида /orders:batchGet.
+// Маршрут регистрируется как "/orders:method", gin передает имя метода с двоеточием.
+// Цепочка метода выполняется до первого прерывания, поэтому хендлер должен быть последним в маршруте
+func CustomMethods(methods map[string]gin.HandlersChain) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := strings.TrimPrefix(c.Param(customMethodParam), ":")
+
+		chain, exists := methods[name]
+		if !exists {
+			NoRouteHandle(c)
+			return
+		}
+
+		for _, handle := range chain {
+			handle(c)
+			if c.IsAborted() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Функция вычисления адреса замены для устаревшего маршрута
+type SuccessorFunc func(c *gin.Context) string
+
+// Middleware для устаревших маршрутов.
+// Выставляет заголовки Deprecation (RFC 9745), Sunset (RFC 8594) и ссылку на замену
+func Deprecated(since, sunset time.Time, successor SuccessorFunc) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	sunsetValue := sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetValue)
+		if successor != nil {
+			if link := successor(c); link != "" {
+				c.Header("Link", "<"+link+`>; rel="successor-version"`)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тестирование заголовков устаревшего маршрута
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	since := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	router := gin.New()
+	router.GET("/api/orders/:uid", Deprecated(since, sunset, func(c *gin.Context) string {
+		return "/api/v1/orders/" + c.Param("uid")
+	}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/api/v1/orders/:uid", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/orders/42", nil))
+
+	if got := w.Header().Get("Deprecation"); got != "@1792368000" {
+		t.Errorf("Expected Deprecation @1792368000, but got %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Wed, 30 Jun 2027 00:00:00 GMT" {
+		t.Errorf("Expected Sunset date, but got %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `</api/v1/orders/42>; rel="successor-version"` {
+		t.Errorf("Expected successor link, but got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/orders/42", nil))
+	if w.Header().Get("Deprecation") != "" || w.Header().Get("Sunset") != "" {
+		t.Errorf("Expected no deprecation headers on versioned route")
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/api"
+)
+
+// Хендлер для получения спецификации OpenAPI
+func OpenAPIHandle(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", api.OpenAPI)
+}
+
+// Хендлер для страницы Swagger UI
+func DocsPageHandle(c *gin.Context) {
+	c.HTML(http.StatusOK, "swagger.html", gin.H{
+		"spec_url": "/api/openapi.json",
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/gql"
+)
+
+// Хендлер GraphQL запросов к заказам
+type GraphQLHandler struct {
+	schema *gql.Schema
+	cfg    *config.Config
+	audit  *audit.Logger
+}
+
+// Конструктор хендлера GraphQL
+func NewGraphQLHandler(schema *gql.Schema, cfg *config.Config, auditLog *audit.Logger) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema: schema,
+		cfg:    cfg,
+		audit:  auditLog,
+	}
+}
+
+// Хендлер для выполнения GraphQL запроса.
+// Принимает POST с JSON телом или GET с параметрами query, operationName и variables.
+// Ошибки выполнения возвращаются в поле errors ответа со статусом 200
+func (h *GraphQLHandler) GraphQLHandle(c *gin.Context) {
+	var request gql.Request
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				WriteProblem(c, ProblemInvalidRequest, "Malformed variables JSON: "+err.Error())
+				return
+			}
+		}
+	} else {
+		decoder := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.HTTPMaxBodyBytes))
+		if err := decoder.Decode(&request); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				WriteProblem(c, ProblemPayloadTooLarge, "Request body exceeds "+formatBytes(maxErr.Limit))
+			} else {
+				WriteProblem(c, ProblemInvalidRequest, "Malformed request JSON: "+err.Error())
+			}
+			return
+		}
+	}
+
+	if request.Query == "" {
+		WriteProblem(c, ProblemInvalidRequest, "No query received")
+		return
+	}
+
+	result := h.schema.Execute(c.Request.Context(), request, gql.Viewer{
+		Role: auth.RoleFromContext(c),
+		Expose: func(orderUID string, exposed []string) {
+			logExposure(h.audit, c, "order.graphql", orderUID, exposed)
+		},
+	})
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/gql"
+)
+
+// Тестирование разбора GraphQL запросов из POST и GET
+func TestGraphQLHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	schema, err := gql.NewSchema(&mockStorage{}, cache.NewCache(10))
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+	handler := NewGraphQLHandler(schema, &config.Config{HTTPMaxBodyBytes: 1 << 10}, nil)
+
+	router := gin.New()
+	router.GET("/graphql", handler.GraphQLHandle)
+	router.POST("/graphql", handler.GraphQLHandle)
+
+	call := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	query := `{"query": "query($n: Int) { orders(first: $n) { pageInfo { hasNextPage } } }", "variables": {"n": 5}}`
+	w := call(httptest.NewRequest("POST", "/graphql", strings.NewReader(query)))
+	var response struct {
+		Data struct {
+			Orders struct {
+				PageInfo struct {
+					HasNextPage bool `json:"hasNextPage"`
+				} `json:"pageInfo"`
+			} `json:"orders"`
+		} `json:"data"`
+		Errors []any `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK || len(response.Errors) > 0 {
+		t.Fatalf("Unexpected response %d: %s", w.Code, w.Body.String())
+	}
+
+	params := url.Values{"query": {"{ orders { nodes { unknown } } }"}}
+	w = call(httptest.NewRequest("GET", "/graphql?"+params.Encode(), nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK || len(response.Errors) == 0 {
+		t.Errorf("Expected validation errors in body, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := call(httptest.NewRequest("GET", "/graphql", nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without query, but got %d", w.Code)
+	}
+	if w := call(httptest.NewRequest("POST", "/graphql", strings.NewReader("{"))); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for malformed JSON, but got %d", w.Code)
+	}
+	large := `{"query": "` + strings.Repeat(" ", 2<<10) + `"}`
+	if w := call(httptest.NewRequest("POST", "/graphql", strings.NewReader(large))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for large body, but got %d", w.Code)
+	}
+}
@@ -2,18 +2,23 @@ package handlers
 
 import (
 	"context"
-	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/segmentio/kafka-go"
     
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
 	"github.com/venexene/wbl0-orders-service/internal/cache"
 	"github.com/venexene/wbl0-orders-service/internal/config"
 	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/masking"
+	"github.com/venexene/wbl0-orders-service/internal/models"
 )
 
 // Структура хендлера
@@ -21,18 +26,60 @@ type Handler struct {
     storage database.StorageInterface
     cfg     *config.Config
     cache   *cache.Cache
+    audit   *audit.Logger
+    validator *validator.Validate
+    events  *events.Bus
 }
 
 // Конструктор структуры хендлера
-func NewHandler(storage database.StorageInterface, cfg *config.Config, cache *cache.Cache) *Handler {
+func NewHandler(storage database.StorageInterface, cfg *config.Config, cache *cache.Cache, auditLog *audit.Logger, bus *events.Bus) *Handler {
     return &Handler{
         storage: storage,
         cfg:     cfg,
         cache:   cache,
+        audit:   auditLog,
+        validator: models.NewValidator(),
+        events:  bus,
     }
 }
 
 
+// Маскирование заказа по роли субъекта с записью в аудит открытых персональных данных
+func (h *Handler) maskOrder(c *gin.Context, order *models.Order) *models.Order {
+    masked, exposed := masking.Order(order, auth.RoleFromContext(c))
+    h.auditExposure(c, "order.read", order.OrderUID, exposed)
+    return masked
+}
+
+
+// Запись в аудит открытых субъекту персональных данных
+func (h *Handler) auditExposure(c *gin.Context, action, orderUID string, exposed []string) {
+    logExposure(h.audit, c, action, orderUID, exposed)
+}
+
+
+// Запись в журнал аудита открытых субъекту полей заказа
+func logExposure(auditLog *audit.Logger, c *gin.Context, action, orderUID string, exposed []string) {
+    if len(exposed) == 0 {
+        return
+    }
+
+    record := audit.Record{
+        Role:     string(auth.RoleFromContext(c)),
+        Action:   action,
+        OrderUID: orderUID,
+        Fields:   exposed,
+        ClientIP: c.ClientIP(),
+        Path:     c.Request.URL.Path,
+    }
+    if principal, ok := auth.PrincipalFromContext(c); ok {
+        record.Subject = principal.Subject
+        record.Method = principal.Method
+    }
+    auditLog.Log(record)
+}
+
+
 // Хендлер для обработки тестового запроса к серверу
 func (h *Handler) TestServerHandle(c *gin.Context) {
     c.JSON(http.StatusOK, gin.H{
@@ -45,10 +92,8 @@ func (h *Handler) TestServerHandle(c *gin.Context) {
 func (h *Handler) TestDBHandle(c *gin.Context) {
     res, err := h.storage.TestDB()
     if err != nil {
-        log.Printf("Failed to test database: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{
-            "error": "Failed to connect database",
-        })
+        slog.Error("Failed to test database", "err", err)
+        WriteProblem(c, ProblemUnavailable, "Failed to connect database")
         return
     }
 
@@ -69,10 +114,8 @@ func (h *Handler) TestKafkaHandle(c *gin.Context) {
     // Установка соединений с Kafka
     connKafka, err := kafka.DialContext(ctxKafka, "tcp", kafkaBrokers)
     if err != nil {
-        log.Printf("Failed to test Kafka: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{
-            "error" : "Failed to connect Kafka",
-        })
+        slog.Error("Failed to test Kafka", "err", err)
+        WriteProblem(c, ProblemUnavailable, "Failed to connect Kafka")
         return
     }
     defer connKafka.Close() // Отложенное закрытие соединения с Kafka
@@ -86,44 +129,10 @@ func (h *Handler) TestKafkaHandle(c *gin.Context) {
 }
 
 
-// Хендлер для обработки запроса на получение всей информации о заказе по UID
+// Хендлер для обработки запроса на получение всей информации о заказе по UID.
+// Представление выбирается по Accept, по умолчанию JSON
 func (h *Handler) GetOrderByUIDHandle(c *gin.Context) {
-    orderUID := c.Param("uid") // Извлечение UID из URL
-    
-    // Проверка что передан не пустой UID
-    if orderUID == "" {
-        c.JSON(http.StatusBadRequest, gin.H{
-            "error": "No UID recieved",
-        })
-        return
-    }
-
-    //Проверка наличия в кэше
-    if cachedOrder, exists := h.cache.Get(orderUID); exists {
-        c.JSON(http.StatusOK, cachedOrder)
-        return
-    }
-
-    // Вызов функции получения всей информации о заказе по UID
-    order, err := h.storage.GetOrderByUID(c.Request.Context(), orderUID)
-    
-    //Обработка ошибок получения заказа
-    if err != nil {
-        log.Printf("Failed to get info by UID: %v", err)
-        if errors.Is(err, pgx.ErrNoRows) {
-            c.JSON(http.StatusNotFound, gin.H{
-                "error": "Failed to find order",
-            })
-        } else {
-            c.JSON(http.StatusInternalServerError, gin.H{
-                "error": "Internal server error",
-            })
-        }
-        return
-    }
-
-    h.cache.Set(order)
-    c.JSON(http.StatusOK, order)
+    h.serveOrder(c, apiOrderMedia)
 }
 
 
@@ -132,16 +141,12 @@ func (h *Handler) GetAllOrdersUIDHandle(c *gin.Context) {
     orderUIDs, err := h.storage.GetAllOrdersUID(c)
 
     if err != nil {
-        log.Printf("Failed to get UIDs: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{
-            "error": "Failed to get order UIDs",
-        })
+        slog.Error("Failed to get UIDs", "err", err)
+        WriteProblem(c, ProblemInternal, "Failed to get order UIDs")
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{
-        "order_uids": orderUIDs,
-    })
+    c.JSON(http.StatusOK, dto.OrderUIDsResponse{OrderUIDs: orderUIDs})
 }
 
 
@@ -150,50 +155,21 @@ func (h* Handler) AllOrdersPageHandle(c *gin.Context) {
     orderUIDs, err := h.storage.GetAllOrdersUID(c)
 
     if err != nil {
-        log.Printf("Failed to get UIDs: %v", err)
-        c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-            "error": "Failed to load orders",
-        })
+        slog.Error("Failed to get UIDs", "err", err)
+        WriteProblem(c, ProblemInternal, "Failed to load orders")
         return
     }
 
+    // С этого события страница продолжает живое обновление списка
     c.HTML(http.StatusOK, "orders.html", gin.H{
-        "orders": orderUIDs,
+        "orders":      orderUIDs,
+        "lastEventID": h.events.LastID(),
     })
 }
 
 
-// Хендлер для страницы о заказе
+// Хендлер для страницы о заказе.
+// Представление выбирается по Accept, по умолчанию HTML
 func (h* Handler) OrderPageHandle(c *gin.Context) {
-    orderUID := c.Param("uid")
-
-    if orderUID == "" {
-        c.HTML(http.StatusBadRequest, "error.html", gin.H{
-            "error": "No UID received",
-        })
-        return
-    }
-
-    if cachedOrder, exists := h.cache.Get(orderUID); exists {
-        c.HTML(http.StatusOK, "order.html", cachedOrder)
-        return
-    }
-
-    order, err := h.storage.GetOrderByUID(c.Request.Context(), orderUID)
-    if err != nil {
-        log.Printf("Failed to get info by UID: %v", err)
-        if errors.Is(err, pgx.ErrNoRows) {
-            c.HTML(http.StatusNotFound, "error.html", gin.H{
-                "error": "Orders not found",
-            })
-        } else {
-            c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-                "error": "Internal server error",
-            })
-        }
-        return
-    }
-
-    h.cache.Set(order)
-    c.HTML(http.StatusOK, "order.html", order)
-}
\ No newline at end of file
+    h.serveOrder(c, pageOrderMedia)
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
 	"github.com/venexene/wbl0-orders-service/internal/cache"
 	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
 	"github.com/venexene/wbl0-orders-service/internal/models"
 )
 
@@ -29,6 +35,22 @@ func (m *mockStorage) GetOrderByUID(ctx context.Context, orderUID string) (*mode
 	return nil, pgx.ErrNoRows
 }
 
+func (m *mockStorage) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error) {
+	return map[string]*models.Order{}, nil
+}
+
+func (m *mockStorage) SearchOrders(ctx context.Context, filter database.OrderFilter) ([]*models.Order, error) {
+	return nil, nil
+}
+
+func (m *mockStorage) GetItemsByOrderUIDs(ctx context.Context, orderUIDs []string) (map[string][]models.Item, error) {
+	return map[string][]models.Item{}, nil
+}
+
+func (m *mockStorage) ExportOrders(ctx context.Context, filter database.OrderFilter, fn func(order *models.Order) error) error {
+	return nil
+}
+
 func (m *mockStorage) GetAllOrdersUID(ctx context.Context) ([]string, error) {
 	return []string{"order1", "order2"}, nil
 }
@@ -49,12 +71,28 @@ func (m *mockStorage) AddOrderIfNotExists(ctx context.Context, order *models.Ord
 	return nil
 }
 
+func (m *mockStorage) AddOrders(ctx context.Context, orders []*models.Order) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockStorage) ExistingOrderUIDs(ctx context.Context, orderUIDs []string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockStorage) GetIdempotencyKey(ctx context.Context, subject, key string, notBefore time.Time) (*database.IdempotencyRecord, error) {
+	return nil, database.ErrIdempotencyKeyNotFound
+}
+
+func (m *mockStorage) AddOrderWithIdempotencyKey(ctx context.Context, order *models.Order, record *database.IdempotencyRecord, notBefore time.Time) error {
+	return nil
+}
+
 
 // Тестирование подключения к базе 
 func TestTestDBHandle(t *testing.T) {
 	cfg := &config.Config{}
 	cache := cache.NewCache(10)
-	handler := NewHandler(&mockStorage{}, cfg, cache)
+	handler := NewHandler(&mockStorage{}, cfg, cache, nil, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -72,7 +110,7 @@ func TestTestDBHandle(t *testing.T) {
 func TestGetOrderByUIDHandle(t *testing.T) {
 	cfg := &config.Config{}
 	cache := cache.NewCache(10)
-	handler := NewHandler(&mockStorage{}, cfg, cache)
+	handler := NewHandler(&mockStorage{}, cfg, cache, nil, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -93,7 +131,10 @@ func TestGetOrderByUIDHandle(t *testing.T) {
 	handler.GetOrderByUIDHandle(c)
 
 	if w.Code != http.StatusNotFound {
-		t.Errorf("Expected status 404, but got ")
+		t.Errorf("Expected status 404, but got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Errorf("Expected problem content type, but got %q", ct)
 	}
 }
 
@@ -101,7 +142,7 @@ func TestGetOrderByUIDHandle(t *testing.T) {
 func TestGetOrderByUIDHandleFromCache(t *testing.T) {
 	cfg := &config.Config{}
 	cache := cache.NewCache(10)
-	handler := NewHandler(&mockStorage{}, cfg, cache)
+	handler := NewHandler(&mockStorage{}, cfg, cache, nil, nil)
 
 	testOrder := &models.Order{OrderUID: "cached"}
 	cache.Set(testOrder)
@@ -116,4 +157,54 @@ func TestGetOrderByUIDHandleFromCache(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, but got %d", w.Code)
 	}
-}
\ No newline at end of file
+}
+
+// Тестирование маскирования данных по роли и записи в аудит
+func TestGetOrderByUIDHandleMasking(t *testing.T) {
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+
+	var auditBuf bytes.Buffer
+	cache := cache.NewCache(10)
+	cache.Set(order)
+	handler := NewHandler(&mockStorage{}, &config.Config{}, cache, audit.NewLogger(&auditBuf), nil)
+
+	request := func(role auth.Role) models.Order {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		c.Params = []gin.Param{{Key: "uid", Value: order.OrderUID}}
+		auth.SetPrincipal(c, &auth.Principal{Subject: "tester", Method: auth.MethodAPIKey, Role: role})
+
+		handler.GetOrderByUIDHandle(c)
+
+		var got models.Order
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return got
+	}
+
+	viewer := request(auth.RoleViewer)
+	if viewer.Delivery.Phone == order.Delivery.Phone || viewer.Payment.Transaction != "" || viewer.Payment.Amount != 0 {
+		t.Errorf("Failed to mask order for viewer: %+v", viewer.Payment)
+	}
+	if auditBuf.Len() != 0 {
+		t.Errorf("Expected no audit records for viewer, but got %s", auditBuf.String())
+	}
+
+	admin := request(auth.RoleAdmin)
+	if admin.Delivery.Phone != order.Delivery.Phone || admin.Payment.Transaction != order.Payment.Transaction {
+		t.Error("Expected full order for admin")
+	}
+
+	var record audit.Record
+	if err := json.Unmarshal(auditBuf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to unmarshal audit record: %v", err)
+	}
+	if record.Subject != "tester" || record.OrderUID != order.OrderUID || len(record.Fields) == 0 {
+		t.Errorf("Unexpected audit record %+v", record)
+	}
+}
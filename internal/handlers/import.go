@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/importer"
+)
+
+// Итоговая строка отчета импорта через HTTP
+type importResult struct {
+	Summary *importer.Summary `json:"summary"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Хендлер для импорта заказов из NDJSON или JSON массива.
+// Отчет по строкам отдается потоком NDJSON, последняя строка содержит итоги.
+// После прерывания импорт продолжается с resume_after=<summary.last_line>
+func (h *Handler) ImportOrdersHandle(c *gin.Context) {
+	options := importer.Options{BatchSize: h.cfg.ImportBatchSize}
+	var errs []FieldError
+
+	if value := c.Query("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "dry_run", Rule: "boolean", Message: "must be true or false"})
+		}
+		options.DryRun = dryRun
+	}
+	if value := c.Query("resume_after"); value != "" {
+		line, err := strconv.Atoi(value)
+		if err != nil || line < 0 {
+			errs = append(errs, FieldError{Field: "resume_after", Rule: "min", Message: "must be a non-negative line number"})
+		}
+		options.ResumeAfter = line
+	}
+	if len(errs) > 0 {
+		WriteValidationProblem(c, "Invalid import parameters", errs)
+		return
+	}
+
+	// Импорт больших файлов может длиться дольше общего таймаута записи ответа
+	controller := http.NewResponseController(c.Writer)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("Failed to reset write deadline for import", "err", err)
+	}
+	// Отчет пишется, пока тело еще читается; без этого HTTP/1 сервер закрывает тело после отправки заголовков
+	if err := controller.EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("Failed to enable full duplex for import", "err", err)
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.ImportMaxBytes)
+
+	summary, err := importer.New(h.storage, options).Run(c.Request.Context(), body, func(entry importer.Entry) error {
+		return encoder.Encode(entry)
+	})
+
+	// Заголовки уже отправлены, поэтому ошибка передается в итоговой строке
+	result := importResult{Summary: summary}
+	if err != nil {
+		slog.Error("Failed to import orders", "last_line", summary.LastLine, "err", err)
+		result.Error = err.Error()
+	} else {
+		log.Printf("Imported orders: %d accepted, %d duplicate, %d invalid, dry run %t",
+			summary.Accepted, summary.Duplicate, summary.Invalid, summary.DryRun)
+	}
+	if err := encoder.Encode(result); err != nil {
+		slog.Error("Failed to write import summary", "err", err)
+	}
+	c.Writer.Flush()
+}
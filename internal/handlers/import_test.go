@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/importer"
+)
+
+// Тестирование импорта заказов через HTTP с отчетом по строкам
+func TestImportOrdersHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	order, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	var compact strings.Builder
+	if err := json.NewEncoder(&compact).Encode(json.RawMessage(order)); err != nil {
+		t.Fatalf("Failed to compact order: %v", err)
+	}
+	body := compact.String() + `{"order_uid": "broken"}` + "\n"
+
+	cfg := &config.Config{ImportBatchSize: 10, ImportMaxBytes: 1 << 20}
+	handler := NewHandler(&mockStorage{}, cfg, cache.NewCache(10), nil, nil)
+
+	router := gin.New()
+	router.POST("/api/v1/orders:method", CustomMethods(map[string]gin.HandlersChain{
+		"import": {handler.ImportOrdersHandle},
+	}))
+
+	post := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/orders:import"+query, strings.NewReader(body)))
+		return w
+	}
+
+	w := post("?dry_run=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 2 report lines and summary, but got %d: %s", len(lines), w.Body.String())
+	}
+
+	var first, second importer.Entry
+	_ = json.Unmarshal([]byte(lines[0]), &first)
+	_ = json.Unmarshal([]byte(lines[1]), &second)
+	if first.Status != importer.StatusAccepted || second.Status != importer.StatusInvalid || second.Line != 2 {
+		t.Errorf("Unexpected report entries %+v %+v", first, second)
+	}
+
+	if err := validateBody(t, compileSchema(t, "ImportResult"), []byte(lines[2])); err != nil {
+		t.Errorf("Summary does not match ImportResult: %v", err)
+	}
+	var result importResult
+	if err := json.Unmarshal([]byte(lines[2]), &result); err != nil {
+		t.Fatalf("Failed to unmarshal summary: %v", err)
+	}
+	if result.Summary == nil || !result.Summary.DryRun || result.Summary.Accepted != 1 || result.Summary.LastLine != 2 {
+		t.Errorf("Unexpected summary %+v", result.Summary)
+	}
+
+	// Продолжение после второй строки не обрабатывает записи
+	w = post("?resume_after=2")
+	lines = strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 1 {
+		t.Errorf("Expected only summary after resume, but got %s", w.Body.String())
+	}
+
+	if w := post("?dry_run=maybe"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for invalid dry_run, but got %d", w.Code)
+	}
+}
+
+// Тестирование импорта из нескольких пакетов через настоящее соединение:
+// отчет пишется одновременно с чтением тела запроса
+func TestImportOrdersHandleStreaming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	order, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(order, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal order: %v", err)
+	}
+
+	const total = 1000
+	var body strings.Builder
+	encoder := json.NewEncoder(&body)
+	for i := 0; i < total; i++ {
+		raw["order_uid"] = fmt.Sprintf("%08x-0000-4000-8000-000000000000", i)
+		if err := encoder.Encode(raw); err != nil {
+			t.Fatalf("Failed to encode order: %v", err)
+		}
+	}
+
+	cfg := &config.Config{ImportBatchSize: 50, ImportMaxBytes: 1 << 26}
+	handler := NewHandler(&mockStorage{}, cfg, cache.NewCache(10), nil, nil)
+
+	router := gin.New()
+	router.POST("/api/v1/orders:method", CustomMethods(map[string]gin.HandlersChain{
+		"import": {handler.ImportOrdersHandle},
+	}))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Тело без длины отправляется частями, как выгрузка из другого процесса
+	upload := io.MultiReader(strings.NewReader(body.String()))
+	resp, err := http.Post(server.URL+"/api/v1/orders:import", "application/x-ndjson", upload)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	defer resp.Body.Close()
+	report, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(report)), "\n")
+	var result importResult
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &result); err != nil {
+		t.Fatalf("Failed to unmarshal summary: %v", err)
+	}
+	if result.Error != "" || result.Summary == nil || result.Summary.Accepted != total || len(lines) != total+1 {
+		t.Errorf("Expected %d accepted orders, but got %d lines and %+v, error %q", total, len(lines), result.Summary, result.Error)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Заголовки идемпотентного создания заказа
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 200
+)
+
+// Хендлер для создания заказа через HTTP.
+// Принимает тот же JSON, что и сообщения Kafka, и проверяет его теми же правилами
+func (h *Handler) CreateOrderHandle(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.HTTPMaxBodyBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			WriteProblem(c, ProblemPayloadTooLarge, "Request body exceeds "+formatBytes(maxErr.Limit))
+		} else {
+			WriteProblem(c, ProblemInvalidRequest, "Failed to read request body")
+		}
+		return
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(body, &order); err != nil {
+		WriteProblem(c, ProblemInvalidRequest, "Malformed order JSON: "+err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(order); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			WriteProblem(c, ProblemInvalidRequest, err.Error())
+			return
+		}
+		WriteValidationProblem(c, "Order does not pass validation", fieldErrors(validationErrs))
+		return
+	}
+
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		err = h.storage.AddOrder(c.Request.Context(), &order)
+	} else {
+		if len(key) > maxIdempotencyKeyLength {
+			WriteProblem(c, ProblemInvalidRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		record := &database.IdempotencyRecord{
+			Subject:     idempotencySubject(c),
+			Key:         key,
+			RequestHash: hashBody(body),
+			OrderUID:    order.OrderUID,
+		}
+		notBefore := time.Now().Add(-h.cfg.IdempotencyKeyTTL)
+
+		// Повтор запроса с тем же ключом возвращает прежний результат
+		previous, lookupErr := h.storage.GetIdempotencyKey(c.Request.Context(), record.Subject, key, notBefore)
+		switch {
+		case lookupErr == nil:
+			if previous.RequestHash != record.RequestHash {
+				WriteProblem(c, ProblemIdempotencyReuse, "Idempotency-Key was used with a different request body")
+				return
+			}
+			c.Header(IdempotencyReplayedHeader, "true")
+			h.writeCreated(c, &order)
+			return
+		case !errors.Is(lookupErr, database.ErrIdempotencyKeyNotFound):
+			slog.Error("Failed to get idempotency key", "err", lookupErr)
+			WriteProblem(c, ProblemInternal, "")
+			return
+		}
+
+		err = h.storage.AddOrderWithIdempotencyKey(c.Request.Context(), &order, record, notBefore)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrOrderExists):
+			WriteProblem(c, ProblemOrderExists, "Order with UID "+order.OrderUID+" already exists")
+		case errors.Is(err, database.ErrIdempotencyKeyExists):
+			WriteProblem(c, ProblemIdempotencyBusy, "Another request with this Idempotency-Key is in progress")
+		default:
+			slog.Error("Failed to add order", "err", err)
+			WriteProblem(c, ProblemInternal, "")
+		}
+		return
+	}
+
+	log.Printf("Order created via HTTP with UID %s", order.OrderUID)
+	h.cache.Set(&order)
+	h.events.Publish(events.OrderCreated, &order)
+	h.writeCreated(c, &order)
+}
+
+// Ответ 201 со ссылкой на созданный заказ
+func (h *Handler) writeCreated(c *gin.Context, order *models.Order) {
+	c.Header("Location", "/api/v1/orders/"+order.OrderUID)
+	c.JSON(http.StatusCreated, dto.FromOrder(h.maskOrder(c, order)))
+}
+
+// Преобразование ошибок валидатора в ошибки по полям.
+// Путь поля строится по JSON именам без имени корневой структуры
+func fieldErrors(errs validator.ValidationErrors) []FieldError {
+	result := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		field := fe.Namespace()
+		if _, rest, found := strings.Cut(field, "."); found {
+			field = rest
+		}
+
+		message := "failed on rule " + fe.Tag()
+		if fe.Param() != "" {
+			message += "=" + fe.Param()
+		}
+
+		result = append(result, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: message,
+		})
+	}
+	return result
+}
+
+// Владелец ключа идемпотентности: субъект аутентификации или IP адрес
+func idempotencySubject(c *gin.Context) string {
+	if principal, ok := auth.PrincipalFromContext(c); ok && principal.Method != auth.MethodAnonymous {
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Хэш тела запроса для сравнения повторов
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Размер в байтах для сообщений об ошибках
+func formatBytes(n int64) string {
+	return strconv.FormatInt(n, 10) + " bytes"
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Мок хранилища с сохранением заказов и ключей идемпотентности в памяти
+type ingestStorage struct {
+	mockStorage
+	orders map[string]bool
+	keys   map[string]*database.IdempotencyRecord
+}
+
+func newIngestStorage() *ingestStorage {
+	return &ingestStorage{
+		orders: make(map[string]bool),
+		keys:   make(map[string]*database.IdempotencyRecord),
+	}
+}
+
+func (s *ingestStorage) AddOrder(ctx context.Context, order *models.Order) error {
+	if s.orders[order.OrderUID] {
+		return database.ErrOrderExists
+	}
+	s.orders[order.OrderUID] = true
+	return nil
+}
+
+func (s *ingestStorage) GetIdempotencyKey(ctx context.Context, subject, key string, notBefore time.Time) (*database.IdempotencyRecord, error) {
+	if record, exists := s.keys[subject+"/"+key]; exists {
+		return record, nil
+	}
+	return nil, database.ErrIdempotencyKeyNotFound
+}
+
+func (s *ingestStorage) AddOrderWithIdempotencyKey(ctx context.Context, order *models.Order, record *database.IdempotencyRecord, notBefore time.Time) error {
+	if err := s.AddOrder(ctx, order); err != nil {
+		return err
+	}
+	s.keys[record.Subject+"/"+record.Key] = record
+	return nil
+}
+
+// Тестирование создания заказа через HTTP
+func TestCreateOrderHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	body, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	var order models.Order
+	if err := json.Unmarshal(body, &order); err != nil {
+		t.Fatalf("Failed to unmarshal order: %v", err)
+	}
+
+	storage := newIngestStorage()
+	cache := cache.NewCache(10)
+	handler := NewHandler(storage, &config.Config{HTTPMaxBodyBytes: 1 << 20, IdempotencyKeyTTL: time.Hour}, cache, nil, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Subject: "uploader", Method: auth.MethodAPIKey, Role: auth.RoleAdmin})
+	})
+	router.POST("/api/v1/orders", handler.CreateOrderHandle)
+
+	post := func(payload string, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(string(body), "key-1")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Location"); got != "/api/v1/orders/"+order.OrderUID {
+		t.Errorf("Expected Location of created order, but got %q", got)
+	}
+	if _, exists := cache.Get(order.OrderUID); !exists {
+		t.Error("Expected created order in cache")
+	}
+
+	// Повтор с тем же ключом возвращает прежний результат
+	w = post(string(body), "key-1")
+	if w.Code != http.StatusCreated || w.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Errorf("Expected replayed 201, but got %d", w.Code)
+	}
+
+	// Тот же ключ с другим телом
+	if w = post(strings.Replace(string(body), `"locale": "en"`, `"locale": "ru"`, 1), "key-1"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for reused key, but got %d", w.Code)
+	}
+
+	// Повтор без ключа
+	if w = post(string(body), ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for duplicate UID, but got %d", w.Code)
+	}
+
+	if w = post("{not json", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for malformed JSON, but got %d", w.Code)
+	}
+}
+
+// Тестирование ошибок валидации по полям
+func TestCreateOrderValidationErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	body, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal order: %v", err)
+	}
+	raw["delivery"].(map[string]any)["email"] = "not-an-email"
+	raw["items"].([]any)[0].(map[string]any)["status"] = 1000
+	invalid, _ := json.Marshal(raw)
+
+	handler := NewHandler(newIngestStorage(), &config.Config{HTTPMaxBodyBytes: 1 << 20}, cache.NewCache(10), nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(string(invalid)))
+	handler.CreateOrderHandle(c)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d", w.Code)
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to unmarshal problem: %v", err)
+	}
+	fields := map[string]string{}
+	for _, fe := range problem.Errors {
+		fields[fe.Field] = fe.Rule
+	}
+	if fields["delivery.email"] != "email" || fields["items[0].status"] != "max" {
+		t.Errorf("Expected errors for delivery.email and items[0].status, but got %+v", problem.Errors)
+	}
+	if err := validateBody(t, compileSchema(t, "Problem"), w.Body.Bytes()); err != nil {
+		t.Errorf("Validation problem does not match Problem: %v", err)
+	}
+
+	// Слишком большое тело запроса
+	handler = NewHandler(newIngestStorage(), &config.Config{HTTPMaxBodyBytes: 16}, cache.NewCache(10), nil, nil)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/v1/orders", strings.NewReader(string(body)))
+	handler.CreateOrderHandle(c)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, but got %d", w.Code)
+	}
+}
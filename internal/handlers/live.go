@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/masking"
+)
+
+// Ограничения соединения живого списка заказов
+const (
+	liveWriteWait      = 10 * time.Second // Время на отправку одного сообщения
+	liveCloseWait      = time.Second      // Ожидание ответного кадра закрытия
+	liveMaxMessageSize = 512              // Клиент присылает только управляющие кадры
+)
+
+// Сообщение живого списка заказов
+type liveMessage struct {
+	Type  string             `json:"type"`
+	ID    uint64             `json:"id,omitempty"`
+	Order *dto.OrderResponse `json:"order,omitempty"`
+}
+
+// Хендлер живого списка заказов по WebSocket с учетом открытых соединений
+type LiveHandler struct {
+	bus      *events.Bus
+	cfg      *config.Config
+	audit    *audit.Logger
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	conns   map[chan struct{}]struct{} // Каналы остановки открытых соединений
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// Конструктор хендлера живого списка заказов.
+// Проверка Origin остается по умолчанию: соединение принимается только со своего хоста
+func NewLiveHandler(bus *events.Bus, cfg *config.Config, auditLog *audit.Logger) *LiveHandler {
+	return &LiveHandler{
+		bus:   bus,
+		cfg:   cfg,
+		audit: auditLog,
+		conns: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Хендлер WebSocket соединения для живого обновления списка заказов.
+// Поддерживает фильтры поиска и продолжение с параметра last_event_id
+func (h *LiveHandler) LiveOrdersHandle(c *gin.Context) {
+	filter, errs := parseOrderFilter(c)
+	var lastID uint64
+	if value := c.Query("last_event_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "last_event_id", Rule: "number", Message: "must be an event ID"})
+		}
+		lastID = parsed
+	}
+	if len(errs) > 0 {
+		WriteValidationProblem(c, "Invalid live parameters", errs)
+		return
+	}
+
+	done, ok := h.track()
+	if !ok {
+		WriteProblem(c, ProblemUnavailable, "Server is shutting down")
+		return
+	}
+	defer h.untrack(done)
+
+	// Upgrade сам отвечает клиенту при ошибке рукопожатия
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.Warn("Failed to upgrade live connection", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	sub, replay, gap := h.bus.Subscribe(func(event events.Event) bool {
+		return filter.Matches(event.Order)
+	}, h.cfg.StreamBufferSize, lastID)
+	defer sub.Close()
+
+	// Чтение нужно для обработки ping, pong и закрытия со стороны клиента
+	closed := make(chan struct{})
+	conn.SetReadLimit(liveMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(2 * h.cfg.StreamHeartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.cfg.StreamHeartbeat))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if gap {
+		if err := h.write(conn, liveMessage{Type: streamResetEvent}); err != nil {
+			return
+		}
+	}
+	for _, event := range replay {
+		if err := h.writeEvent(c, conn, event); err != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(h.cfg.StreamHeartbeat)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-done:
+			h.close(conn, closed, websocket.CloseGoingAway, "Server is shutting down")
+			return
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Отстающий клиент переподключится с last_event_id и получит пропущенное из кольца
+				if errors.Is(sub.Err(), events.ErrOverflow) {
+					slog.Warn("Closed live connection", "client", c.ClientIP(), "err", sub.Err())
+					h.close(conn, closed, websocket.CloseTryAgainLater, "Client is too slow")
+				} else {
+					h.close(conn, closed, websocket.CloseGoingAway, "Server is shutting down")
+				}
+				return
+			}
+			if err := h.writeEvent(c, conn, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Закрытие всех соединений кадром 1001 с ожиданием их завершения.
+// Нужно отдельно от http.Server.Shutdown, который не отслеживает перехваченные соединения
+func (h *LiveHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closing {
+		h.closing = true
+		for done := range h.conns {
+			close(done)
+		}
+	}
+	h.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Число открытых соединений
+func (h *LiveHandler) Connections() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.conns)
+}
+
+// Учет нового соединения, после начала остановки новые не принимаются
+func (h *LiveHandler) track() (chan struct{}, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return nil, false
+	}
+	h.wg.Add(1)
+	done := make(chan struct{})
+	h.conns[done] = struct{}{}
+	return done, true
+}
+
+// Снятие соединения с учета
+func (h *LiveHandler) untrack(done chan struct{}) {
+	h.mu.Lock()
+	delete(h.conns, done)
+	h.mu.Unlock()
+	h.wg.Done()
+}
+
+// Отправка кадра закрытия и ожидание ответа клиента
+func (h *LiveHandler) close(conn *websocket.Conn, closed <-chan struct{}, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(liveWriteWait)); err != nil {
+		return
+	}
+	select {
+	case <-closed:
+	case <-time.After(liveCloseWait):
+	}
+}
+
+// Отправка сообщения с ограничением времени записи
+func (h *LiveHandler) write(conn *websocket.Conn, message liveMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+	return conn.WriteJSON(message)
+}
+
+// Отправка события с заказом, замаскированным по роли клиента
+func (h *LiveHandler) writeEvent(c *gin.Context, conn *websocket.Conn, event events.Event) error {
+	masked, exposed := masking.Order(event.Order, auth.RoleFromContext(c))
+	response := dto.FromOrder(masked)
+	if err := h.write(conn, liveMessage{Type: event.Type, ID: event.ID, Order: &response}); err != nil {
+		return err
+	}
+	logExposure(h.audit, c, "order.live", event.Order.OrderUID, exposed)
+	return nil
+}
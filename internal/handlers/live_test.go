@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование живого списка заказов: доставка, продолжение, переполнение и остановка
+func TestLiveOrdersHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+
+	bus := events.NewBus(10)
+	cfg := &config.Config{StreamBufferSize: 2, StreamHeartbeat: time.Second}
+	handler := NewLiveHandler(bus, cfg, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/orders/live", func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Subject: "tester", Role: auth.RoleViewer})
+		handler.LiveOrdersHandle(c)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/orders/live"
+
+	dial := func(query string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url+query, nil)
+		if err != nil {
+			t.Fatalf("Failed to dial live endpoint: %v", err)
+		}
+		return conn
+	}
+	waitSubscribers := func(n int) {
+		for deadline := time.Now().Add(time.Second); bus.Subscribers() != n; {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d subscribers, but got %d", n, bus.Subscribers())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	read := func(conn *websocket.Conn) liveMessage {
+		var message liveMessage
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed to read live message: %v", err)
+		}
+		return message
+	}
+
+	conn := dial("?customer_id=" + order.CustomerID)
+	waitSubscribers(1)
+
+	published := bus.Publish(events.OrderCreated, order)
+	message := read(conn)
+	if message.Type != events.OrderCreated || message.ID != published.ID || message.Order == nil {
+		t.Fatalf("Unexpected message %+v", message)
+	}
+	if message.Order.OrderUID != order.OrderUID || message.Order.Delivery.Phone == order.Delivery.Phone {
+		t.Errorf("Expected masked order, but got %+v", message.Order)
+	}
+	conn.Close()
+	waitSubscribers(0)
+
+	// Продолжение с идентификатора страницы отдает пропущенное событие
+	resumed := dial("?last_event_id=" + strconv.FormatUint(published.ID-1, 10))
+	if message := read(resumed); message.ID != published.ID {
+		t.Errorf("Expected replay of event %d, but got %+v", published.ID, message)
+	}
+	resumed.Close()
+	waitSubscribers(0)
+
+	// Клиент, не читающий сообщения, отключается кодом 1013
+	slow := dial("")
+	defer slow.Close()
+	waitSubscribers(1)
+	for i := 0; i < 5; i++ {
+		bus.Publish(events.OrderCreated, order)
+	}
+	var closeErr *websocket.CloseError
+	for closeErr == nil {
+		slow.SetReadDeadline(time.Now().Add(time.Second))
+		if _, _, err := slow.ReadMessage(); err != nil && !errors.As(err, &closeErr) {
+			t.Fatalf("Expected close frame, but got %v", err)
+		}
+	}
+	if closeErr.Code != websocket.CloseTryAgainLater {
+		t.Errorf("Expected close code %d, but got %d", websocket.CloseTryAgainLater, closeErr.Code)
+	}
+
+	// Остановка закрывает соединения кодом 1001 и не принимает новые
+	active := dial("")
+	defer active.Close()
+	waitSubscribers(1)
+
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := active.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := handler.Shutdown(ctx); err != nil {
+		t.Fatalf("Failed to shutdown live handler: %v", err)
+	}
+	if handler.Connections() != 0 {
+		t.Errorf("Expected no connections after shutdown, but got %d", handler.Connections())
+	}
+	if err := <-closed; !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected close code %d, but got %v", websocket.CloseGoingAway, err)
+	}
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after shutdown, but got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+)
+
+// Представления заказа
+const (
+	mediaJSON = "application/json"
+	mediaXML  = "application/xml"
+	mediaHTML = "text/html"
+)
+
+// Представления заказа в порядке предпочтения для API и для страниц.
+// Порядок решает, что отдать на Accept: */* или без заголовка
+var (
+	apiOrderMedia  = []string{mediaJSON, mediaXML, mediaHTML}
+	pageOrderMedia = []string{mediaHTML, mediaJSON, mediaXML}
+)
+
+// Вид представления в ETag, у JSON суффикса нет для совместимости с прежними тегами
+var mediaETagVariants = map[string]string{
+	mediaJSON: "",
+	mediaXML:  "xml",
+	mediaHTML: "html",
+}
+
+// Выбор представления по заголовку Accept с учетом весов q и масок type/*.
+// Для каждого предложенного типа берется вес самого точного подходящего диапазона,
+// при равных весах побеждает порядок offered. Пустая строка означает 406
+func negotiateMedia(accept string, offered []string) string {
+	accept = strings.TrimSpace(accept)
+	if accept == "" {
+		return offered[0]
+	}
+
+	type mediaRange struct {
+		typ, subtype string
+		q            float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		typ, subtype, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+		if typ == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed >= 0 {
+					q = min(parsed, 1)
+				} else {
+					q = 0
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, media := range offered {
+		typ, subtype, _ := strings.Cut(media, "/")
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			var s int
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = media, q
+		}
+	}
+	return best
+}
+
+// Отдача заказа в представлении, выбранном по Accept.
+// Общая логика для API и HTML страницы заказа
+func (h *Handler) serveOrder(c *gin.Context, offered []string) {
+	media := negotiateMedia(c.GetHeader("Accept"), offered)
+	if media == "" {
+		WriteProblem(c, ProblemNotAcceptable, "Supported media types: "+strings.Join(offered, ", "))
+		return
+	}
+
+	entry, ok := h.loadOrder(c)
+	if !ok {
+		return
+	}
+
+	etag := orderETag(entry.ETag, auth.RoleFromContext(c), mediaETagVariants[media])
+	if h.notModified(c, etag, entry.Order.DateCreated) {
+		return
+	}
+
+	masked := h.maskOrder(c, entry.Order)
+	switch media {
+	case mediaHTML:
+		c.HTML(http.StatusOK, "order.html", masked)
+	case mediaXML:
+		c.XML(http.StatusOK, dto.FromOrder(masked))
+	default:
+		c.JSON(http.StatusOK, dto.FromOrder(masked))
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование выбора представления по Accept
+func TestNegotiateMedia(t *testing.T) {
+	cases := []struct {
+		accept   string
+		offered  []string
+		expected string
+	}{
+		{"", apiOrderMedia, mediaJSON},
+		{"", pageOrderMedia, mediaHTML},
+		{"*/*", apiOrderMedia, mediaJSON},
+		{"*/*", pageOrderMedia, mediaHTML},
+		{"application/xml", apiOrderMedia, mediaXML},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", apiOrderMedia, mediaHTML},
+		{"application/json;q=0.5, application/xml", apiOrderMedia, mediaXML},
+		{"application/*", pageOrderMedia, mediaJSON},
+		{"text/*;q=0, */*", apiOrderMedia, mediaJSON},
+		{"*/*, application/json;q=0", apiOrderMedia, mediaXML},
+		{"image/png", apiOrderMedia, ""},
+		{"application/json;q=0", apiOrderMedia, ""},
+	}
+
+	for _, tc := range cases {
+		if got := negotiateMedia(tc.accept, tc.offered); got != tc.expected {
+			t.Errorf("negotiateMedia(%q, %v): expected %q, but got %q", tc.accept, tc.offered, tc.expected, got)
+		}
+	}
+}
+
+// Тестирование отдачи заказа в XML и ответа 406
+func TestGetOrderByUIDHandleNegotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+	cache := cache.NewCache(10)
+	cache.Set(order)
+	handler := NewHandler(&mockStorage{}, &config.Config{}, cache, nil, nil)
+
+	call := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1/orders/"+order.OrderUID, nil)
+		c.Request.Header.Set("Accept", accept)
+		c.Params = []gin.Param{{Key: "uid", Value: order.OrderUID}}
+		auth.SetPrincipal(c, &auth.Principal{Subject: "tester", Role: auth.RoleAdmin})
+		handler.GetOrderByUIDHandle(c)
+		return w
+	}
+
+	w := call("application/xml")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), mediaXML) {
+		t.Fatalf("Expected XML response, but got %d with %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var got dto.OrderResponse
+	if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to unmarshal XML response: %v", err)
+	}
+	if got.OrderUID != order.OrderUID || len(got.Items) != len(order.Items) || got.Payment.Amount == nil {
+		t.Errorf("Unexpected XML order: %+v", got)
+	}
+
+	jsonTag := call("application/json").Header().Get("ETag")
+	if xmlTag := w.Header().Get("ETag"); xmlTag == "" || xmlTag == jsonTag {
+		t.Errorf("Expected distinct ETags for XML and JSON, but got %q and %q", xmlTag, jsonTag)
+	}
+	if vary := w.Header().Get("Vary"); !strings.Contains(vary, "Accept") {
+		t.Errorf("Expected Vary to include Accept, but got %q", vary)
+	}
+
+	if w := call("image/png"); w.Code != http.StatusNotAcceptable || w.Header().Get("Content-Type") != ProblemContentType {
+		t.Errorf("Expected 406 problem, but got %d with %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
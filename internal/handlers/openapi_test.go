@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/venexene/wbl0-orders-service/api"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/importer"
+	consumer "github.com/venexene/wbl0-orders-service/internal/kafka"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Адрес, под которым спецификация регистрируется в компиляторе схем
+const specURL = "https://orders.local/openapi.json"
+
+// Компиляция схемы из раздела components/schemas спецификации
+func compileSchema(t *testing.T, name string) *jsonschema.Schema {
+	t.Helper()
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(api.OpenAPI))
+	if err != nil {
+		t.Fatalf("Failed to parse OpenAPI document: %v", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+	if err := compiler.AddResource(specURL, doc); err != nil {
+		t.Fatalf("Failed to add OpenAPI document: %v", err)
+	}
+
+	schema, err := compiler.Compile(specURL + "#/components/schemas/" + name)
+	if err != nil {
+		t.Fatalf("Failed to compile schema %s: %v", name, err)
+	}
+	return schema
+}
+
+// Проверка тела ответа по схеме
+func validateBody(t *testing.T, schema *jsonschema.Schema, body []byte) error {
+	t.Helper()
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to parse body %s: %v", body, err)
+	}
+	return schema.Validate(instance)
+}
+
+// Тестирование совпадения схемы Order с правилами validate на тестовых данных
+func TestOrderSchemaMatchesValidator(t *testing.T) {
+	schema := compileSchema(t, "Order")
+	validate := validator.New()
+
+	valid, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+
+	// Изменения, которые должны отклоняться и валидатором, и схемой
+	mutations := map[string]func(order map[string]any){
+		"valid":               func(order map[string]any) {},
+		"uuid not v4":         func(order map[string]any) { order["order_uid"] = "1864b7f1-c455-1300-bfdc-d339429c2099" },
+		"long track number":   func(order map[string]any) { order["track_number"] = strings.Repeat("A", 51) },
+		"non alphanum entry":  func(order map[string]any) { order["entry"] = "WB-IL" },
+		"bad phone":           func(order map[string]any) { order["delivery"].(map[string]any)["phone"] = "12345" },
+		"bad email":           func(order map[string]any) { order["delivery"].(map[string]any)["email"] = "not-an-email" },
+		"zero amount":         func(order map[string]any) { order["payment"].(map[string]any)["amount"] = 0 },
+		"no items":            func(order map[string]any) { order["items"] = []any{} },
+		"status too big":      func(order map[string]any) { order["items"].([]any)[0].(map[string]any)["status"] = 1000 },
+		"missing customer id": func(order map[string]any) { delete(order, "customer_id") },
+		"signature optional":  func(order map[string]any) { delete(order, "internal_signature") },
+	}
+
+	for name, mutate := range mutations {
+		t.Run(name, func(t *testing.T) {
+			var raw map[string]any
+			if err := json.Unmarshal(valid, &raw); err != nil {
+				t.Fatalf("Failed to unmarshal order: %v", err)
+			}
+			mutate(raw)
+			body, _ := json.Marshal(raw)
+
+			var order models.Order
+			validatorErr := json.Unmarshal(body, &order)
+			if validatorErr == nil {
+				validatorErr = validate.Struct(order)
+			}
+			schemaErr := validateBody(t, schema, body)
+
+			if (validatorErr == nil) != (schemaErr == nil) {
+				t.Errorf("Validator and schema disagree: validator=%v, schema=%v", validatorErr, schemaErr)
+			}
+		})
+	}
+}
+
+// Сбор имен JSON полей структуры
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// Тестирование наличия в спецификации всех полей моделей и структур ответов
+func TestOrderSchemaCoversModel(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(api.OpenAPI, &spec); err != nil {
+		t.Fatalf("Failed to unmarshal OpenAPI document: %v", err)
+	}
+
+	schemaModels := map[string]reflect.Type{
+		"Order":                  reflect.TypeOf(models.Order{}),
+		"Delivery":               reflect.TypeOf(models.Delivery{}),
+		"Payment":                reflect.TypeOf(models.Payment{}),
+		"Item":                   reflect.TypeOf(models.Item{}),
+		"OrderResponse":          reflect.TypeOf(dto.OrderResponse{}),
+		"DeliveryResponse":       reflect.TypeOf(dto.DeliveryResponse{}),
+		"PaymentResponse":        reflect.TypeOf(dto.PaymentResponse{}),
+		"ItemResponse":           reflect.TypeOf(dto.ItemResponse{}),
+		"OrderUIDs":              reflect.TypeOf(dto.OrderUIDsResponse{}),
+		"BatchGetOrdersRequest":  reflect.TypeOf(dto.BatchGetOrdersRequest{}),
+		"BatchGetOrdersResponse": reflect.TypeOf(dto.BatchGetOrdersResponse{}),
+		"OrderSearchResponse":    reflect.TypeOf(dto.OrderSearchResponse{}),
+		"ImportEntry":            reflect.TypeOf(importer.Entry{}),
+		"ImportResult":           reflect.TypeOf(importResult{}),
+		"FieldError":             reflect.TypeOf(FieldError{}),
+		"Problem":                reflect.TypeOf(Problem{}),
+	}
+
+	for schemaName, typ := range schemaModels {
+		properties := spec.Components.Schemas[schemaName].Properties
+		fields := jsonFields(typ)
+
+		for _, field := range fields {
+			if _, exists := properties[field]; !exists {
+				t.Errorf("Schema %s has no property %s", schemaName, field)
+			}
+		}
+		if len(properties) != len(fields) {
+			t.Errorf("Schema %s has %d properties, but model has %d fields", schemaName, len(properties), len(fields))
+		}
+	}
+}
+
+// Тестирование ответов хендлеров по спецификации
+func TestHandlerResponsesMatchSpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+	cache := cache.NewCache(10)
+	cache.Set(order)
+	handler := NewHandler(&mockStorage{}, &config.Config{}, cache, nil, nil)
+
+	orderResponse := compileSchema(t, "OrderResponse")
+	problem := compileSchema(t, "Problem")
+	uids := compileSchema(t, "OrderUIDs")
+	status := compileSchema(t, "Status")
+
+	call := func(handle gin.HandlerFunc, uid string, role auth.Role) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1/orders/"+uid, nil)
+		c.Params = []gin.Param{{Key: "uid", Value: uid}}
+		auth.SetPrincipal(c, &auth.Principal{Subject: "tester", Role: role})
+		handle(c)
+		return w
+	}
+
+	for _, role := range []auth.Role{auth.RoleViewer, auth.RoleSupport, auth.RoleFinance, auth.RoleAdmin} {
+		w := call(handler.GetOrderByUIDHandle, order.OrderUID, role)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, but got %d", role, w.Code)
+		}
+		if err := validateBody(t, orderResponse, w.Body.Bytes()); err != nil {
+			t.Errorf("Response for %s does not match OrderResponse: %v", role, err)
+		}
+	}
+
+	if w := call(handler.GetOrderByUIDHandle, "nosuchorder", auth.RoleAdmin); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, but got %d", w.Code)
+	} else if err := validateBody(t, problem, w.Body.Bytes()); err != nil {
+		t.Errorf("Not found response does not match Problem: %v", err)
+	}
+
+	if err := validateBody(t, uids, call(handler.GetAllOrdersUIDHandle, "", auth.RoleViewer).Body.Bytes()); err != nil {
+		t.Errorf("UIDs response does not match OrderUIDs: %v", err)
+	}
+	if err := validateBody(t, status, call(handler.TestServerHandle, "", auth.RoleViewer).Body.Bytes()); err != nil {
+		t.Errorf("Server check response does not match Status: %v", err)
+	}
+
+	admin := NewAdminHandler(nil, config.Default(), consumer.NewMetrics(), nil)
+	if err := validateBody(t, compileSchema(t, "ConsumerStats"), call(admin.ConsumerStatsHandle, "", auth.RoleAdmin).Body.Bytes()); err != nil {
+		t.Errorf("Consumer stats response does not match ConsumerStats: %v", err)
+	}
+}
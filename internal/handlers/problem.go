@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тип содержимого ответа с ошибкой по RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// Заголовок и ключ контекста с идентификатором запроса
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "handlers.request_id"
+)
+
+// Вид ошибки со стабильным машиночитаемым типом
+type ProblemType struct {
+	Type   string
+	Title  string
+	Status int
+}
+
+// Каталог видов ошибок API
+var (
+	ProblemInvalidRequest   = ProblemType{"/problems/invalid-request", "Invalid request", http.StatusBadRequest}
+	ProblemUnauthorized     = ProblemType{"/problems/unauthorized", "Authentication required", http.StatusUnauthorized}
+	ProblemForbidden        = ProblemType{"/problems/forbidden", "Access denied", http.StatusForbidden}
+	ProblemNotFound         = ProblemType{"/problems/not-found", "Resource not found", http.StatusNotFound}
+	ProblemOrderNotFound    = ProblemType{"/problems/order-not-found", "Order not found", http.StatusNotFound}
+	ProblemMethodNotAllowed = ProblemType{"/problems/method-not-allowed", "Method not allowed", http.StatusMethodNotAllowed}
+	ProblemNotAcceptable    = ProblemType{"/problems/not-acceptable", "No acceptable representation", http.StatusNotAcceptable}
+	ProblemInvalidConfig    = ProblemType{"/problems/invalid-config", "Invalid configuration", http.StatusUnprocessableEntity}
+	ProblemValidation       = ProblemType{"/problems/validation-failed", "Validation failed", http.StatusUnprocessableEntity}
+	ProblemOrderExists      = ProblemType{"/problems/order-exists", "Order already exists", http.StatusConflict}
+	ProblemIdempotencyReuse = ProblemType{"/problems/idempotency-key-reused", "Idempotency key reused with different request", http.StatusUnprocessableEntity}
+	ProblemIdempotencyBusy  = ProblemType{"/problems/idempotency-key-in-use", "Idempotency key is being processed", http.StatusConflict}
+	ProblemReplayRunning    = ProblemType{"/problems/replay-in-progress", "Replay already in progress", http.StatusConflict}
+	ProblemPayloadTooLarge  = ProblemType{"/problems/payload-too-large", "Request body too large", http.StatusRequestEntityTooLarge}
+	ProblemRateLimited      = ProblemType{"/problems/rate-limited", "Too many requests", http.StatusTooManyRequests}
+	ProblemInternal         = ProblemType{"/problems/internal", "Internal server error", http.StatusInternalServerError}
+	ProblemUnavailable      = ProblemType{"/problems/dependency-unavailable", "Dependency unavailable", http.StatusServiceUnavailable}
+)
+
+// Тело ответа с ошибкой
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Ошибка валидации отдельного поля
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+// Формирование ошибки для текущего запроса
+func NewProblem(c *gin.Context, pt ProblemType, detail string) *Problem {
+	return &Problem{
+		Type:      pt.Type,
+		Title:     pt.Title,
+		Status:    pt.Status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: RequestIDFromContext(c),
+	}
+}
+
+// Ответ с ошибкой и прерывание обработки запроса.
+// API получает application/problem+json, браузер - страницу error.html
+func WriteProblem(c *gin.Context, pt ProblemType, detail string) {
+	writeProblem(c, NewProblem(c, pt, detail))
+}
+
+// Ответ с ошибкой валидации и списком ошибок по полям
+func WriteValidationProblem(c *gin.Context, detail string, errs []FieldError) {
+	problem := NewProblem(c, ProblemValidation, detail)
+	problem.Errors = errs
+	writeProblem(c, problem)
+}
+
+// Отправка сформированной ошибки в согласованном формате
+func writeProblem(c *gin.Context, problem *Problem) {
+	if wantsHTML(c) {
+		message := problem.Title
+		if problem.Detail != "" {
+			message = problem.Detail
+		}
+		c.HTML(problem.Status, "error.html", gin.H{
+			"error":      message,
+			"request_id": problem.RequestID,
+		})
+		c.Abort()
+		return
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// Проверка, что ответ нужно отдать HTML страницей
+func wantsHTML(c *gin.Context) bool {
+	path := c.Request.URL.Path
+	if strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/admin/") {
+		return false
+	}
+	// Без заголовка Accept выбирается первый вариант, то есть JSON
+	return c.NegotiateFormat(ProblemContentType, gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+}
+
+// Соответствие HTTP статусов видам ошибок для middleware других пакетов
+func problemTypeForStatus(status int) ProblemType {
+	switch status {
+	case http.StatusBadRequest:
+		return ProblemInvalidRequest
+	case http.StatusUnauthorized:
+		return ProblemUnauthorized
+	case http.StatusForbidden:
+		return ProblemForbidden
+	case http.StatusNotFound:
+		return ProblemNotFound
+	case http.StatusMethodNotAllowed:
+		return ProblemMethodNotAllowed
+	case http.StatusTooManyRequests:
+		return ProblemRateLimited
+	case http.StatusServiceUnavailable:
+		return ProblemUnavailable
+	default:
+		return ProblemInternal
+	}
+}
+
+// Функция отказа для middleware аутентификации и ограничения частоты
+func DenyWithProblem(c *gin.Context, status int, detail string) {
+	WriteProblem(c, problemTypeForStatus(status), detail)
+}
+
+// Хендлер для несуществующих маршрутов
+func NoRouteHandle(c *gin.Context) {
+	WriteProblem(c, ProblemNotFound, "No route for "+c.Request.Method+" "+c.Request.URL.Path)
+}
+
+// Хендлер для неподдерживаемых методов
+func NoMethodHandle(c *gin.Context) {
+	WriteProblem(c, ProblemMethodNotAllowed, "Method "+c.Request.Method+" is not allowed for "+c.Request.URL.Path)
+}
+
+// Обработка паники в хендлере
+func RecoveryHandle(c *gin.Context, err any) {
+	slog.Error("Recovered from panic", "method", c.Request.Method, "path", c.Request.URL.Path, "err", err)
+	WriteProblem(c, ProblemInternal, "")
+}
+
+// Допустимый формат входящего идентификатора запроса
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Middleware идентификатора запроса.
+// Использует входящий X-Request-ID или создает новый и возвращает его в ответе
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			buf := make([]byte, 8)
+			_, _ = rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// Получение идентификатора запроса из контекста
+func RequestIDFromContext(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Создание роутера с обработкой ошибок как в сервисе
+func newProblemRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(RequestID(), gin.CustomRecovery(RecoveryHandle))
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(NoRouteHandle)
+	router.NoMethod(NoMethodHandle)
+
+	router.GET("/api/orders/:uid", func(c *gin.Context) {
+		WriteProblem(c, ProblemOrderNotFound, "No order with UID "+c.Param("uid"))
+	})
+	router.GET("/api/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return router
+}
+
+// Тестирование формата ответов с ошибками
+func TestProblemResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := newProblemRouter()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantType string
+	}{
+		{"handler problem", "GET", "/api/orders/42", http.StatusNotFound, ProblemOrderNotFound.Type},
+		{"no route", "GET", "/api/unknown/route", http.StatusNotFound, ProblemNotFound.Type},
+		{"no method", "DELETE", "/api/orders/42", http.StatusMethodNotAllowed, ProblemMethodNotAllowed.Type},
+		{"panic", "GET", "/api/panic", http.StatusInternalServerError, ProblemInternal.Type},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("Expected status %d, but got %d", tt.wantCode, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("Expected problem content type, but got %q", ct)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal problem: %v", err)
+			}
+			if problem.Type != tt.wantType || problem.Status != tt.wantCode {
+				t.Errorf("Unexpected problem %+v", problem)
+			}
+			if problem.Instance != tt.path || problem.RequestID != "req-1" {
+				t.Errorf("Unexpected instance or request id in %+v", problem)
+			}
+		})
+	}
+}
+
+// Тестирование создания идентификатора запроса
+func TestRequestIDGenerated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := newProblemRouter()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/orders/42", nil)
+	req.Header.Set(RequestIDHeader, "bad id with spaces")
+	router.ServeHTTP(w, req)
+
+	id := w.Header().Get(RequestIDHeader)
+	if id == "" || id == "bad id with spaces" {
+		t.Errorf("Expected generated request id, but got %q", id)
+	}
+}
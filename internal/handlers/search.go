@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/export"
+	"github.com/venexene/wbl0-orders-service/internal/masking"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Размер страницы поиска по умолчанию и максимальный
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// Число выгруженных заказов между отправками данных клиенту
+const exportFlushEvery = 100
+
+// Разбор условий отбора заказов из параметров запроса
+func parseOrderFilter(c *gin.Context) (database.OrderFilter, []FieldError) {
+	filter := database.OrderFilter{
+		CustomerID:      c.Query("customer_id"),
+		TrackNumber:     c.Query("track_number"),
+		DeliveryService: c.Query("delivery_service"),
+		Locale:          c.Query("locale"),
+	}
+	var errs []FieldError
+
+	parseTime := func(name string) time.Time {
+		value := c.Query(name)
+		if value == "" {
+			return time.Time{}
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+		if t, err := time.Parse(time.DateOnly, value); err == nil {
+			return t
+		}
+		errs = append(errs, FieldError{Field: name, Rule: "datetime", Message: "must be RFC 3339 date-time or YYYY-MM-DD date"})
+		return time.Time{}
+	}
+	filter.CreatedFrom = parseTime("created_from")
+	filter.CreatedTo = parseTime("created_to")
+
+	return filter, errs
+}
+
+// Хендлер для поиска заказов по фильтру с постраничной выдачей
+func (h *Handler) SearchOrdersHandle(c *gin.Context) {
+	filter, errs := parseOrderFilter(c)
+
+	limit := defaultSearchLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			errs = append(errs, FieldError{Field: "limit", Rule: "range", Message: "must be a number between 1 and " + strconv.Itoa(maxSearchLimit)})
+		}
+		limit = parsed
+	}
+	if token := c.Query("page_token"); token != "" {
+		cursor, err := database.ParseOrderCursor(token)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "page_token", Rule: "format", Message: "malformed page token"})
+		}
+		filter.After = cursor
+	}
+
+	if len(errs) > 0 {
+		WriteValidationProblem(c, "Invalid search parameters", errs)
+		return
+	}
+
+	// Лишний заказ показывает, что есть следующая страница
+	filter.Limit = limit + 1
+	orders, err := h.storage.SearchOrders(c.Request.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to search orders", "err", err)
+		WriteProblem(c, ProblemInternal, "")
+		return
+	}
+
+	response := dto.OrderSearchResponse{Orders: make([]dto.OrderResponse, 0, len(orders))}
+	if len(orders) > limit {
+		orders = orders[:limit]
+		last := orders[len(orders)-1]
+		response.NextPageToken = database.OrderCursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}.Encode()
+	}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, dto.FromOrder(h.maskOrder(c, order)))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Хендлер для потоковой выгрузки заказов в NDJSON, CSV или Parquet.
+// Использует те же фильтры, что и поиск, и правила маскирования роли субъекта
+func (h *Handler) ExportOrdersHandle(c *gin.Context) {
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		WriteValidationProblem(c, "Invalid export parameters", []FieldError{
+			{Field: "format", Rule: "oneof", Message: "must be one of ndjson, csv, parquet"},
+		})
+		return
+	}
+
+	filter, errs := parseOrderFilter(c)
+	if len(errs) > 0 {
+		WriteValidationProblem(c, "Invalid export parameters", errs)
+		return
+	}
+
+	// Выгрузка может длиться дольше общего таймаута записи ответа
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("Failed to reset write deadline for export", "err", err)
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="orders.`+format.Extension()+`"`)
+	c.Status(http.StatusOK)
+
+	writer, err := export.NewWriter(format, c.Writer)
+	if err != nil {
+		slog.Error("Failed to create export writer", "err", err)
+		c.Abort()
+		return
+	}
+
+	role := auth.RoleFromContext(c)
+	var exposed []string
+	count := 0
+
+	err = h.storage.ExportOrders(c.Request.Context(), filter, func(order *models.Order) error {
+		masked, fields := masking.Order(order, role)
+		for _, field := range fields {
+			if !slices.Contains(exposed, field) {
+				exposed = append(exposed, field)
+			}
+		}
+
+		response := dto.FromOrder(masked)
+		if err := writer.Write(&response); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+
+	// Одна запись аудита на всю выгрузку
+	h.auditExposure(c, "order.export", "", exposed)
+
+	// Заголовки уже отправлены, поэтому ошибка только прерывает поток
+	if err != nil {
+		slog.Error("Failed to export orders", "rows", count, "err", err)
+		c.Abort()
+		return
+	}
+	c.Writer.Flush()
+	log.Printf("Exported %d orders as %s", count, format)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Мок хранилища с упорядоченным списком заказов
+type searchStorage struct {
+	mockStorage
+	orders  []*models.Order
+	filters []database.OrderFilter
+}
+
+// Отбор заказов мока по клиенту и позиции страницы
+func (s *searchStorage) matching(filter database.OrderFilter) []*models.Order {
+	var result []*models.Order
+	passed := filter.After == nil
+	for _, order := range s.orders {
+		if !passed {
+			passed = order.OrderUID == filter.After.OrderUID
+			continue
+		}
+		if filter.CustomerID != "" && order.CustomerID != filter.CustomerID {
+			continue
+		}
+		result = append(result, order)
+	}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
+
+func (s *searchStorage) SearchOrders(ctx context.Context, filter database.OrderFilter) ([]*models.Order, error) {
+	s.filters = append(s.filters, filter)
+	return s.matching(filter), nil
+}
+
+func (s *searchStorage) ExportOrders(ctx context.Context, filter database.OrderFilter, fn func(order *models.Order) error) error {
+	s.filters = append(s.filters, filter)
+	for _, order := range s.matching(filter) {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Создание хранилища с тремя заказами, сначала новые
+func newSearchStorage(t *testing.T) *searchStorage {
+	t.Helper()
+
+	storage := &searchStorage{}
+	uids := []string{
+		"33333333-3333-4333-8333-333333333333",
+		"22222222-2222-4222-8222-222222222222",
+		"11111111-1111-4111-8111-111111111111",
+	}
+	for i, uid := range uids {
+		order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+		if err != nil {
+			t.Fatalf("Failed to load order from file: %v", err)
+		}
+		order.OrderUID = uid
+		order.DateCreated = time.Date(2026, time.March, 3-i, 0, 0, 0, 0, time.UTC)
+		storage.orders = append(storage.orders, order)
+	}
+	return storage
+}
+
+// Выполнение запроса от субъекта с указанной ролью
+func serveAs(router *gin.Engine, role auth.Role, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("X-Test-Role", string(role))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// Роутер с ролью субъекта из тестового заголовка
+func newSearchRouter(handler *Handler) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Subject: "analyst", Role: auth.Role(c.GetHeader("X-Test-Role"))})
+	})
+	router.GET("/api/v1/orders", handler.SearchOrdersHandle)
+	router.GET("/api/v1/orders/export", handler.ExportOrdersHandle)
+	return router
+}
+
+// Тестирование постраничного поиска заказов
+func TestSearchOrdersHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storage := newSearchStorage(t)
+	router := newSearchRouter(NewHandler(storage, &config.Config{}, cache.NewCache(10), nil, nil))
+
+	w := serveAs(router, auth.RoleViewer, "/api/v1/orders?limit=2&customer_id=test")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := validateBody(t, compileSchema(t, "OrderSearchResponse"), w.Body.Bytes()); err != nil {
+		t.Errorf("Response does not match OrderSearchResponse: %v", err)
+	}
+
+	var page dto.OrderSearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(page.Orders) != 2 || page.NextPageToken == "" {
+		t.Fatalf("Expected 2 orders and next page token, but got %d orders and %q", len(page.Orders), page.NextPageToken)
+	}
+	if storage.filters[0].CustomerID != "test" || storage.filters[0].Limit != 3 {
+		t.Errorf("Unexpected filter %+v", storage.filters[0])
+	}
+
+	w = serveAs(router, auth.RoleViewer, "/api/v1/orders?limit=2&page_token="+page.NextPageToken)
+	page = dto.OrderSearchResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(page.Orders) != 1 || page.Orders[0].OrderUID != storage.orders[2].OrderUID || page.NextPageToken != "" {
+		t.Errorf("Expected last order without next page, but got %+v", page)
+	}
+
+	for _, query := range []string{"limit=0", "limit=1000", "created_from=yesterday", "page_token=***"} {
+		if w := serveAs(router, auth.RoleViewer, "/api/v1/orders?"+query); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422 for %s, but got %d", query, w.Code)
+		}
+	}
+}
+
+// Тестирование выгрузки заказов с маскированием по роли
+func TestExportOrdersHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var auditBuf bytes.Buffer
+	storage := newSearchStorage(t)
+	router := newSearchRouter(NewHandler(storage, &config.Config{}, cache.NewCache(10), audit.NewLogger(&auditBuf), nil))
+
+	// NDJSON для роли viewer без сумм и с маскированным телефоном
+	w := serveAs(router, auth.RoleViewer, "/api/v1/orders/export?created_from=2026-03-02")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected NDJSON export, but got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, but got %d", len(lines))
+	}
+	var first dto.OrderResponse
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Failed to unmarshal line: %v", err)
+	}
+	if first.Payment.Amount != nil || first.Delivery.Phone == storage.orders[0].Delivery.Phone {
+		t.Error("Expected viewer export to be masked")
+	}
+	if storage.filters[0].CreatedFrom.IsZero() {
+		t.Error("Expected created_from to be passed to storage")
+	}
+	if auditBuf.Len() != 0 {
+		t.Errorf("Expected no audit records for masked export, but got %s", auditBuf.String())
+	}
+
+	// CSV для администратора с одной записью аудита на выгрузку
+	w = serveAs(router, auth.RoleAdmin, "/api/v1/orders/export?format=csv")
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 1+3*len(storage.orders[0].Items) {
+		t.Errorf("Expected header and one row per item, but got %d records", len(records))
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "orders.csv") {
+		t.Errorf("Unexpected Content-Disposition %q", w.Header().Get("Content-Disposition"))
+	}
+	if lines := strings.Count(auditBuf.String(), "\n"); lines != 1 || !strings.Contains(auditBuf.String(), `"order.export"`) {
+		t.Errorf("Expected one export audit record, but got %s", auditBuf.String())
+	}
+
+	if w := serveAs(router, auth.RoleAdmin, "/api/v1/orders/export?format=xlsx"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for unknown format, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/audit"
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/masking"
+)
+
+// Интервал переподключения, который сообщается клиенту потока
+const streamRetry = 3 * time.Second
+
+// Событие, сообщающее клиенту о пропуске событий, после него нужно перечитать список заказов
+const streamResetEvent = "stream.reset"
+
+// Хендлер потока событий о заказах
+type StreamHandler struct {
+	bus   *events.Bus
+	cfg   *config.Config
+	audit *audit.Logger
+}
+
+// Конструктор хендлера потока событий
+func NewStreamHandler(bus *events.Bus, cfg *config.Config, auditLog *audit.Logger) *StreamHandler {
+	return &StreamHandler{bus: bus, cfg: cfg, audit: auditLog}
+}
+
+// Хендлер потока новых заказов в формате Server-Sent Events.
+// Поддерживает фильтры поиска и продолжение с заголовка Last-Event-ID
+func (h *StreamHandler) StreamOrdersHandle(c *gin.Context) {
+	filter, errs := parseOrderFilter(c)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		// Для клиентов, которые не могут передать заголовок
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "last_event_id", Rule: "number", Message: "must be an event ID"})
+		}
+		lastID = parsed
+	}
+
+	if len(errs) > 0 {
+		WriteValidationProblem(c, "Invalid stream parameters", errs)
+		return
+	}
+
+	sub, replay, gap := h.bus.Subscribe(func(event events.Event) bool {
+		return filter.Matches(event.Order)
+	}, h.cfg.StreamBufferSize, lastID)
+	defer sub.Close()
+
+	// Поток открыт дольше общего таймаута записи ответа
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("Failed to reset write deadline for stream", "err", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry.Milliseconds())
+	if gap {
+		fmt.Fprintf(c.Writer, "event: %s\ndata: {}\n\n", streamResetEvent)
+	}
+	for _, event := range replay {
+		if err := h.writeEvent(c, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.cfg.StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Медленный клиент переподключится с Last-Event-ID и получит пропущенное из кольца
+				if errors.Is(sub.Err(), events.ErrOverflow) {
+					slog.Warn("Closed order stream", "client", c.ClientIP(), "err", sub.Err())
+				}
+				return
+			}
+			if err := h.writeEvent(c, event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// Отправка события с заказом, замаскированным по роли клиента
+func (h *StreamHandler) writeEvent(c *gin.Context, event events.Event) error {
+	masked, exposed := masking.Order(event.Order, auth.RoleFromContext(c))
+	data, err := json.Marshal(dto.FromOrder(masked))
+	if err != nil {
+		slog.Error("Failed to marshal order for stream", "order_uid", event.Order.OrderUID, "err", err)
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+		return err
+	}
+	logExposure(h.audit, c, "order.stream", event.Order.OrderUID, exposed)
+	return nil
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/dto"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Событие, прочитанное из потока
+type sseEvent struct {
+	id, event, data string
+}
+
+// Чтение заданного числа событий потока, комментарии считаются событиями heartbeat
+func readEvents(t *testing.T, scanner *bufio.Scanner, count int) []sseEvent {
+	t.Helper()
+
+	var result []sseEvent
+	var current sseEvent
+	for len(result) < count && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current != (sseEvent{}) {
+				result = append(result, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+			result = append(result, sseEvent{event: "heartbeat"})
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if len(result) < count {
+		t.Fatalf("Expected %d events, but stream ended after %v: %v", count, result, scanner.Err())
+	}
+	return result
+}
+
+// Тестирование потока новых заказов: фильтр, маскирование, продолжение и heartbeat
+func TestStreamOrdersHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+	other := *order
+	other.OrderUID = "b563feb7-b2b8-4b6c-9f5d-3b7a1c1d9e10"
+	other.DeliveryService = "other"
+
+	bus := events.NewBus(10)
+	cfg := &config.Config{StreamBufferSize: 10, StreamHeartbeat: 50 * time.Millisecond}
+	handler := NewStreamHandler(bus, cfg, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/orders/stream", func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Subject: "tester", Role: auth.RoleViewer})
+		handler.StreamOrdersHandle(c)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	open := func(query, lastEventID string) (*bufio.Scanner, func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/orders/stream"+query, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to open stream: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Unexpected stream response %d with %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return bufio.NewScanner(resp.Body), func() {
+			cancel()
+			resp.Body.Close()
+		}
+	}
+
+	waitSubscribers := func(n int) {
+		for deadline := time.Now().Add(time.Second); bus.Subscribers() != n; {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d subscribers, but got %d", n, bus.Subscribers())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	stream, closeStream := open("?delivery_service="+order.DeliveryService, "")
+	waitSubscribers(1)
+
+	bus.Publish(events.OrderCreated, &other)
+	published := bus.Publish(events.OrderCreated, order)
+
+	var got []sseEvent
+	for len(got) == 0 {
+		for _, event := range readEvents(t, stream, 1) {
+			if event.event != "heartbeat" {
+				got = append(got, event)
+			}
+		}
+	}
+	if got[0].event != events.OrderCreated || got[0].id == "" {
+		t.Fatalf("Unexpected event %+v", got[0])
+	}
+	var response dto.OrderResponse
+	if err := json.Unmarshal([]byte(got[0].data), &response); err != nil {
+		t.Fatalf("Failed to unmarshal event data: %v", err)
+	}
+	if response.OrderUID != order.OrderUID || response.Delivery.Phone == order.Delivery.Phone {
+		t.Errorf("Expected masked matching order, but got %+v", response)
+	}
+
+	// Без новых событий приходит heartbeat
+	if received := readEvents(t, stream, 1); received[0].event != "heartbeat" {
+		t.Errorf("Expected heartbeat, but got %+v", received[0])
+	}
+	closeStream()
+	waitSubscribers(0)
+
+	// Продолжение после первого события отдает пропущенное из кольца
+	resumed, closeResumed := open("", strconv.FormatUint(published.ID-1, 10))
+	defer closeResumed()
+	replayed := readEvents(t, resumed, 1)
+	if replayed[0].id != strconv.FormatUint(published.ID, 10) {
+		t.Errorf("Expected replay of event %d, but got %+v", published.ID, replayed[0])
+	}
+
+	// Слишком старый идентификатор приводит к событию сброса
+	reset, closeReset := open("", "1")
+	defer closeReset()
+	if received := readEvents(t, reset, 1); received[0].event != streamResetEvent {
+		t.Errorf("Expected reset event, but got %+v", received[0])
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/orders/stream?last_event_id=abc", nil))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for malformed event ID, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Максимальная длина строки NDJSON
+const maxLineSize = 16 << 20
+
+// Исходная запись файла импорта.
+// Line - номер строки для NDJSON или порядковый номер элемента для JSON массива
+type Record struct {
+	Line int
+	Raw  json.RawMessage
+}
+
+// Последовательное чтение записей из NDJSON или JSON массива.
+// Формат определяется по первому значимому символу
+type Decoder struct {
+	next func() (Record, error)
+}
+
+// Создание декодера записей
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	reader := bufio.NewReaderSize(r, 64<<10)
+
+	first, err := firstSignificant(reader)
+	if err != nil {
+		if err == io.EOF {
+			return &Decoder{next: func() (Record, error) { return Record{}, io.EOF }}, nil
+		}
+		return nil, fmt.Errorf("Failed to read input: %v", err)
+	}
+
+	if first == '[' {
+		return newArrayDecoder(reader)
+	}
+	return newNDJSONDecoder(reader), nil
+}
+
+// Чтение следующей записи, io.EOF в конце входных данных
+func (d *Decoder) Next() (Record, error) {
+	return d.next()
+}
+
+// Первый непробельный символ без его извлечения из потока
+func firstSignificant(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		return b, reader.UnreadByte()
+	}
+}
+
+// Декодер JSON массива: элементы читаются по одному
+func newArrayDecoder(reader io.Reader) (*Decoder, error) {
+	decoder := json.NewDecoder(reader)
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("Failed to read JSON array: %v", err)
+	}
+
+	index := 0
+	return &Decoder{next: func() (Record, error) {
+		if !decoder.More() {
+			return Record{}, io.EOF
+		}
+
+		index++
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return Record{}, fmt.Errorf("Failed to read JSON array element %d: %v", index, err)
+		}
+		return Record{Line: index, Raw: raw}, nil
+	}}, nil
+}
+
+// Декодер NDJSON: одна запись на строку, пустые строки пропускаются
+func newNDJSONDecoder(reader io.Reader) *Decoder {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+
+	line := 0
+	return &Decoder{next: func() (Record, error) {
+		for scanner.Scan() {
+			line++
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+			return Record{Line: line, Raw: append(json.RawMessage(nil), raw...)}, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return Record{}, fmt.Errorf("Failed to read line %d: %v", line+1, err)
+		}
+		return Record{}, io.EOF
+	}}
+}
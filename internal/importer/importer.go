@@ -0,0 +1,253 @@
+// Пакет importer выполняет пакетный импорт заказов из NDJSON и JSON массивов
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Статусы записей в отчете импорта
+const (
+	StatusAccepted  = "accepted"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+)
+
+// Размер пакета по умолчанию
+const DefaultBatchSize = 500
+
+// Хранилище заказов для импорта
+type Store interface {
+	ExistingOrderUIDs(ctx context.Context, orderUIDs []string) ([]string, error)
+	// Возвращает UID заказов, которые уже были сохранены к моменту вставки
+	AddOrders(ctx context.Context, orders []*models.Order) ([]string, error)
+}
+
+// Параметры импорта
+type Options struct {
+	BatchSize   int  // Число заказов в одной транзакции
+	DryRun      bool // Только проверка без записи в БД
+	ResumeAfter int  // Записи с номером не больше этого пропускаются
+
+	// Вызывается после сохранения каждого пакета с номером последней обработанной записи
+	Checkpoint func(line int) error
+}
+
+// Строка отчета по одной записи
+type Entry struct {
+	Line     int      `json:"line"`
+	OrderUID string   `json:"order_uid,omitempty"`
+	Status   string   `json:"status"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Итоги импорта
+type Summary struct {
+	Accepted  int  `json:"accepted"`
+	Duplicate int  `json:"duplicate"`
+	Invalid   int  `json:"invalid"`
+	Skipped   int  `json:"skipped"`
+	LastLine  int  `json:"last_line"`
+	DryRun    bool `json:"dry_run"`
+}
+
+// Импорт заказов
+type Importer struct {
+	store     Store
+	validator *validator.Validate
+	options   Options
+}
+
+// Конструктор импорта
+func New(store Store, options Options) *Importer {
+	if options.BatchSize < 1 {
+		options.BatchSize = DefaultBatchSize
+	}
+	return &Importer{
+		store:     store,
+		validator: models.NewValidator(),
+		options:   options,
+	}
+}
+
+// Ожидающая сохранения запись пакета
+type pending struct {
+	line  int
+	order *models.Order
+}
+
+// Импорт всех записей из r с записью отчета в report.
+// LastLine в итогах - последняя запись, результат которой сохранен, с нее можно продолжить
+func (im *Importer) Run(ctx context.Context, r io.Reader, report func(Entry) error) (*Summary, error) {
+	summary := &Summary{DryRun: im.options.DryRun, LastLine: im.options.ResumeAfter}
+
+	decoder, err := NewDecoder(r)
+	if err != nil {
+		return summary, err
+	}
+
+	seen := make(map[string]bool)
+	var batch []pending
+	var invalid []Entry
+	lastLine := im.options.ResumeAfter
+
+	flush := func() error {
+		if err := im.flush(ctx, batch, invalid, summary, report); err != nil {
+			return err
+		}
+		batch, invalid = batch[:0], invalid[:0]
+		summary.LastLine = lastLine
+		if im.options.Checkpoint != nil {
+			return im.options.Checkpoint(lastLine)
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		record, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+		if record.Line <= im.options.ResumeAfter {
+			summary.Skipped++
+			continue
+		}
+		lastLine = record.Line
+
+		order, problems := im.decode(record.Raw)
+		switch {
+		case len(problems) > 0:
+			invalid = append(invalid, Entry{Line: record.Line, OrderUID: order.OrderUID, Status: StatusInvalid, Errors: problems})
+		case seen[order.OrderUID]:
+			// Повтор UID внутри файла
+			invalid = append(invalid, Entry{Line: record.Line, OrderUID: order.OrderUID, Status: StatusDuplicate})
+		default:
+			seen[order.OrderUID] = true
+			batch = append(batch, pending{line: record.Line, order: order})
+		}
+
+		if len(batch)+len(invalid) >= im.options.BatchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	if len(batch) > 0 || len(invalid) > 0 {
+		if err := flush(); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// Разбор и валидация записи
+func (im *Importer) decode(raw json.RawMessage) (*models.Order, []string) {
+	var order models.Order
+	if err := json.Unmarshal(raw, &order); err != nil {
+		return &order, []string{"Malformed JSON: " + err.Error()}
+	}
+
+	if err := im.validator.Struct(order); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return &order, []string{err.Error()}
+		}
+
+		problems := make([]string, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			field := fe.Namespace()
+			if _, rest, found := strings.Cut(field, "."); found {
+				field = rest
+			}
+			problem := field + ": failed on rule " + fe.Tag()
+			if fe.Param() != "" {
+				problem += "=" + fe.Param()
+			}
+			problems = append(problems, problem)
+		}
+		return &order, problems
+	}
+
+	return &order, nil
+}
+
+// Сохранение пакета и запись отчета в порядке строк
+func (im *Importer) flush(ctx context.Context, batch []pending, invalid []Entry, summary *Summary, report func(Entry) error) error {
+	uids := make([]string, 0, len(batch))
+	for _, p := range batch {
+		uids = append(uids, p.order.OrderUID)
+	}
+
+	existing, err := im.store.ExistingOrderUIDs(ctx, uids)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, uid := range existing {
+		exists[strings.ToLower(uid)] = true
+	}
+
+	var fresh []*models.Order
+	for _, p := range batch {
+		if !exists[strings.ToLower(p.order.OrderUID)] {
+			fresh = append(fresh, p.order)
+		}
+	}
+
+	// Заказ мог быть сохранен параллельно после проверки, такие заказы хранилище пропускает
+	if !im.options.DryRun {
+		raced, err := im.store.AddOrders(ctx, fresh)
+		if err != nil {
+			return fmt.Errorf("Failed to add orders batch: %v", err)
+		}
+		for _, uid := range raced {
+			exists[strings.ToLower(uid)] = true
+		}
+	}
+
+	entries := make([]Entry, 0, len(batch)+len(invalid))
+	for _, p := range batch {
+		status := StatusAccepted
+		if exists[strings.ToLower(p.order.OrderUID)] {
+			status = StatusDuplicate
+		}
+		entries = append(entries, Entry{Line: p.line, OrderUID: p.order.OrderUID, Status: status})
+	}
+
+	entries = append(entries, invalid...)
+	slices.SortFunc(entries, func(a, b Entry) int { return a.Line - b.Line })
+
+	for _, entry := range entries {
+		switch entry.Status {
+		case StatusAccepted:
+			summary.Accepted++
+		case StatusDuplicate:
+			summary.Duplicate++
+		case StatusInvalid:
+			summary.Invalid++
+		}
+		if report != nil {
+			if err := report(entry); err != nil {
+				return fmt.Errorf("Failed to write report: %v", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Хранилище в памяти с подсчетом пакетов.
+// Заказы из raced появляются в хранилище между проверкой и вставкой
+type memoryStore struct {
+	orders  map[string]bool
+	raced   map[string]bool
+	batches int
+}
+
+func (s *memoryStore) ExistingOrderUIDs(ctx context.Context, orderUIDs []string) ([]string, error) {
+	var existing []string
+	for _, uid := range orderUIDs {
+		if s.orders[uid] {
+			existing = append(existing, uid)
+		}
+	}
+	return existing, nil
+}
+
+func (s *memoryStore) AddOrders(ctx context.Context, orders []*models.Order) ([]string, error) {
+	s.batches++
+	var existing []string
+	for _, order := range orders {
+		if s.orders[order.OrderUID] || s.raced[order.OrderUID] {
+			existing = append(existing, order.OrderUID)
+			continue
+		}
+		s.orders[order.OrderUID] = true
+	}
+	return existing, nil
+}
+
+// Тестовые записи: три новых заказа, повтор в файле, заказ из БД и две ошибки
+func testInput(t *testing.T) (string, string) {
+	t.Helper()
+
+	data, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal order: %v", err)
+	}
+
+	withUID := func(uid string) string {
+		raw["order_uid"] = uid
+		line, _ := json.Marshal(raw)
+		return string(line)
+	}
+
+	lines := []string{
+		withUID("11111111-1111-4111-8111-111111111111"),
+		withUID("22222222-2222-4222-8222-222222222222"),
+		"",
+		withUID("11111111-1111-4111-8111-111111111111"),
+		`{"order_uid": "broken"`,
+		withUID("33333333-3333-4333-8333-333333333333"),
+		withUID("not-a-uuid"),
+		withUID("44444444-4444-4444-8444-444444444444"),
+	}
+	ndjson := strings.Join(lines, "\n") + "\n"
+
+	var elements []string
+	for _, line := range lines {
+		if line != "" && !strings.HasPrefix(line, `{"order_uid": "broken"`) {
+			elements = append(elements, line)
+		}
+	}
+	return ndjson, "[\n" + strings.Join(elements, ",\n") + "\n]"
+}
+
+// Тестирование импорта NDJSON с отчетом по строкам
+func TestImportNDJSON(t *testing.T) {
+	ndjson, _ := testInput(t)
+	store := &memoryStore{orders: map[string]bool{"33333333-3333-4333-8333-333333333333": true}}
+
+	var entries []Entry
+	var checkpoints []int
+	importer := New(store, Options{
+		BatchSize:  3,
+		Checkpoint: func(line int) error { checkpoints = append(checkpoints, line); return nil },
+	})
+	summary, err := importer.Run(context.Background(), strings.NewReader(ndjson), func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	expected := map[int]string{
+		1: StatusAccepted,
+		2: StatusAccepted,
+		4: StatusDuplicate,
+		5: StatusInvalid,
+		6: StatusDuplicate,
+		7: StatusInvalid,
+		8: StatusAccepted,
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d report entries, but got %d: %+v", len(expected), len(entries), entries)
+	}
+	for i, entry := range entries {
+		if expected[entry.Line] != entry.Status {
+			t.Errorf("Line %d: expected %s, but got %s", entry.Line, expected[entry.Line], entry.Status)
+		}
+		if i > 0 && entries[i-1].Line > entry.Line {
+			t.Error("Expected report entries in line order")
+		}
+	}
+	if entries[5].Errors == nil || !strings.Contains(entries[5].Errors[0], "order_uid") {
+		t.Errorf("Expected field error for invalid UID, but got %v", entries[5].Errors)
+	}
+
+	if summary.Accepted != 3 || summary.Duplicate != 2 || summary.Invalid != 2 || summary.LastLine != 8 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if len(store.orders) != 4 || store.batches != 3 {
+		t.Errorf("Expected 4 orders in 3 batches, but got %d in %d", len(store.orders), store.batches)
+	}
+	if len(checkpoints) != 3 || checkpoints[len(checkpoints)-1] != 8 {
+		t.Errorf("Unexpected checkpoints %v", checkpoints)
+	}
+}
+
+// Тестирование проверки JSON массива без записи в БД
+func TestImportJSONArrayDryRun(t *testing.T) {
+	_, array := testInput(t)
+	store := &memoryStore{orders: map[string]bool{}}
+
+	summary, err := New(store, Options{DryRun: true}).Run(context.Background(), strings.NewReader(array), nil)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if summary.Accepted != 4 || summary.Duplicate != 1 || summary.Invalid != 1 || !summary.DryRun {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if len(store.orders) != 0 || store.batches != 0 {
+		t.Error("Expected dry run not to write orders")
+	}
+}
+
+// Тестирование продолжения импорта после прерывания
+func TestImportResume(t *testing.T) {
+	ndjson, _ := testInput(t)
+	store := &memoryStore{orders: map[string]bool{}}
+
+	summary, err := New(store, Options{ResumeAfter: 5}).Run(context.Background(), strings.NewReader(ndjson), nil)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if summary.Skipped != 4 || summary.Accepted != 2 || summary.Invalid != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if store.orders["11111111-1111-4111-8111-111111111111"] {
+		t.Error("Expected records before resume point to be skipped")
+	}
+}
+
+// Тестирование заказа, сохраненного параллельно между проверкой и вставкой
+func TestImportConcurrentInsert(t *testing.T) {
+	ndjson, _ := testInput(t)
+	store := &memoryStore{
+		orders: map[string]bool{},
+		raced:  map[string]bool{"22222222-2222-4222-8222-222222222222": true},
+	}
+
+	var entries []Entry
+	summary, err := New(store, Options{}).Run(context.Background(), strings.NewReader(ndjson), func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected conflict not to fail the batch, but got %v", err)
+	}
+
+	if summary.Accepted != 3 || summary.Duplicate != 2 || summary.Invalid != 2 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if entries[1].Line != 2 || entries[1].Status != StatusDuplicate {
+		t.Errorf("Expected line 2 reported as duplicate, but got %+v", entries[1])
+	}
+	if len(store.orders) != 3 || !store.orders["11111111-1111-4111-8111-111111111111"] {
+		t.Errorf("Expected other orders of the batch saved, but got %v", store.orders)
+	}
+}
@@ -2,86 +2,136 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
-	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/segmentio/kafka-go"
 
 	"github.com/venexene/wbl0-orders-service/internal/cache"
 	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/decoder"
+	"github.com/venexene/wbl0-orders-service/internal/events"
 	"github.com/venexene/wbl0-orders-service/internal/models"
 )
 
-// Структура консьюмера
-type Consumer struct {
-	reader    *kafka.Reader
-	storage   *database.Storage
+// Хранилище заказов из сообщений
+type OrderStore interface {
+	AddOrderFromMessage(ctx context.Context, message *database.ProcessedMessage, order *models.Order) (bool, error)
+	CheckOrderFromMessage(ctx context.Context, message *database.ProcessedMessage, order *models.Order) (bool, error)
+}
+
+// Обработчик сообщений с заказами
+type OrderHandler struct {
+	storage   OrderStore
 	validator *validator.Validate
-	cache	  *cache.Cache
+	cache     *cache.Cache
+	events    *events.Bus
+	decoders  *decoder.Decoders
 }
 
-// Конструктор консьюмера
-func NewConsumer(brokers []string, topic string, storage *database.Storage, cache *cache.Cache) *Consumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: brokers,
-		Topic: topic,
-		MinBytes: 10e3,
-		MaxBytes: 10e6,
-		MaxWait: time.Second,
-		Dialer: &kafka.Dialer{
-			Timeout:   10 * time.Second,
-			DualStack: true,
-		},
-		MaxAttempts: 3,
-
-	})
-
-	validate := validator.New()
-
-	return &Consumer{
-		reader: reader,
-		storage: storage,
-		validator: validate,
-		cache: cache,
+// Конструктор обработчика заказов
+func NewOrderHandler(storage OrderStore, cache *cache.Cache, bus *events.Bus, decoders *decoder.Decoders) *OrderHandler {
+	return &OrderHandler{
+		storage:   storage,
+		validator: models.NewValidator(),
+		cache:     cache,
+		events:    bus,
+		decoders:  decoders,
 	}
 }
 
-// Основной метод для получения сообщений
-func (c *Consumer) Consume(ctx context.Context) {
-	for {
-		msg, err := c.reader.ReadMessage(ctx) // Чтение сообщений из Kafka
-		if err != nil {
-			log.Printf("Kafka failed to consume: %v", err)
-			continue
-		}
-		log.Printf("Received message: %s", string(msg.Value))
+// Проверка, что формат сообщения поддерживается, до обработки
+func (h *OrderHandler) Validate(msg kafka.Message) error {
+	_, err := h.decoders.Format(msg)
+	return err
+}
 
-		var order models.Order
-		// Десериализация JSON
-		if err := json.Unmarshal(msg.Value, &order); err != nil {
-			log.Printf("Failed to unmarshal message: %v", err)
-			continue
-		}
-		
-		// Валидация структуры
-		if err := c.validator.Struct(order); err != nil {
-			log.Printf("Failed to validate: %v", err)
-			continue
-		}
+// Обработка сообщения с заказом; возвращает те же результаты, что и Plan.
+// Неразбираемые, невалидные и конфликтующие сообщения отклоняются, ошибки БД повторяются
+func (h *OrderHandler) Handle(ctx context.Context, msg kafka.Message) (string, error) {
+	order, processed, err := h.prepare(ctx, msg)
+	if err != nil {
+		return "", err
+	}
+	if order == nil {
+		return OutcomeSkipped, nil
+	}
+
+	// Сохранение в БД вместе с отметкой об обработке сообщения
+	duplicate, err := h.storage.AddOrderFromMessage(ctx, processed, order)
+	switch {
+	case errors.Is(err, database.ErrMessageConflict):
+		// Тот же UID с другим содержимым требует ручного разбора
+		return "", Reject(ReasonConflict, err)
+	case err != nil:
+		return "", err
+	case duplicate:
+		log.Printf("Skipped duplicate message %s for order %s", processed.MessageID, order.OrderUID)
+		return OutcomeDuplicate, nil
+	}
+
+	log.Printf("Order saved with UID %s", order.OrderUID)
+	h.cache.Set(order)                           // Добавление в кэш
+	h.events.Publish(events.OrderCreated, order) // Оповещение подписчиков потока заказов
+	return OutcomeCreated, nil
+}
 
-		// Сохраниение в БД
-		if err := c.storage.AddOrderIfNotExists(ctx, &order); err != nil {
-			log.Printf("Failed to add order: %v", err)
-		} else {
-			log.Printf("Order saved with UID %s", order.OrderUID)
-			c.cache.Set(&order) // Добавление в кэш
+// Оценка результата обработки сообщения без записи в БД, кэш и шину событий
+func (h *OrderHandler) Plan(ctx context.Context, msg kafka.Message) (string, error) {
+	order, processed, err := h.prepare(ctx, msg)
+	if err != nil {
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			return rejected.Reason, rejected.Err
 		}
+		return ReasonFailed, err
 	}
+	if order == nil {
+		return OutcomeSkipped, nil
+	}
+
+	duplicate, err := h.storage.CheckOrderFromMessage(ctx, processed, order)
+	switch {
+	case errors.Is(err, database.ErrMessageConflict):
+		return ReasonConflict, err
+	case err != nil:
+		return ReasonFailed, err
+	case duplicate:
+		return OutcomeDuplicate, nil
+	}
+	return OutcomeCreated, nil
 }
 
-// Функция для закрытия соединения с Kafka
-func (c *Consumer) Close() error {
-	return c.reader.Close()
-}
\ No newline at end of file
+// Десериализация и валидация заказа из сообщения.
+// Для событий других типов возвращает nil без ошибки
+func (h *OrderHandler) prepare(ctx context.Context, msg kafka.Message) (*models.Order, *database.ProcessedMessage, error) {
+	// Десериализация в формате из заголовка content-type или формате по умолчанию
+	message, err := h.decoders.Decode(ctx, msg)
+	if err != nil {
+		return nil, nil, Reject(ReasonInvalid, err)
+	}
+	log.Printf("Received %s message of %d bytes, schema version %d", message.Format, len(msg.Value), message.SchemaVersion)
+
+	// Обрабатываются только события создания заказа
+	if message.EventType != events.OrderCreated {
+		log.Printf("Skipped message with event type %s", message.EventType)
+		return nil, nil, nil
+	}
+	order := message.Order
+
+	// Валидация структуры
+	if err := h.validator.Struct(order); err != nil {
+		return nil, nil, Reject(ReasonInvalid, err)
+	}
+
+	processed := &database.ProcessedMessage{
+		MessageID:   MessageID(msg),
+		OrderUID:    order.OrderUID,
+		ContentHash: models.ContentHash(order),
+		Topic:       msg.Topic,
+		Partition:   msg.Partition,
+		Offset:      msg.Offset,
+	}
+	return order, processed, nil
+}
@@ -0,0 +1,135 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	"github.com/venexene/wbl0-orders-service/internal/decoder"
+	"github.com/venexene/wbl0-orders-service/internal/envelope"
+	"github.com/venexene/wbl0-orders-service/internal/events"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Хранилище с заданным результатом сохранения
+type fakeOrderStore struct {
+	duplicate bool
+	err       error
+	saved     []*database.ProcessedMessage
+}
+
+func (s *fakeOrderStore) AddOrderFromMessage(ctx context.Context, message *database.ProcessedMessage, order *models.Order) (bool, error) {
+	s.saved = append(s.saved, message)
+	return s.duplicate, s.err
+}
+
+func (s *fakeOrderStore) CheckOrderFromMessage(ctx context.Context, message *database.ProcessedMessage, order *models.Order) (bool, error) {
+	return s.duplicate, s.err
+}
+
+// Тестирование обработки сообщений с заказами
+func TestOrderHandler(t *testing.T) {
+	value, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	decoders, err := decoder.New(decoder.FormatJSON, nil, envelope.Default())
+	if err != nil {
+		t.Fatalf("Failed to create decoders: %v", err)
+	}
+	msg := kafka.Message{Topic: "orders", Offset: 7, Value: value}
+
+	rejectedAs := func(_ string, err error) string {
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			return rejected.Reason
+		}
+		return ""
+	}
+
+	store := &fakeOrderStore{}
+	orders := cache.NewCache(10)
+	handler := NewOrderHandler(store, orders, events.NewBus(0), decoders)
+	outcome, err := handler.Handle(context.Background(), msg)
+	if err != nil || outcome != OutcomeCreated {
+		t.Fatalf("Expected created order, but got %q, %v", outcome, err)
+	}
+	if len(store.saved) != 1 || store.saved[0].Offset != 7 || store.saved[0].MessageID != MessageID(msg) {
+		t.Errorf("Unexpected processed message %+v", store.saved)
+	}
+	if _, exists := orders.Get(store.saved[0].OrderUID); !exists {
+		t.Error("Expected order in cache")
+	}
+
+	store.duplicate = true
+	if outcome, err := handler.Handle(context.Background(), msg); err != nil || outcome != OutcomeDuplicate {
+		t.Errorf("Expected duplicate, but got %q, %v", outcome, err)
+	}
+	store.duplicate = false
+
+	invalid := kafka.Message{Topic: "orders", Value: []byte("{")}
+	if reason := rejectedAs(handler.Handle(context.Background(), invalid)); reason != ReasonInvalid {
+		t.Errorf("Expected invalid rejection, but got %q", reason)
+	}
+
+	store.err = database.ErrMessageConflict
+	if reason := rejectedAs(handler.Handle(context.Background(), msg)); reason != ReasonConflict {
+		t.Errorf("Expected conflict rejection, but got %q", reason)
+	}
+
+	// Ошибки БД не отклоняются, а повторяются маршрутизатором
+	store.err = errors.New("connection refused")
+	if _, err := handler.Handle(context.Background(), msg); err == nil || rejectedAs("", err) != "" {
+		t.Errorf("Expected retryable error, but got %v", err)
+	}
+}
+
+// Тестирование оценки результата без записи
+func TestOrderHandlerPlan(t *testing.T) {
+	value, err := os.ReadFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	decoders, err := decoder.New(decoder.FormatJSON, nil, envelope.Default())
+	if err != nil {
+		t.Fatalf("Failed to create decoders: %v", err)
+	}
+	msg := kafka.Message{Topic: "orders", Value: value}
+
+	store := &fakeOrderStore{}
+	orders := cache.NewCache(10)
+	handler := NewOrderHandler(store, orders, events.NewBus(0), decoders)
+
+	cases := []struct {
+		name      string
+		msg       kafka.Message
+		duplicate bool
+		err       error
+		expected  string
+	}{
+		{"new order", msg, false, nil, OutcomeCreated},
+		{"same content", msg, true, nil, OutcomeDuplicate},
+		{"other content", msg, false, database.ErrMessageConflict, ReasonConflict},
+		{"database down", msg, false, errors.New("connection refused"), ReasonFailed},
+		{"malformed", kafka.Message{Value: []byte("{")}, false, nil, ReasonInvalid},
+	}
+	for _, tc := range cases {
+		store.duplicate, store.err = tc.duplicate, tc.err
+		outcome, err := handler.Plan(context.Background(), tc.msg)
+		if outcome != tc.expected {
+			t.Errorf("Expected %s for %s, but got %s (%v)", tc.expected, tc.name, outcome, err)
+		}
+		if (err != nil) != (tc.expected == ReasonConflict || tc.expected == ReasonFailed || tc.expected == ReasonInvalid) {
+			t.Errorf("Unexpected error for %s: %v", tc.name, err)
+		}
+	}
+
+	if len(store.saved) != 0 || orders.Size() != 0 {
+		t.Errorf("Expected no writes, but got %d saved and %d cached", len(store.saved), orders.Size())
+	}
+}
@@ -0,0 +1,79 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовки сообщения в топике недоставленных сообщений
+const (
+	DLQReasonHeader    = "dlq-reason"
+	DLQErrorHeader     = "dlq-error"
+	DLQTopicHeader     = "dlq-original-topic"
+	DLQPartitionHeader = "dlq-original-partition"
+	DLQOffsetHeader    = "dlq-original-offset"
+	DLQTimeHeader      = "dlq-time"
+)
+
+// Запись сообщений в Kafka
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Топик недоставленных сообщений.
+// Без топика сообщения только записываются в лог
+type DeadLetterQueue struct {
+	writer messageWriter
+}
+
+// Конструктор очереди недоставленных сообщений, пустой топик отключает отправку
+func NewDeadLetterQueue(brokers []string, topic string) *DeadLetterQueue {
+	if topic == "" {
+		return &DeadLetterQueue{}
+	}
+	return &DeadLetterQueue{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}}
+}
+
+// Отправка исходного сообщения с причиной и координатами в исходном топике
+func (q *DeadLetterQueue) Send(ctx context.Context, msg kafka.Message, reason string, cause error) error {
+	if q.writer == nil {
+		slog.Error("Dropped message, dead-lettering disabled", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "reason", reason, "err", cause)
+		return nil
+	}
+
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: DLQReasonHeader, Value: []byte(reason)},
+		kafka.Header{Key: DLQErrorHeader, Value: []byte(cause.Error())},
+		kafka.Header{Key: DLQTopicHeader, Value: []byte(msg.Topic)},
+		kafka.Header{Key: DLQPartitionHeader, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: DLQOffsetHeader, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: DLQTimeHeader, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	err := q.writer.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	if err != nil {
+		return fmt.Errorf("Failed to write dead letter: %v", err)
+	}
+	return nil
+}
+
+// Закрытие соединения с топиком
+func (q *DeadLetterQueue) Close() error {
+	if q.writer == nil {
+		return nil
+	}
+	return q.writer.Close()
+}
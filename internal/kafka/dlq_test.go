@@ -0,0 +1,95 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Запись сообщений в память
+type fakeWriter struct {
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+// Значение заголовка по ключу
+func headerValue(msg kafka.Message, key string) string {
+	for _, header := range msg.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// Тестирование отправки сообщения в топик недоставленных сообщений
+func TestDeadLetterQueueSend(t *testing.T) {
+	writer := &fakeWriter{}
+	queue := &DeadLetterQueue{writer: writer}
+	original := kafka.Message{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("key"),
+		Value:     []byte(`{"order_uid":"1"}`),
+		Headers:   []kafka.Header{{Key: MessageIDHeader, Value: []byte("m-1")}},
+	}
+
+	if err := queue.Send(context.Background(), original, ReasonConflict, errors.New("different content")); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if len(writer.messages) != 1 {
+		t.Fatalf("Expected one message, but got %d", len(writer.messages))
+	}
+
+	sent := writer.messages[0]
+	if string(sent.Key) != "key" || string(sent.Value) != string(original.Value) {
+		t.Errorf("Expected original key and value, but got %s %s", sent.Key, sent.Value)
+	}
+	expected := map[string]string{
+		MessageIDHeader:    "m-1",
+		DLQReasonHeader:    ReasonConflict,
+		DLQErrorHeader:     "different content",
+		DLQTopicHeader:     "orders",
+		DLQPartitionHeader: "2",
+		DLQOffsetHeader:    "42",
+	}
+	for key, value := range expected {
+		if got := headerValue(sent, key); got != value {
+			t.Errorf("Expected header %s=%q, but got %q", key, value, got)
+		}
+	}
+	if len(original.Headers) != 1 {
+		t.Errorf("Expected original headers untouched, but got %v", original.Headers)
+	}
+
+	// Без топика сообщение только логируется
+	if err := (&DeadLetterQueue{}).Send(context.Background(), original, ReasonConflict, errors.New("x")); err != nil {
+		t.Errorf("Expected no error without topic, but got %v", err)
+	}
+}
+
+// Тестирование идентификатора сообщения
+func TestMessageID(t *testing.T) {
+	withHeader := kafka.Message{Value: []byte("a"), Headers: []kafka.Header{{Key: "Message-Id", Value: []byte("m-1")}}}
+	if id := MessageID(withHeader); id != "m-1" {
+		t.Errorf("Expected header ID, but got %q", id)
+	}
+
+	first := MessageID(kafka.Message{Value: []byte("a")})
+	second := MessageID(kafka.Message{Value: []byte("a"), Offset: 10})
+	other := MessageID(kafka.Message{Value: []byte("b")})
+	if first != second || first == other || len(first) != len("sha256:")+64 {
+		t.Errorf("Unexpected content IDs %q %q %q", first, second, other)
+	}
+}
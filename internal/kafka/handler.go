@@ -0,0 +1,72 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовок с типом события, по которому выбирается обработчик
+const EventTypeHeader = "event-type"
+
+// Причины отправки в топик недоставленных сообщений
+const (
+	ReasonConflict = "conflict"
+	ReasonInvalid  = "invalid"
+	ReasonFailed   = "failed"
+)
+
+// Результаты обработки сообщения, кроме причин отправки в DLQ
+const (
+	OutcomeHandled   = "handled"
+	OutcomeSkipped   = "skipped"
+	OutcomeCreated   = "created"
+	OutcomeDuplicate = "duplicate"
+)
+
+// Обработчик сообщений топика.
+// Возвращает результат обработки, например created или duplicate; он попадает в отчет повтора.
+// Ошибка, созданная Reject, сразу отправляет сообщение в топик недоставленных сообщений,
+// остальные ошибки считаются временными и повторяются
+type Handler interface {
+	Handle(ctx context.Context, msg kafka.Message) (string, error)
+}
+
+// Обработчик, который может оценить результат обработки без изменений.
+// Используется при пробном повторе; возвращает результат или причину отказа с ошибкой
+type Planner interface {
+	Plan(ctx context.Context, msg kafka.Message) (string, error)
+}
+
+// Обработчик в виде функции с результатом handled
+type HandlerFunc func(ctx context.Context, msg kafka.Message) error
+
+// Вызов функции обработчика
+func (f HandlerFunc) Handle(ctx context.Context, msg kafka.Message) (string, error) {
+	if err := f(ctx, msg); err != nil {
+		return "", err
+	}
+	return OutcomeHandled, nil
+}
+
+// Отказ в обработке сообщения, повтор которого не поможет
+type RejectedError struct {
+	Reason string
+	Err    error
+}
+
+// Текст ошибки с причиной
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Reason, e.Err)
+}
+
+// Исходная ошибка
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Отказ в обработке с причиной для заголовка dlq-reason
+func Reject(reason string, err error) error {
+	return &RejectedError{Reason: reason, Err: err}
+}
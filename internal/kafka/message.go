@@ -0,0 +1,27 @@
+package consumer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовок с идентификатором сообщения, заданным продюсером
+const MessageIDHeader = "message-id"
+
+// Максимальная длина идентификатора из заголовка
+const maxMessageIDLength = 200
+
+// Идентификатор сообщения для дедупликации: заголовок продюсера
+// или хэш значения для продюсеров без идентификаторов
+func MessageID(msg kafka.Message) string {
+	for _, header := range msg.Headers {
+		if strings.EqualFold(header.Key, MessageIDHeader) && len(header.Value) > 0 && len(header.Value) <= maxMessageIDLength {
+			return string(header.Value)
+		}
+	}
+	sum := sha256.Sum256(msg.Value)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
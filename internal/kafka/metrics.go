@@ -0,0 +1,53 @@
+package consumer
+
+import (
+	"sync"
+	"time"
+)
+
+// Счетчики обработки сообщений одного топика
+type TopicStats struct {
+	Received      uint64    `json:"received"`
+	Handled       uint64    `json:"handled"`
+	Skipped       uint64    `json:"skipped"`
+	Retried       uint64    `json:"retried"`
+	DeadLettered  uint64    `json:"dead_lettered"`
+	LastOffset    int64     `json:"last_offset"`
+	LastMessageAt time.Time `json:"last_message_at"`
+}
+
+// Общие для всех маршрутов счетчики по топикам
+type Metrics struct {
+	mu     sync.Mutex
+	topics map[string]*TopicStats
+}
+
+// Конструктор счетчиков
+func NewMetrics() *Metrics {
+	return &Metrics{topics: make(map[string]*TopicStats)}
+}
+
+// Изменение счетчиков топика
+func (m *Metrics) update(topic string, fn func(stats *TopicStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, exists := m.topics[topic]
+	if !exists {
+		stats = &TopicStats{}
+		m.topics[topic] = stats
+	}
+	fn(stats)
+}
+
+// Копия счетчиков всех топиков
+func (m *Metrics) Snapshot() map[string]TopicStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]TopicStats, len(m.topics))
+	for topic, stats := range m.topics {
+		snapshot[topic] = *stats
+	}
+	return snapshot
+}
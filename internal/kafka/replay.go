@@ -0,0 +1,305 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+var (
+	// Некорректные параметры повтора
+	ErrInvalidReplay = errors.New("Invalid replay request")
+	// Для топика не зарегистрирован маршрут
+	ErrNoRoute = errors.New("No route for topic")
+	// Другой повтор еще выполняется
+	ErrReplayRunning = errors.New("Replay already in progress")
+)
+
+// Время ожидания следующего сообщения, после которого раздел считается прочитанным.
+// Последние смещения могут занимать служебные записи транзакций, которые читатель не отдает
+const replayIdleTimeout = 10 * time.Second
+
+// Параметры повтора: топик, раздел и начальное смещение или время
+type ReplayRequest struct {
+	// Топик, по умолчанию топик заказов из конфигурации
+	Topic string `json:"topic,omitempty"`
+	// Раздел, без него повторяются все разделы
+	Partition *int `json:"partition,omitempty"`
+	// Начальное смещение в разделе или время, задается одно из двух
+	Offset *int64     `json:"offset,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+	// Максимум сообщений, ограничен kafka_replay_max_messages
+	Limit int `json:"limit,omitempty"`
+	// Только оценка результата без записи в БД и DLQ
+	DryRun bool `json:"dry_run"`
+}
+
+// Результат обработки одного сообщения при повторе
+type ReplayEntry struct {
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Key       string `json:"key,omitempty"`
+	Outcome   string `json:"outcome"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Прочитанный диапазон раздела; next_offset позволяет продолжить повтор следующим запросом
+type ReplayPartition struct {
+	Partition   int   `json:"partition"`
+	StartOffset int64 `json:"start_offset"`
+	EndOffset   int64 `json:"end_offset"`
+	NextOffset  int64 `json:"next_offset"`
+}
+
+// Отчет о повторе
+type ReplayReport struct {
+	Topic      string            `json:"topic"`
+	DryRun     bool              `json:"dry_run"`
+	Outcomes   map[string]int    `json:"outcomes"`
+	Partitions []ReplayPartition `json:"partitions"`
+	Entries    []ReplayEntry     `json:"entries"`
+}
+
+// Диапазон смещений раздела: от начального до смещения после последнего сообщения на момент запуска
+type partitionRange struct {
+	Partition int
+	Start     int64
+	End       int64
+}
+
+// Проверка параметров повтора
+func (req *ReplayRequest) validate() error {
+	if (req.Offset == nil) == (req.Since == nil) {
+		return fmt.Errorf("%w: exactly one of offset and since must be set", ErrInvalidReplay)
+	}
+	if req.Offset != nil && req.Partition == nil {
+		return fmt.Errorf("%w: offset requires partition", ErrInvalidReplay)
+	}
+	if req.Offset != nil && *req.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidReplay)
+	}
+	if req.Partition != nil && *req.Partition < 0 {
+		return fmt.Errorf("%w: partition must not be negative", ErrInvalidReplay)
+	}
+	if req.Limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", ErrInvalidReplay)
+	}
+	return nil
+}
+
+// Повтор сообщений топика с заданного смещения или времени через обработчики его маршрута.
+// Сообщения читаются отдельными читателями без группы, поэтому смещения основной группы не меняются.
+// Повтор идет до конца разделов на момент запуска или до лимита сообщений
+func (r *Router) Replay(ctx context.Context, req ReplayRequest) (*ReplayReport, error) {
+	if req.Topic == "" {
+		req.Topic = r.cfg.KafkaTopic
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	limit := r.cfg.KafkaReplayMaxMessages
+	if req.Limit > 0 && req.Limit < limit {
+		limit = req.Limit
+	}
+
+	rt := r.routeFor(req.Topic)
+	if rt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoRoute, req.Topic)
+	}
+	if !r.replaying.TryLock() {
+		return nil, ErrReplayRunning
+	}
+	defer r.replaying.Unlock()
+
+	ranges, err := r.replayRanges(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReplayReport{
+		Topic:      req.Topic,
+		DryRun:     req.DryRun,
+		Outcomes:   make(map[string]int),
+		Partitions: []ReplayPartition{},
+		Entries:    []ReplayEntry{},
+	}
+	// Отдельные счетчики, чтобы повтор не искажал счетчики основного чтения
+	replayer := &Router{cfg: r.cfg, metrics: NewMetrics()}
+
+	log.Printf("Replaying topic %s from %d partitions, limit %d, dry run %t", req.Topic, len(ranges), limit, req.DryRun)
+	for _, pr := range ranges {
+		next := pr.Start
+		var err error
+		if pr.Start < pr.End && len(report.Entries) < limit {
+			next, err = r.replayPartition(ctx, replayer, rt, req, pr, limit, report)
+		}
+		report.Partitions = append(report.Partitions, ReplayPartition{
+			Partition:   pr.Partition,
+			StartOffset: pr.Start,
+			EndOffset:   pr.End,
+			NextOffset:  next,
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	log.Printf("Replayed %d messages of topic %s: %v", len(report.Entries), req.Topic, report.Outcomes)
+	return report, nil
+}
+
+// Повтор одного раздела; возвращает смещение, с которого можно продолжить
+func (r *Router) replayPartition(ctx context.Context, replayer *Router, rt *route, req ReplayRequest, pr partitionRange, limit int, report *ReplayReport) (int64, error) {
+	reader := r.newPartitionReader(req.Topic, pr.Partition, pr.Start)
+	defer reader.Close()
+
+	next := pr.Start
+	for next < pr.End && len(report.Entries) < limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, replayIdleTimeout)
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				log.Printf("No more messages in %s/%d after offset %d", req.Topic, pr.Partition, next)
+				return next, nil
+			}
+			return next, fmt.Errorf("Failed to read %s/%d: %v", req.Topic, pr.Partition, err)
+		}
+		if msg.Offset >= pr.End {
+			break
+		}
+
+		entry := ReplayEntry{Partition: msg.Partition, Offset: msg.Offset, Key: string(msg.Key)}
+		if req.DryRun {
+			var cause error
+			entry.Outcome, cause = rt.plan(ctx, msg)
+			if cause != nil {
+				entry.Error = cause.Error()
+			}
+		} else {
+			var ok bool
+			entry.Outcome, ok = replayer.process(ctx, rt, msg)
+			if !ok {
+				return next, ctx.Err()
+			}
+		}
+		report.Outcomes[entry.Outcome]++
+		report.Entries = append(report.Entries, entry)
+		next = msg.Offset + 1
+	}
+	return next, nil
+}
+
+// Маршрут, обрабатывающий топик
+func (r *Router) routeFor(topic string) *route {
+	for _, rt := range r.routes {
+		if rt.Topic == topic || (rt.pattern != nil && rt.pattern.MatchString(topic)) {
+			return rt
+		}
+	}
+	return nil
+}
+
+// Оценка результата обработки без изменений
+func (rt *route) plan(ctx context.Context, msg kafka.Message) (string, error) {
+	if rt.Validate != nil {
+		if err := rt.Validate(msg); err != nil {
+			return ReasonInvalid, err
+		}
+	}
+	handler := rt.handlerFor(msg)
+	if handler == nil {
+		return OutcomeSkipped, nil
+	}
+	if planner, ok := handler.(Planner); ok {
+		return planner.Plan(ctx, msg)
+	}
+	return OutcomeHandled, nil
+}
+
+// Создание читателя одного раздела без группы консьюмеров
+func (r *Router) kafkaPartitionReader(topic string, partition int, offset int64) partitionReader {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   r.cfg.Brokers(),
+		Topic:     topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+		MaxWait:   r.cfg.KafkaMaxWait,
+		Dialer: &kafka.Dialer{
+			Timeout:   r.cfg.KafkaDialTimeout,
+			DualStack: true,
+		},
+		MaxAttempts: 3,
+	})
+	// Ошибка возможна только для читателя группы
+	reader.SetOffset(offset)
+	return reader
+}
+
+// Определение диапазонов смещений разделов по лидерам разделов
+func (r *Router) kafkaReplayRanges(ctx context.Context, req ReplayRequest) ([]partitionRange, error) {
+	dialer := &kafka.Dialer{Timeout: r.cfg.KafkaDialTimeout, DualStack: true}
+	broker := r.cfg.Brokers()[0]
+
+	conn, err := dialer.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect Kafka: %v", err)
+	}
+	partitions, err := conn.ReadPartitions(req.Topic)
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read partitions of %s: %v", req.Topic, err)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].ID < partitions[j].ID })
+
+	var ranges []partitionRange
+	for _, partition := range partitions {
+		if req.Partition != nil && partition.ID != *req.Partition {
+			continue
+		}
+
+		leader, err := dialer.DialLeader(ctx, "tcp", broker, req.Topic, partition.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to connect leader of %s/%d: %v", req.Topic, partition.ID, err)
+		}
+		pr, err := readPartitionRange(leader, req, partition.ID)
+		leader.Close()
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, pr)
+	}
+	if req.Partition != nil && len(ranges) == 0 {
+		return nil, fmt.Errorf("%w: topic %s has no partition %d", ErrInvalidReplay, req.Topic, *req.Partition)
+	}
+	return ranges, nil
+}
+
+// Начальное и конечное смещение раздела; начальное смещение приводится к сохраненному диапазону
+func readPartitionRange(leader *kafka.Conn, req ReplayRequest, partition int) (partitionRange, error) {
+	first, last, err := leader.ReadOffsets()
+	if err != nil {
+		return partitionRange{}, fmt.Errorf("Failed to read offsets of %s/%d: %v", req.Topic, partition, err)
+	}
+
+	start := first
+	if req.Offset != nil {
+		start = *req.Offset
+	} else {
+		start, err = leader.ReadOffset(*req.Since)
+		if err != nil {
+			return partitionRange{}, fmt.Errorf("Failed to find offset of %s/%d at %s: %v", req.Topic, partition, req.Since.Format(time.RFC3339), err)
+		}
+		// Нет сообщений новее заданного времени
+		if start < 0 {
+			start = last
+		}
+	}
+	start = max(first, min(start, last))
+	return partitionRange{Partition: partition, Start: start, End: last}, nil
+}
@@ -0,0 +1,188 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+)
+
+// Обработчик, умеющий оценивать результат без изменений
+type planningHandler struct {
+	handled []int64
+	planned []int64
+}
+
+func (h *planningHandler) Handle(ctx context.Context, msg kafka.Message) (string, error) {
+	h.handled = append(h.handled, msg.Offset)
+	if string(msg.Value) == "conflict" {
+		return "", Reject(ReasonConflict, errors.New("different content"))
+	}
+	return OutcomeCreated, nil
+}
+
+func (h *planningHandler) Plan(ctx context.Context, msg kafka.Message) (string, error) {
+	h.planned = append(h.planned, msg.Offset)
+	if string(msg.Value) == "conflict" {
+		return ReasonConflict, errors.New("different content")
+	}
+	return OutcomeCreated, nil
+}
+
+// Маршрутизатор с разделами в памяти: partition -> сообщения, начиная со смещения 0
+func newReplayRouter(t *testing.T, handler Handler, partitions map[int][]string) (*Router, *fakeWriter, map[int]int64) {
+	t.Helper()
+
+	router := NewRouter(&config.Config{KafkaTopic: "orders", KafkaReplayMaxMessages: 100}, NewMetrics())
+	if err := router.Handle(Route{Topic: "orders", Handler: handler}); err != nil {
+		t.Fatalf("Failed to add route: %v", err)
+	}
+	writer := &fakeWriter{}
+	router.routes[0].dlq = &DeadLetterQueue{writer: writer}
+
+	started := make(map[int]int64)
+	router.replayRanges = func(ctx context.Context, req ReplayRequest) ([]partitionRange, error) {
+		var ranges []partitionRange
+		for partition := 0; partition < len(partitions); partition++ {
+			if req.Partition != nil && *req.Partition != partition {
+				continue
+			}
+			start := int64(0)
+			if req.Offset != nil {
+				start = *req.Offset
+			}
+			ranges = append(ranges, partitionRange{Partition: partition, Start: start, End: int64(len(partitions[partition]))})
+		}
+		return ranges, nil
+	}
+	router.newPartitionReader = func(topic string, partition int, offset int64) partitionReader {
+		started[partition] = offset
+		reader := &fakeReader{}
+		for i, value := range partitions[partition] {
+			if int64(i) >= offset {
+				reader.messages = append(reader.messages, kafka.Message{
+					Topic:     topic,
+					Partition: partition,
+					Offset:    int64(i),
+					Value:     []byte(value),
+				})
+			}
+		}
+		return reader
+	}
+	return router, writer, started
+}
+
+// Тестирование повтора через обычную обработку
+func TestRouterReplay(t *testing.T) {
+	handler := &planningHandler{}
+	router, writer, started := newReplayRouter(t, handler, map[int][]string{
+		0: {"a", "conflict", "b"},
+		1: {"c", "d"},
+	})
+
+	since := time.Now().Add(-time.Hour)
+	report, err := router.Replay(context.Background(), ReplayRequest{Since: &since, Limit: 4})
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+
+	if report.Topic != "orders" || len(report.Entries) != 4 {
+		t.Fatalf("Expected 4 entries for orders, but got %+v", report)
+	}
+	// Результаты те же, что и при пробном повторе
+	if report.Outcomes[OutcomeCreated] != 3 || report.Outcomes[ReasonConflict] != 1 {
+		t.Errorf("Unexpected outcomes %v", report.Outcomes)
+	}
+	if len(handler.handled) != 4 || len(handler.planned) != 0 {
+		t.Errorf("Expected 4 handled and none planned, but got %v %v", handler.handled, handler.planned)
+	}
+	if len(writer.messages) != 1 || headerValue(writer.messages[0], DLQOffsetHeader) != "1" {
+		t.Errorf("Expected conflict dead-lettered, but got %v", writer.messages)
+	}
+
+	// Лимит останавливает повтор во втором разделе, next_offset указывает на продолжение
+	expected := []ReplayPartition{
+		{Partition: 0, StartOffset: 0, EndOffset: 3, NextOffset: 3},
+		{Partition: 1, StartOffset: 0, EndOffset: 2, NextOffset: 1},
+	}
+	if len(report.Partitions) != len(expected) {
+		t.Fatalf("Expected partitions %+v, but got %+v", expected, report.Partitions)
+	}
+	for i := range expected {
+		if report.Partitions[i] != expected[i] {
+			t.Errorf("Expected partition %+v, but got %+v", expected[i], report.Partitions[i])
+		}
+	}
+
+	// Повтор не меняет счетчики основного чтения
+	if stats := router.metrics.Snapshot(); len(stats) != 0 {
+		t.Errorf("Expected no main metrics, but got %v", stats)
+	}
+
+	// Продолжение с next_offset
+	partition, offset := 1, int64(1)
+	report, err = router.Replay(context.Background(), ReplayRequest{Topic: "orders", Partition: &partition, Offset: &offset})
+	if err != nil {
+		t.Fatalf("Failed to continue replay: %v", err)
+	}
+	if len(report.Entries) != 1 || report.Entries[0].Offset != 1 || started[1] != 1 {
+		t.Errorf("Expected replay of offset 1, but got %+v from %d", report.Entries, started[1])
+	}
+}
+
+// Тестирование пробного повтора
+func TestRouterReplayDryRun(t *testing.T) {
+	handler := &planningHandler{}
+	router, writer, _ := newReplayRouter(t, handler, map[int][]string{0: {"a", "conflict"}})
+
+	partition, offset := 0, int64(0)
+	report, err := router.Replay(context.Background(), ReplayRequest{Partition: &partition, Offset: &offset, DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+
+	if !report.DryRun || report.Outcomes[OutcomeCreated] != 1 || report.Outcomes[ReasonConflict] != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if report.Entries[1].Error != "different content" {
+		t.Errorf("Expected conflict error in report, but got %+v", report.Entries[1])
+	}
+	if len(handler.handled) != 0 || len(writer.messages) != 0 {
+		t.Errorf("Expected no side effects, but got %v handled and %d dead letters", handler.handled, len(writer.messages))
+	}
+}
+
+// Тестирование ошибок повтора
+func TestRouterReplayErrors(t *testing.T) {
+	router, _, _ := newReplayRouter(t, &planningHandler{}, map[int][]string{0: {"a"}})
+
+	partition, offset, negative := 0, int64(0), int64(-1)
+	since := time.Now()
+	invalid := map[string]ReplayRequest{
+		"no start":            {},
+		"offset and since":    {Partition: &partition, Offset: &offset, Since: &since},
+		"offset without part": {Offset: &offset},
+		"negative offset":     {Partition: &partition, Offset: &negative},
+		"negative limit":      {Since: &since, Limit: -1},
+	}
+	for name, req := range invalid {
+		if _, err := router.Replay(context.Background(), req); !errors.Is(err, ErrInvalidReplay) {
+			t.Errorf("Expected ErrInvalidReplay for %s, but got %v", name, err)
+		}
+	}
+
+	if _, err := router.Replay(context.Background(), ReplayRequest{Topic: "payments", Since: &since}); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected ErrNoRoute, but got %v", err)
+	}
+
+	router.replaying.Lock()
+	defer router.replaying.Unlock()
+	if _, err := router.Replay(context.Background(), ReplayRequest{Since: &since}); !errors.Is(err, ErrReplayRunning) {
+		t.Errorf("Expected ErrReplayRunning, but got %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+)
+
+// Маршрут сообщений: топик или регулярное выражение с обработчиками и настройками
+type Route struct {
+	// Топик или регулярное выражение для имен топиков; выражение сопоставляется
+	// со списком топиков брокера при запуске
+	Topic   string
+	Pattern string
+	// Группа консьюмеров, по умолчанию группа из конфигурации с суффиксом по топику
+	GroupID string
+
+	// Обработчики по заголовку event-type и обработчик остальных сообщений.
+	// Сообщения без подходящего обработчика пропускаются
+	EventHandlers map[string]Handler
+	Handler       Handler
+
+	// Проверка сообщения до обработки, отказ сразу отправляет сообщение в DLQ
+	Validate func(msg kafka.Message) error
+	// Число участников группы; разделы делятся между ними, порядок внутри раздела сохраняется
+	Concurrency int
+	// Число повторов временных ошибок и начальная задержка между ними
+	Retries      int
+	RetryBackoff time.Duration
+	// Топик недоставленных сообщений, пустой топик только логирует их
+	DLQTopic string
+}
+
+// Чтение сообщений группой консьюмеров
+type messageReader interface {
+	partitionReader
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Чтение сообщений без группы и фиксации смещений
+type partitionReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+// Маршрут с разобранным выражением и очередью недоставленных сообщений
+type route struct {
+	Route
+	pattern *regexp.Regexp
+	dlq     *DeadLetterQueue
+}
+
+// Маршрутизатор сообщений нескольких топиков с общим жизненным циклом и счетчиками
+type Router struct {
+	cfg     *config.Config
+	metrics *Metrics
+	routes  []*route
+
+	newReader  func(topics []string, groupID string) messageReader
+	listTopics func(ctx context.Context) ([]string, error)
+
+	newPartitionReader func(topic string, partition int, offset int64) partitionReader
+	replayRanges       func(ctx context.Context, req ReplayRequest) ([]partitionRange, error)
+	replaying          sync.Mutex
+
+	mu      sync.Mutex
+	readers []messageReader
+	closed  bool
+}
+
+// Конструктор маршрутизатора
+func NewRouter(cfg *config.Config, metrics *Metrics) *Router {
+	r := &Router{cfg: cfg, metrics: metrics}
+	r.newReader = r.kafkaReader
+	r.listTopics = r.brokerTopics
+	r.newPartitionReader = r.kafkaPartitionReader
+	r.replayRanges = r.kafkaReplayRanges
+	return r
+}
+
+// Регистрация маршрута
+func (r *Router) Handle(rt Route) error {
+	if (rt.Topic == "") == (rt.Pattern == "") {
+		return errors.New("Failed to add route: exactly one of topic and pattern must be set")
+	}
+	if rt.Handler == nil && len(rt.EventHandlers) == 0 {
+		return fmt.Errorf("Failed to add route %s: no handlers", rt.name())
+	}
+
+	registered := &route{Route: rt}
+	if rt.Pattern != "" {
+		pattern, err := regexp.Compile(rt.Pattern)
+		if err != nil {
+			return fmt.Errorf("Failed to add route %s: %v", rt.Pattern, err)
+		}
+		registered.pattern = pattern
+	}
+	if registered.Concurrency < 1 {
+		registered.Concurrency = 1
+	}
+	if registered.RetryBackoff <= 0 {
+		registered.RetryBackoff = time.Second
+	}
+	if registered.GroupID == "" {
+		registered.GroupID = r.cfg.KafkaGroupID + "." + strings.Trim(nonGroupChars.ReplaceAllString(rt.name(), "-"), "-")
+	}
+	registered.dlq = NewDeadLetterQueue(r.cfg.Brokers(), rt.DLQTopic)
+
+	r.routes = append(r.routes, registered)
+	return nil
+}
+
+// Символы, заменяемые в имени группы по умолчанию
+var nonGroupChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Имя маршрута для логов
+func (rt *Route) name() string {
+	if rt.Topic != "" {
+		return rt.Topic
+	}
+	return rt.Pattern
+}
+
+// Запуск всех маршрутов и ожидание их завершения при отмене контекста.
+// Топики всех маршрутов определяются до запуска, чтобы ошибка не оставляла часть маршрутов работающими
+func (r *Router) Run(ctx context.Context) error {
+	topics := make([][]string, len(r.routes))
+	for i, rt := range r.routes {
+		resolved, err := r.resolve(ctx, rt)
+		if err != nil {
+			return err
+		}
+		topics[i] = resolved
+	}
+
+	var wg sync.WaitGroup
+	for i, rt := range r.routes {
+		log.Printf("Consuming topics %s with group %s and %d workers", strings.Join(topics[i], ","), rt.GroupID, rt.Concurrency)
+
+		for range rt.Concurrency {
+			reader := r.newReader(topics[i], rt.GroupID)
+			if !r.track(reader) {
+				reader.Close()
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.consume(ctx, rt, reader)
+			}()
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// Топики маршрута: заданный топик или топики брокера, подходящие под выражение
+func (r *Router) resolve(ctx context.Context, rt *route) ([]string, error) {
+	if rt.pattern == nil {
+		return []string{rt.Topic}, nil
+	}
+
+	all, err := r.listTopics(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list topics for %s: %v", rt.Pattern, err)
+	}
+	var topics []string
+	for _, topic := range all {
+		if rt.pattern.MatchString(topic) {
+			topics = append(topics, topic)
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("Failed to start route %s: no matching topics", rt.Pattern)
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+// Цикл чтения одного участника группы.
+// Смещение фиксируется после обработки, поэтому при сбое сообщение будет прочитано повторно
+func (r *Router) consume(ctx context.Context, rt *route, reader messageReader) {
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			// Завершение работы при отмене контекста или закрытии
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return
+			}
+			slog.Error("Kafka failed to consume", "err", err)
+			continue
+		}
+
+		if _, ok := r.process(ctx, rt, msg); !ok {
+			return
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to commit offset", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "err", err)
+		}
+	}
+}
+
+// Обработка сообщения с повторами временных ошибок.
+// Возвращает результат обработки или причину отправки в DLQ,
+// а также false, если обработка прервана отменой контекста и смещение фиксировать нельзя
+func (r *Router) process(ctx context.Context, rt *route, msg kafka.Message) (string, bool) {
+	r.metrics.update(msg.Topic, func(stats *TopicStats) {
+		stats.Received++
+		stats.LastOffset = msg.Offset
+		stats.LastMessageAt = time.Now()
+	})
+
+	if rt.Validate != nil {
+		if err := rt.Validate(msg); err != nil {
+			r.deadLetter(ctx, rt, msg, ReasonInvalid, err)
+			return ReasonInvalid, true
+		}
+	}
+
+	handler := rt.handlerFor(msg)
+	if handler == nil {
+		r.metrics.update(msg.Topic, func(stats *TopicStats) { stats.Skipped++ })
+		return OutcomeSkipped, true
+	}
+
+	delay := rt.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		outcome, err := handler.Handle(ctx, msg)
+		if err == nil {
+			if outcome == OutcomeSkipped {
+				r.metrics.update(msg.Topic, func(stats *TopicStats) { stats.Skipped++ })
+			} else {
+				r.metrics.update(msg.Topic, func(stats *TopicStats) { stats.Handled++ })
+			}
+			return outcome, true
+		}
+
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			r.deadLetter(ctx, rt, msg, rejected.Reason, rejected.Err)
+			return rejected.Reason, true
+		}
+		if ctx.Err() != nil {
+			return "", false
+		}
+		if attempt >= rt.Retries {
+			r.deadLetter(ctx, rt, msg, ReasonFailed, err)
+			return ReasonFailed, true
+		}
+
+		slog.Warn("Failed to handle message, will retry", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "delay", delay, "err", err)
+		r.metrics.update(msg.Topic, func(stats *TopicStats) { stats.Retried++ })
+		select {
+		case <-ctx.Done():
+			return "", false
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Обработчик по заголовку event-type или обработчик по умолчанию
+func (rt *route) handlerFor(msg kafka.Message) Handler {
+	for _, header := range msg.Headers {
+		if strings.EqualFold(header.Key, EventTypeHeader) {
+			if handler, exists := rt.EventHandlers[string(header.Value)]; exists {
+				return handler
+			}
+			break
+		}
+	}
+	return rt.Handler
+}
+
+// Отправка сообщения в топик недоставленных сообщений маршрута
+func (r *Router) deadLetter(ctx context.Context, rt *route, msg kafka.Message, reason string, cause error) {
+	slog.Error("Dead-lettering message", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "reason", reason, "err", cause)
+	r.metrics.update(msg.Topic, func(stats *TopicStats) { stats.DeadLettered++ })
+	if err := rt.dlq.Send(ctx, msg, reason, cause); err != nil {
+		slog.Error("Failed to dead-letter message", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "err", err)
+	}
+}
+
+// Запоминание читателя для закрытия; после Close новые читатели не запускаются
+func (r *Router) track(reader messageReader) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	r.readers = append(r.readers, reader)
+	return true
+}
+
+// Закрытие всех читателей
+func (r *Router) closeReaders() error {
+	r.mu.Lock()
+	readers := r.readers
+	r.readers = nil
+	r.closed = true
+	r.mu.Unlock()
+
+	var errs []error
+	for _, reader := range readers {
+		errs = append(errs, reader.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Закрытие читателей и очередей недоставленных сообщений
+func (r *Router) Close() error {
+	errs := []error{r.closeReaders()}
+	for _, rt := range r.routes {
+		errs = append(errs, rt.dlq.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Создание читателя группы консьюмеров
+func (r *Router) kafkaReader(topics []string, groupID string) messageReader {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     r.cfg.Brokers(),
+		GroupID:     groupID,
+		GroupTopics: topics,
+		MinBytes:    10e3,
+		MaxBytes:    10e6,
+		MaxWait:     r.cfg.KafkaMaxWait,
+		Dialer: &kafka.Dialer{
+			Timeout:   r.cfg.KafkaDialTimeout,
+			DualStack: true,
+		},
+		MaxAttempts: 3,
+	})
+}
+
+// Получение списка топиков брокера
+func (r *Router) brokerTopics(ctx context.Context) ([]string, error) {
+	dialer := &kafka.Dialer{Timeout: r.cfg.KafkaDialTimeout, DualStack: true}
+	conn, err := dialer.DialContext(ctx, "tcp", r.cfg.Brokers()[0])
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var topics []string
+	for _, partition := range partitions {
+		if !seen[partition.Topic] {
+			seen[partition.Topic] = true
+			topics = append(topics, partition.Topic)
+		}
+	}
+	return topics, nil
+}
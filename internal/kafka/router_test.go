@@ -0,0 +1,310 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+)
+
+// Читатель, отдающий сообщения из очереди, а затем ожидающий отмены контекста
+type fakeReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []kafka.Message
+	drained   func()
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	drained := r.drained
+	r.drained = nil
+	r.mu.Unlock()
+
+	if drained != nil {
+		drained()
+	}
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Close() error {
+	return nil
+}
+
+// Маршрутизатор с читателями из памяти: сообщения раздаются по топикам,
+// запуск завершается после того, как все читатели выбрали свои очереди
+type routerHarness struct {
+	router  *Router
+	readers []*fakeReader
+	groups  map[string][]string
+}
+
+func newRouterHarness(messages ...kafka.Message) *routerHarness {
+	h := &routerHarness{groups: make(map[string][]string)}
+	h.router = NewRouter(&config.Config{KafkaGroupID: "orders"}, NewMetrics())
+	h.router.listTopics = func(ctx context.Context) ([]string, error) {
+		return []string{"payments.v1", "orders", "payments.v2", "audit"}, nil
+	}
+	h.router.newReader = func(topics []string, groupID string) messageReader {
+		h.groups[groupID] = topics
+		reader := &fakeReader{}
+		for _, msg := range messages {
+			for _, topic := range topics {
+				if msg.Topic == topic {
+					reader.messages = append(reader.messages, msg)
+				}
+			}
+		}
+		h.readers = append(h.readers, reader)
+		return reader
+	}
+	return h
+}
+
+// Запуск маршрутизатора до обработки всех сообщений
+func (h *routerHarness) run(t *testing.T) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pending sync.WaitGroup
+	for _, rt := range h.router.routes {
+		pending.Add(rt.Concurrency)
+	}
+	newReader := h.router.newReader
+	h.router.newReader = func(topics []string, groupID string) messageReader {
+		reader := newReader(topics, groupID).(*fakeReader)
+		reader.drained = pending.Done
+		return reader
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- h.router.Run(ctx) }()
+
+	pending.Wait()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Failed to run router: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Router did not stop after cancel")
+	}
+}
+
+// Подмена очереди недоставленных сообщений маршрута на запись в память
+func (h *routerHarness) deadLetters(index int) *fakeWriter {
+	writer := &fakeWriter{}
+	h.router.routes[index].dlq = &DeadLetterQueue{writer: writer}
+	return writer
+}
+
+// Сообщение топика с заголовком типа события
+func eventMessage(topic string, offset int64, eventType string) kafka.Message {
+	msg := kafka.Message{Topic: topic, Offset: offset, Value: []byte("{}")}
+	if eventType != "" {
+		msg.Headers = []kafka.Header{{Key: EventTypeHeader, Value: []byte(eventType)}}
+	}
+	return msg
+}
+
+// Тестирование выбора обработчика по типу события
+func TestRouterDispatchesByEventType(t *testing.T) {
+	h := newRouterHarness(
+		eventMessage("orders", 0, "order.created"),
+		eventMessage("orders", 1, "order.cancelled"),
+		eventMessage("orders", 2, "order.unknown"),
+		eventMessage("orders", 3, ""),
+	)
+
+	var created, cancelled []int64
+	err := h.router.Handle(Route{
+		Topic: "orders",
+		EventHandlers: map[string]Handler{
+			"order.created": HandlerFunc(func(ctx context.Context, msg kafka.Message) error {
+				created = append(created, msg.Offset)
+				return nil
+			}),
+			"order.cancelled": HandlerFunc(func(ctx context.Context, msg kafka.Message) error {
+				cancelled = append(cancelled, msg.Offset)
+				return nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add route: %v", err)
+	}
+	h.run(t)
+
+	if len(created) != 1 || created[0] != 0 || len(cancelled) != 1 || cancelled[0] != 1 {
+		t.Errorf("Unexpected dispatch: created %v, cancelled %v", created, cancelled)
+	}
+	if committed := len(h.readers[0].committed); committed != 4 {
+		t.Errorf("Expected 4 committed messages, but got %d", committed)
+	}
+
+	stats := h.router.metrics.Snapshot()["orders"]
+	if stats.Received != 4 || stats.Handled != 2 || stats.Skipped != 2 || stats.LastOffset != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if _, exists := h.groups["orders.orders"]; !exists {
+		t.Errorf("Expected default group orders.orders, but got %v", h.groups)
+	}
+}
+
+// Тестирование отправки в DLQ невалидных, отклоненных и неудачных сообщений
+func TestRouterDeadLetters(t *testing.T) {
+	h := newRouterHarness(
+		eventMessage("orders", 0, ""),
+		kafka.Message{Topic: "orders", Offset: 1, Value: []byte("bad")},
+		eventMessage("orders", 2, "conflict"),
+		eventMessage("orders", 3, "flaky"),
+		eventMessage("orders", 4, "broken"),
+	)
+
+	attempts := make(map[int64]int)
+	err := h.router.Handle(Route{
+		Topic:   "orders",
+		GroupID: "custom",
+		Handler: HandlerFunc(func(ctx context.Context, msg kafka.Message) error {
+			attempts[msg.Offset]++
+			switch headerValue(msg, EventTypeHeader) {
+			case "conflict":
+				return Reject(ReasonConflict, errors.New("different content"))
+			case "flaky":
+				if attempts[msg.Offset] < 3 {
+					return errors.New("database is down")
+				}
+			case "broken":
+				return errors.New("database is down")
+			}
+			return nil
+		}),
+		Validate: func(msg kafka.Message) error {
+			if string(msg.Value) == "bad" {
+				return errors.New("not JSON")
+			}
+			return nil
+		},
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add route: %v", err)
+	}
+	writer := h.deadLetters(0)
+	h.run(t)
+
+	reasons := make(map[string]string)
+	for _, msg := range writer.messages {
+		reasons[headerValue(msg, DLQOffsetHeader)] = headerValue(msg, DLQReasonHeader)
+	}
+	expected := map[string]string{"1": ReasonInvalid, "2": ReasonConflict, "4": ReasonFailed}
+	if len(reasons) != len(expected) {
+		t.Fatalf("Expected dead letters %v, but got %v", expected, reasons)
+	}
+	for offset, reason := range expected {
+		if reasons[offset] != reason {
+			t.Errorf("Expected offset %s dead-lettered as %s, but got %q", offset, reason, reasons[offset])
+		}
+	}
+
+	if attempts[1] != 0 || attempts[2] != 1 || attempts[3] != 3 || attempts[4] != 3 {
+		t.Errorf("Unexpected attempts %v", attempts)
+	}
+	if committed := len(h.readers[0].committed); committed != 5 {
+		t.Errorf("Expected 5 committed messages, but got %d", committed)
+	}
+	stats := h.router.metrics.Snapshot()["orders"]
+	if stats.Handled != 2 || stats.DeadLettered != 3 || stats.Retried != 4 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if _, exists := h.groups["custom"]; !exists {
+		t.Errorf("Expected group custom, but got %v", h.groups)
+	}
+}
+
+// Тестирование маршрута по регулярному выражению с несколькими участниками группы
+func TestRouterPatternRoute(t *testing.T) {
+	h := newRouterHarness(
+		eventMessage("payments.v1", 0, ""),
+		eventMessage("payments.v2", 0, ""),
+		eventMessage("orders", 0, ""),
+	)
+
+	var mu sync.Mutex
+	var topics []string
+	err := h.router.Handle(Route{
+		Pattern: `^payments\..+$`,
+		Handler: HandlerFunc(func(ctx context.Context, msg kafka.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			topics = append(topics, msg.Topic)
+			return nil
+		}),
+		Concurrency: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add route: %v", err)
+	}
+	h.run(t)
+
+	group := "orders.payments-.."
+	resolved, exists := h.groups[group]
+	if !exists || len(resolved) != 2 || resolved[0] != "payments.v1" || resolved[1] != "payments.v2" {
+		t.Errorf("Expected group %s with both payments topics, but got %v", group, h.groups)
+	}
+	if len(h.readers) != 2 {
+		t.Errorf("Expected 2 readers, but got %d", len(h.readers))
+	}
+	// Каждый участник в тесте получает все сообщения, в Kafka разделы делятся между ними
+	if len(topics) != 4 {
+		t.Errorf("Expected 4 handled messages, but got %v", topics)
+	}
+	if _, exists := h.router.metrics.Snapshot()["orders"]; exists {
+		t.Error("Expected orders topic not to be consumed")
+	}
+}
+
+// Тестирование проверки маршрутов
+func TestRouterHandleErrors(t *testing.T) {
+	handler := HandlerFunc(func(ctx context.Context, msg kafka.Message) error { return nil })
+	invalid := map[string]Route{
+		"no topic":    {Handler: handler},
+		"both":        {Topic: "orders", Pattern: "orders.*", Handler: handler},
+		"no handler":  {Topic: "orders"},
+		"bad pattern": {Pattern: "(", Handler: handler},
+	}
+	for name, rt := range invalid {
+		if err := NewRouter(&config.Config{}, NewMetrics()).Handle(rt); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	h := newRouterHarness()
+	h.router.Handle(Route{Pattern: "^missing$", Handler: handler})
+	if err := h.router.Run(context.Background()); err == nil {
+		t.Error("Expected error for pattern without topics")
+	}
+}
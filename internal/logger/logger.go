@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Текущий уровень логирования, меняется без пересоздания логгера
+var level = new(slog.LevelVar)
+
+// Настройка логгера по умолчанию.
+// Стандартный пакет log тоже пишет через этот обработчик с уровнем INFO;
+// предупреждения и ошибки записываются вызовами slog с явным уровнем
+func Setup(levelName string) error {
+	return setup(os.Stderr, levelName)
+}
+
+// Настройка логгера с выводом в w
+func setup(w io.Writer, levelName string) error {
+	if err := SetLevel(levelName); err != nil {
+		return err
+	}
+
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+
+	// Время добавляет обработчик slog
+	log.SetFlags(0)
+	log.SetOutput(Writer(slog.LevelInfo))
+
+	return nil
+}
+
+// Изменение уровня логирования
+func SetLevel(levelName string) error {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(levelName)); err != nil {
+		return fmt.Errorf("Failed to parse log level %q: %v", levelName, err)
+	}
+	level.Set(parsed)
+	return nil
+}
+
+// Writer для библиотек, которые пишут строки в io.Writer, например стандартного log и gin.
+// Каждая строка записывается в логгер по умолчанию с заданным уровнем
+func Writer(level slog.Level) io.Writer {
+	return lineWriter{level: level}
+}
+
+// Запись строк в логгер по умолчанию
+type lineWriter struct {
+	level slog.Level
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			slog.Log(context.Background(), w.level, line)
+		}
+	}
+	return len(p), nil
+}
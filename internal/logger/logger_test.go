@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Тестирование вывода стандартного пакета log и Writer через обработчик slog
+func TestStdLogLevel(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+	defer slog.SetDefault(slog.Default())
+
+	var out bytes.Buffer
+	if err := setup(&out, "info"); err != nil {
+		t.Fatalf("Failed to setup logger: %v", err)
+	}
+
+	// Уровень не зависит от текста сообщения
+	log.Printf("Failed to populate cache: %v", "timeout")
+	slog.Error("Dead-lettering message", "reason", "conflict")
+	if !strings.Contains(out.String(), `level=INFO msg="Failed to populate cache: timeout"`) || !strings.Contains(out.String(), `level=ERROR msg="Dead-lettering message"`) {
+		t.Errorf("Unexpected output at info level: %s", out.String())
+	}
+
+	// Многострочная запись разбивается на отдельные сообщения
+	out.Reset()
+	fmt.Fprint(Writer(slog.LevelWarn), "first\nsecond\n")
+	if strings.Count(out.String(), "level=WARN") != 2 {
+		t.Errorf("Expected two warnings, but got %s", out.String())
+	}
+
+	// Смена уровня действует на уже настроенный вывод log и Writer
+	out.Reset()
+	if err := SetLevel("error"); err != nil {
+		t.Fatalf("Failed to set level: %v", err)
+	}
+	log.Printf("Created cache")
+	fmt.Fprintln(Writer(slog.LevelDebug), "[GIN-debug] GET /api/v1/orders")
+	slog.Error("Kafka failed to consume")
+	if strings.Contains(out.String(), "Created cache") || strings.Contains(out.String(), "GIN-debug") || !strings.Contains(out.String(), "Kafka failed to consume") {
+		t.Errorf("Unexpected output at error level: %s", out.String())
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
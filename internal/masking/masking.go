@@ -0,0 +1,102 @@
+package masking
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Поля с персональными данными, доступ к которым в открытом виде записывается в аудит
+const (
+	FieldDeliveryName    = "delivery.name"
+	FieldDeliveryPhone   = "delivery.phone"
+	FieldDeliveryEmail   = "delivery.email"
+	FieldDeliveryAddress = "delivery.address"
+	FieldTransaction     = "payment.transaction"
+)
+
+// Маскирование заказа в соответствии с ролью.
+// Возвращает копию заказа и список полей, оставшихся открытыми
+func Order(order *models.Order, role auth.Role) (*models.Order, []string) {
+	masked := *order
+	masked.Items = append([]models.Item(nil), order.Items...)
+
+	var exposed []string
+
+	// Контакты клиента полностью видит только администратор
+	if role == auth.RoleAdmin {
+		exposed = append(exposed, FieldDeliveryName, FieldDeliveryPhone, FieldDeliveryEmail, FieldDeliveryAddress)
+	} else {
+		masked.Delivery.Name = Name(order.Delivery.Name)
+		masked.Delivery.Phone = Phone(order.Delivery.Phone)
+		masked.Delivery.Email = Email(order.Delivery.Email)
+		masked.Delivery.Address = Hidden(order.Delivery.Address)
+	}
+
+	switch role {
+	case auth.RoleAdmin, auth.RoleFinance:
+		exposed = append(exposed, FieldTransaction)
+
+	case auth.RoleSupport:
+		// Поддержка видит суммы, но не идентификаторы платежа
+		masked.Payment.Transaction = Tail(order.Payment.Transaction, 4)
+		masked.Payment.RequestID = ""
+
+	default:
+		// Остальным ролям платежные идентификаторы и суммы не показываются
+		masked.Payment.Transaction = ""
+		masked.Payment.RequestID = ""
+		masked.Payment.Amount = 0
+		masked.Payment.DeliveryCost = 0
+		masked.Payment.GoodsTotal = 0
+		masked.Payment.CustomFee = 0
+	}
+
+	return &masked, exposed
+}
+
+// Частичное скрытие телефона: видны код страны и две последние цифры
+func Phone(phone string) string {
+	if len(phone) <= 5 {
+		return Hidden(phone)
+	}
+	return phone[:3] + strings.Repeat("*", len(phone)-5) + phone[len(phone)-2:]
+}
+
+// Частичное скрытие почты: видны первая буква и домен
+func Email(email string) string {
+	local, domain, found := strings.Cut(email, "@")
+	if !found || local == "" {
+		return Hidden(email)
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***@" + domain
+}
+
+// Частичное скрытие имени: видны первые буквы слов
+func Name(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		runes := []rune(word)
+		words[i] = string(runes[0]) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// Частичное скрытие значения: видны последние n символов
+func Tail(value string, n int) string {
+	if len(value) <= n {
+		return Hidden(value)
+	}
+	return strings.Repeat("*", len(value)-n) + value[len(value)-n:]
+}
+
+// Полное скрытие значения
+func Hidden(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "***"
+}
@@ -0,0 +1,78 @@
+package masking
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Тестирование маскирования заказа для каждой роли
+func TestOrderByRole(t *testing.T) {
+	order, err := models.LoadOrderFromFile("../../testdata/order1.json")
+	if err != nil {
+		t.Fatalf("Failed to load order from file: %v", err)
+	}
+
+	tests := []struct {
+		role            auth.Role
+		contactsVisible bool
+		transaction     bool
+		amounts         bool
+		exposed         int
+	}{
+		{auth.RoleViewer, false, false, false, 0},
+		{auth.RoleWriter, false, false, false, 0},
+		{auth.RoleSupport, false, false, true, 0},
+		{auth.RoleFinance, false, true, true, 1},
+		{auth.RoleAdmin, true, true, true, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			masked, exposed := Order(order, tt.role)
+
+			if (masked.Delivery.Phone == order.Delivery.Phone) != tt.contactsVisible {
+				t.Errorf("Unexpected phone %q", masked.Delivery.Phone)
+			}
+			if (masked.Delivery.Email == order.Delivery.Email) != tt.contactsVisible {
+				t.Errorf("Unexpected email %q", masked.Delivery.Email)
+			}
+			if (masked.Payment.Transaction == order.Payment.Transaction) != tt.transaction {
+				t.Errorf("Unexpected transaction %q", masked.Payment.Transaction)
+			}
+			if (masked.Payment.Amount == order.Payment.Amount) != tt.amounts {
+				t.Errorf("Unexpected amount %d", masked.Payment.Amount)
+			}
+			if len(exposed) != tt.exposed {
+				t.Errorf("Expected %d exposed fields, but got %v", tt.exposed, exposed)
+			}
+		})
+	}
+
+	// Исходный заказ не должен меняться
+	if slices.Contains([]string{order.Delivery.Phone, order.Delivery.Email}, "***") {
+		t.Error("Masking changed original order")
+	}
+}
+
+// Тестирование частичного скрытия значений
+func TestPartialMasks(t *testing.T) {
+	if got := Phone("+9720000000"); got != "+97******00" {
+		t.Errorf("Unexpected masked phone %q", got)
+	}
+	if got := Email("test@gmail.com"); got != "t***@gmail.com" {
+		t.Errorf("Unexpected masked email %q", got)
+	}
+	if got := Email("ёжик@почта.рф"); got != "ё***@почта.рф" || !utf8.ValidString(got) {
+		t.Errorf("Unexpected masked non-ASCII email %q", got)
+	}
+	if got := Name("Test Testov"); got != "T*** T***" {
+		t.Errorf("Unexpected masked name %q", got)
+	}
+	if got := Tail("b563feb7b2b84b6test", 4); got != "***************test" {
+		t.Errorf("Unexpected masked transaction %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// Вычисление хэша содержимого заказа.
+// Время создания приводится к UTC и к микросекундам, как в timestamptz,
+// чтобы заказы из Kafka и из БД давали один хэш
+func ContentHash(order *Order) string {
+	normalized := *order
+	normalized.DateCreated = normalized.DateCreated.UTC().Truncate(time.Microsecond)
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		// Заказ всегда сериализуется, но без хэша содержимое не должно совпадать ни с чем
+		slog.Error("Failed to marshal order for hashing", "order_uid", order.OrderUID, "err", err)
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
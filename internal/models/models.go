@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -84,10 +86,24 @@ func LoadOrderFromFile(path string) (*Order, error) {
         return nil, fmt.Errorf("Failed to unmarshal JSON: %v", err)
     }
 
-    validate := validator.New()
+    validate := NewValidator()
     if err := validate.Struct(order); err != nil {
         return nil, fmt.Errorf("Failed to validate order: %v", err)
     }
 
     return &order, nil
-}
\ No newline at end of file
+}
+
+// Создание валидатора заказов.
+// В ошибках валидации поля называются по JSON тегам
+func NewValidator() *validator.Validate {
+    validate := validator.New()
+    validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+        name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+        if name == "-" {
+            return ""
+        }
+        return name
+    })
+    return validate
+}
@@ -0,0 +1,107 @@
+// Пакет orders содержит общую для REST, gRPC и GraphQL логику получения заказов:
+// проверку UID и пакетную выборку с кэшем
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Формат UID заказа, хранящегося в БД; другие значения не отправляются в БД
+var uidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var (
+	// В запросе нет ни одного UID
+	ErrNoUIDs = errors.New("No order UIDs received")
+	// В запросе больше UID, чем разрешено batch_get_max_uids
+	ErrTooManyUIDs = errors.New("Too many order UIDs in one request")
+)
+
+// Проверка формата UID заказа
+func ValidUID(uid string) bool {
+	return uidPattern.MatchString(uid)
+}
+
+// Удаление пустых и повторяющихся UID с сохранением порядка
+func UniqueUIDs(uids []string) []string {
+	seen := make(map[string]bool, len(uids))
+	result := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		uid = strings.TrimSpace(uid)
+		if uid == "" || seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		result = append(result, uid)
+	}
+	return result
+}
+
+// Хранилище для пакетной загрузки заказов
+type Store interface {
+	GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error)
+}
+
+// Результат пакетной выборки в порядке запроса
+type Batch struct {
+	Orders      []*models.Order
+	MissingUIDs []string
+}
+
+// Получение нескольких заказов: сначала из кэша, промахи из БД одним запросом.
+// UID очищаются от пробелов и повторов, пустой список и список длиннее limit отклоняются
+func BatchGet(ctx context.Context, store Store, orders *cache.Cache, uids []string, limit int) (*Batch, error) {
+	uids = UniqueUIDs(uids)
+	if len(uids) == 0 {
+		return nil, ErrNoUIDs
+	}
+	if len(uids) > limit {
+		return nil, fmt.Errorf("%w, maximum is %d", ErrTooManyUIDs, limit)
+	}
+
+	// Поиск в кэше
+	found := make(map[string]*models.Order, len(uids))
+	var misses []string
+	for _, uid := range uids {
+		if order, exists := orders.Get(uid); exists {
+			found[uid] = order
+		} else if ValidUID(uid) {
+			misses = append(misses, strings.ToLower(uid))
+		}
+	}
+
+	// Загрузка промахов из БД
+	if len(misses) > 0 {
+		loaded, err := store.GetOrdersByUIDs(ctx, misses)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get orders by UIDs: %v", err)
+		}
+		for _, order := range loaded {
+			orders.Set(order)
+		}
+		for _, uid := range uids {
+			if order, exists := loaded[strings.ToLower(uid)]; exists {
+				found[uid] = order
+			}
+		}
+	}
+
+	batch := &Batch{
+		Orders:      make([]*models.Order, 0, len(found)),
+		MissingUIDs: []string{},
+	}
+	for _, uid := range uids {
+		if order, exists := found[uid]; exists {
+			batch.Orders = append(batch.Orders, order)
+		} else {
+			batch.MissingUIDs = append(batch.MissingUIDs, uid)
+		}
+	}
+	return batch, nil
+}
@@ -0,0 +1,86 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/venexene/wbl0-orders-service/internal/cache"
+	"github.com/venexene/wbl0-orders-service/internal/models"
+)
+
+// Хранилище в памяти с записью запрошенных UID
+type fakeStore struct {
+	orders    map[string]*models.Order
+	requested []string
+	err       error
+}
+
+func (s *fakeStore) GetOrdersByUIDs(ctx context.Context, orderUIDs []string) (map[string]*models.Order, error) {
+	s.requested = append(s.requested, orderUIDs...)
+	found := make(map[string]*models.Order)
+	for _, uid := range orderUIDs {
+		if order, exists := s.orders[uid]; exists {
+			found[uid] = order
+		}
+	}
+	return found, s.err
+}
+
+// Тестирование проверки и очистки UID
+func TestUIDs(t *testing.T) {
+	if !ValidUID("1864B7F1-C455-4300-BFDC-D339429C2099") || ValidUID("broken") || ValidUID(" 1864b7f1-c455-4300-bfdc-d339429c2099") {
+		t.Error("Unexpected UID validation")
+	}
+
+	uids := UniqueUIDs([]string{" a ", "", "b", "a", "  "})
+	if len(uids) != 2 || uids[0] != "a" || uids[1] != "b" {
+		t.Errorf("Expected [a b], but got %v", uids)
+	}
+}
+
+// Тестирование пакетной выборки: кэш, БД, порядок запроса и ограничения
+func TestBatchGet(t *testing.T) {
+	cached := &models.Order{OrderUID: "11111111-1111-4111-8111-111111111111"}
+	stored := &models.Order{OrderUID: "22222222-2222-4222-8222-222222222222"}
+	store := &fakeStore{orders: map[string]*models.Order{stored.OrderUID: stored}}
+	orders := cache.NewCache(10)
+	orders.Set(cached)
+
+	uids := []string{
+		"22222222-2222-4222-8222-222222222222",
+		"33333333-3333-4333-8333-333333333333",
+		"broken",
+		cached.OrderUID,
+		"22222222-2222-4222-8222-222222222222",
+	}
+	batch, err := BatchGet(context.Background(), store, orders, uids, 4)
+	if err != nil {
+		t.Fatalf("Failed to batch get: %v", err)
+	}
+
+	if len(batch.Orders) != 2 || batch.Orders[0] != stored || batch.Orders[1] != cached {
+		t.Errorf("Expected stored and cached orders in request order, but got %v", batch.Orders)
+	}
+	if len(batch.MissingUIDs) != 2 || batch.MissingUIDs[0] != uids[1] || batch.MissingUIDs[1] != "broken" {
+		t.Errorf("Unexpected missing UIDs %v", batch.MissingUIDs)
+	}
+	// В БД уходят только промахи кэша в формате UUID
+	if len(store.requested) != 2 {
+		t.Errorf("Expected two UIDs requested from database, but got %v", store.requested)
+	}
+	if _, exists := orders.Get(stored.OrderUID); !exists {
+		t.Error("Expected loaded order in cache")
+	}
+
+	if _, err := BatchGet(context.Background(), store, orders, []string{" "}, 4); !errors.Is(err, ErrNoUIDs) {
+		t.Errorf("Expected ErrNoUIDs, but got %v", err)
+	}
+	if _, err := BatchGet(context.Background(), store, orders, []string{"a", "b", "c"}, 2); !errors.Is(err, ErrTooManyUIDs) {
+		t.Errorf("Expected ErrTooManyUIDs, but got %v", err)
+	}
+	store.err = errors.New("connection refused")
+	if _, err := BatchGet(context.Background(), store, orders, []string{"44444444-4444-4444-8444-444444444444"}, 2); err == nil {
+		t.Error("Expected database error")
+	}
+}
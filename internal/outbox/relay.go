@@ -0,0 +1,167 @@
+// Пакет outbox публикует в Kafka события, записанные в таблицу outbox
+// в одной транзакции с заказами
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	consumer "github.com/venexene/wbl0-orders-service/internal/kafka"
+)
+
+// Хранилище событий outbox
+type Store interface {
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]database.OutboxEvent, error)
+	DeleteOutboxEvents(ctx context.Context, ids []int64) error
+	RescheduleOutboxEvent(ctx context.Context, id int64, delay time.Duration, cause string) error
+}
+
+// Запись сообщений в Kafka
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Ретранслятор событий outbox в Kafka
+type Relay struct {
+	store     Store
+	writer    Writer
+	batchSize int
+	interval  time.Duration
+	// Время, на которое захватываются события; после сбоя процесса во время
+	// публикации события становятся доступны снова по его истечении.
+	// Больше времени публикации, чтобы события не захватил другой ретранслятор
+	lease        time.Duration
+	writeTimeout time.Duration
+	maxBackoff   time.Duration
+}
+
+// Конструктор ретранслятора
+func NewRelay(store Store, writer Writer, cfg *config.Config) *Relay {
+	return &Relay{
+		store:        store,
+		writer:       writer,
+		batchSize:    cfg.OutboxBatchSize,
+		interval:     cfg.OutboxPollInterval,
+		lease:        cfg.OutboxLease,
+		writeTimeout: cfg.OutboxWriteTimeout,
+		maxBackoff:   cfg.OutboxMaxBackoff,
+	}
+}
+
+// Создание писателя в топик событий.
+// Сообщения распределяются по разделам хэшем ключа, поэтому события одного заказа
+// попадают в один раздел и сохраняют порядок
+func NewWriter(cfg *config.Config) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers()...),
+		Topic:                  cfg.KafkaOutboxTopic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           10 * time.Millisecond,
+		WriteTimeout:           cfg.OutboxWriteTimeout,
+		AllowAutoTopicCreation: true,
+	}
+}
+
+// Периодическая публикация событий до отмены контекста
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		// Полные пакеты публикуются подряд, пока накопленные события не закончатся
+		for {
+			claimed, err := r.RelayOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to relay outbox events", "err", err)
+				}
+				break
+			}
+			if claimed < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Публикация одного пакета событий. Возвращает число захваченных событий
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.store.ClaimOutboxEvents(ctx, r.batchSize, r.lease)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	messages := make([]kafka.Message, len(events))
+	for i, event := range events {
+		messages[i] = kafka.Message{
+			Key:   []byte(event.Key),
+			Value: event.Payload,
+			Headers: []kafka.Header{
+				{Key: consumer.MessageIDHeader, Value: []byte("outbox-" + strconv.FormatInt(event.ID, 10))},
+				{Key: consumer.EventTypeHeader, Value: []byte(event.EventType)},
+				{Key: "content-type", Value: []byte("application/json")},
+			},
+		}
+	}
+
+	// Публикация вместе с повторами писателя ограничена, чтобы завершиться до конца аренды.
+	// Ошибки отдельных сообщений приходят в WriteErrors, остальные относятся ко всему пакету
+	writeCtx, cancel := context.WithTimeout(ctx, r.writeTimeout)
+	writeErr := r.writer.WriteMessages(writeCtx, messages...)
+	cancel()
+	var perMessage kafka.WriteErrors
+	hasPerMessage := errors.As(writeErr, &perMessage) && len(perMessage) == len(events)
+
+	var published []int64
+	for i, event := range events {
+		failure := writeErr
+		if hasPerMessage {
+			failure = perMessage[i]
+		}
+		if failure == nil {
+			published = append(published, event.ID)
+			continue
+		}
+
+		delay := r.backoff(event.Attempts)
+		slog.Warn("Failed to publish outbox event, will retry", "event_id", event.ID, "delay", delay, "err", failure)
+		if err := r.store.RescheduleOutboxEvent(ctx, event.ID, delay, failure.Error()); err != nil {
+			slog.Error("Failed to reschedule outbox event", "event_id", event.ID, "err", err)
+		}
+	}
+
+	// Неудаленные после публикации события будут отправлены повторно после аренды
+	if err := r.store.DeleteOutboxEvents(ctx, published); err != nil {
+		return len(events), err
+	}
+	return len(events), nil
+}
+
+// Задержка перед следующей попыткой: удвоение интервала опроса с ограничением сверху
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.interval
+	for i := 1; i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.maxBackoff)
+}
+
+// Закрытие писателя
+func (r *Relay) Close() error {
+	return r.writer.Close()
+}
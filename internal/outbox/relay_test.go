@@ -0,0 +1,171 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/venexene/wbl0-orders-service/internal/config"
+	"github.com/venexene/wbl0-orders-service/internal/database"
+	consumer "github.com/venexene/wbl0-orders-service/internal/kafka"
+)
+
+// Хранилище событий в памяти
+type fakeStore struct {
+	pending     []database.OutboxEvent
+	deleted     []int64
+	rescheduled map[int64]time.Duration
+	lease       time.Duration
+}
+
+func (s *fakeStore) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]database.OutboxEvent, error) {
+	s.lease = lease
+	claimed := s.pending
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+	s.pending = s.pending[len(claimed):]
+	for i := range claimed {
+		claimed[i].Attempts++
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	s.deleted = append(s.deleted, ids...)
+	return nil
+}
+
+func (s *fakeStore) RescheduleOutboxEvent(ctx context.Context, id int64, delay time.Duration, cause string) error {
+	s.rescheduled[id] = delay
+	return nil
+}
+
+// Писатель в память с настраиваемой ошибкой
+type fakeWriter struct {
+	messages []kafka.Message
+	err      error
+	deadline time.Time
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.deadline, _ = ctx.Deadline()
+	if w.err == nil {
+		w.messages = append(w.messages, msgs...)
+	}
+	return w.err
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+// Ретранслятор с хранилищем из трех событий
+func newTestRelay(writer *fakeWriter) (*Relay, *fakeStore) {
+	store := &fakeStore{rescheduled: make(map[int64]time.Duration)}
+	for i, key := range []string{"a", "b", "c"} {
+		store.pending = append(store.pending, database.OutboxEvent{
+			ID:        int64(i + 1),
+			Key:       key,
+			EventType: database.OutboxOrderStored,
+			Payload:   []byte(`{"order_uid":"` + key + `"}`),
+		})
+	}
+
+	cfg := config.Default()
+	cfg.OutboxBatchSize = 2
+	return NewRelay(store, writer, cfg), store
+}
+
+// Тестирование публикации и удаления событий
+func TestRelayOnce(t *testing.T) {
+	writer := &fakeWriter{}
+	relay, store := newTestRelay(writer)
+
+	claimed, err := relay.RelayOnce(context.Background())
+	if err != nil || claimed != 2 {
+		t.Fatalf("Expected two events, but got %d, %v", claimed, err)
+	}
+	if len(writer.messages) != 2 || string(writer.messages[0].Key) != "a" || string(writer.messages[1].Key) != "b" {
+		t.Fatalf("Unexpected messages %v", writer.messages)
+	}
+	headers := map[string]string{}
+	for _, header := range writer.messages[0].Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if headers["message-id"] != "outbox-1" || headers[consumer.EventTypeHeader] != database.OutboxOrderStored {
+		t.Errorf("Unexpected headers %v", headers)
+	}
+	if len(store.deleted) != 2 {
+		t.Errorf("Expected published events deleted, but got %v", store.deleted)
+	}
+
+	claimed, _ = relay.RelayOnce(context.Background())
+	claimed2, _ := relay.RelayOnce(context.Background())
+	if claimed != 1 || claimed2 != 0 || len(store.deleted) != 3 {
+		t.Errorf("Expected remaining event published once, but got %d, %d, %v", claimed, claimed2, store.deleted)
+	}
+}
+
+// Тестирование повторов при ошибках публикации
+func TestRelayFailures(t *testing.T) {
+	// Ошибка отдельного сообщения откладывает только его
+	writer := &fakeWriter{err: kafka.WriteErrors{nil, errors.New("leader not available")}}
+	relay, store := newTestRelay(writer)
+	if _, err := relay.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != 1 {
+		t.Errorf("Expected only first event deleted, but got %v", store.deleted)
+	}
+	if delay, exists := store.rescheduled[2]; !exists || delay != time.Second {
+		t.Errorf("Expected second event rescheduled after poll interval, but got %v", store.rescheduled)
+	}
+
+	// Ошибка всего пакета откладывает все события
+	writer = &fakeWriter{err: errors.New("connection refused")}
+	relay, store = newTestRelay(writer)
+	relay.RelayOnce(context.Background())
+	if len(store.deleted) != 0 || len(store.rescheduled) != 2 {
+		t.Errorf("Expected all events rescheduled, but got deleted %v, rescheduled %v", store.deleted, store.rescheduled)
+	}
+}
+
+// Тестирование роста задержки между попытками
+func TestRelayBackoff(t *testing.T) {
+	relay, _ := newTestRelay(&fakeWriter{})
+	expected := map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 10: time.Minute, 100: time.Minute}
+	for attempts, delay := range expected {
+		if got := relay.backoff(attempts); got != delay {
+			t.Errorf("Expected delay %s after %d attempts, but got %s", delay, attempts, got)
+		}
+	}
+}
+
+// Тестирование аренды событий: она задается отдельно и превышает время публикации
+func TestRelayLease(t *testing.T) {
+	writer := &fakeWriter{}
+	relay, store := newTestRelay(writer)
+	relay.lease = 45 * time.Second
+	relay.writeTimeout = 5 * time.Second
+
+	if _, err := relay.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if store.lease != 45*time.Second {
+		t.Errorf("Expected events claimed for outbox_lease, but got %s", store.lease)
+	}
+	if writer.deadline.IsZero() || writer.deadline.After(time.Now().Add(relay.writeTimeout)) {
+		t.Errorf("Expected publish limited by write timeout, but got deadline %v", writer.deadline)
+	}
+
+	// По умолчанию аренда не связана с задержкой повторов
+	cfg := config.Default()
+	cfg.OutboxMaxBackoff = time.Hour
+	if relay := NewRelay(store, writer, cfg); relay.lease != cfg.OutboxLease {
+		t.Errorf("Expected lease %s, but got %s", cfg.OutboxLease, relay.lease)
+	}
+}
@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+	"github.com/venexene/wbl0-orders-service/internal/config"
+)
+
+// Набор правил ограничения
+type Rules struct {
+	// Общее ограничение клиента для маршрутов без отдельного правила
+	Default Limit
+	// Отдельные ограничения по ключу "МЕТОД /шаблон/маршрута"
+	Routes map[string]Limit
+	// Ограничение всех запросов с одного IP адреса до аутентификации,
+	// в том числе без учетных данных или с неверными
+	IP Limit
+}
+
+// Разбор правил маршрутов вида "GET /api/v1/orders/:uid=5/10" через запятую,
+// где 5 - запросов в секунду, 10 - допустимый всплеск
+func ParseRoutes(raw string) (map[string]Limit, error) {
+	routes := make(map[string]Limit)
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		sep := strings.LastIndex(entry, "=")
+		if sep < 0 {
+			return nil, fmt.Errorf("Failed to parse rate limit %q, expected \"METHOD /path=rps/burst\"", entry)
+		}
+		route := strings.Join(strings.Fields(entry[:sep]), " ")
+
+		rateRaw, burstRaw, found := strings.Cut(entry[sep+1:], "/")
+		if !found {
+			return nil, fmt.Errorf("Failed to parse rate limit %q: missing burst", entry)
+		}
+		rate, err := strconv.ParseFloat(rateRaw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse rate of %q: %v", route, err)
+		}
+		burst, err := strconv.Atoi(burstRaw)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse burst of %q: %v", route, err)
+		}
+
+		routes[route] = Limit{Rate: rate, Burst: burst}
+	}
+
+	return routes, nil
+}
+
+// Формирование правил из конфигурации
+func RulesFromConfig(cfg *config.Config) (Rules, error) {
+	routes, err := ParseRoutes(cfg.RateLimitRoutes)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	return Rules{
+		Default: Limit{Rate: cfg.RateLimitRPS, Burst: cfg.RateLimitBurst},
+		Routes:  routes,
+		IP:      Limit{Rate: cfg.RateLimitIPRPS, Burst: cfg.RateLimitIPBurst},
+	}, nil
+}
+
+// Ограничитель запросов с заменяемыми на лету правилами
+type Limiter struct {
+	mu      sync.RWMutex
+	rules   Rules
+	aliases map[string]string
+	store   Store
+	now     func() time.Time
+}
+
+// Конструктор ограничителя
+func NewLimiter(rules Rules, store Store) *Limiter {
+	return &Limiter{
+		rules:   rules,
+		aliases: make(map[string]string),
+		store:   store,
+		now:     time.Now,
+	}
+}
+
+// Псевдоним маршрута: запросы к нему ограничиваются правилом целевого маршрута
+// и расходуют общее с ним ведро. Используется для устаревших маршрутов с тем же хендлером
+func (l *Limiter) Alias(route, target string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.aliases[route] = target
+}
+
+// Замена правил без сброса счетчиков
+func (l *Limiter) Update(rules Rules) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = rules
+}
+
+// Выбор ограничения для маршрута.
+// Возвращает false, если маршрут не ограничивается
+func (l *Limiter) limitFor(route string) (Limit, string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if target, exists := l.aliases[route]; exists {
+		route = target
+	}
+	if limit, exists := l.rules.Routes[route]; exists {
+		return limit, route, limit.Rate > 0 && limit.Burst > 0
+	}
+	limit := l.rules.Default
+	return limit, "*", limit.Rate > 0 && limit.Burst > 0
+}
+
+// Ограничение по IP адресу до аутентификации.
+// Возвращает false, если оно отключено
+func (l *Limiter) ipLimit() (Limit, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	limit := l.rules.IP
+	return limit, limit.Rate > 0 && limit.Burst > 0
+}
+
+// Ключ клиента: API ключ или субъект токена, иначе IP адрес
+func clientKey(c *gin.Context) string {
+	if principal, ok := auth.PrincipalFromContext(c); ok && principal.Method != auth.MethodAnonymous {
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware ограничения частоты запросов по клиенту после аутентификации.
+// Отвечает 429 с заголовками Retry-After и RateLimit-*
+func Middleware(limiter *Limiter, deny auth.DenyFunc) gin.HandlerFunc {
+	deny = denyOrDefault(deny)
+
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		limit, scope, limited := limiter.limitFor(route)
+		if !limited {
+			c.Next()
+			return
+		}
+
+		if limiter.take(c, clientKey(c)+"|"+scope, limit, deny) {
+			c.Next()
+		}
+	}
+}
+
+// Middleware ограничения частоты запросов по IP адресу, подключается до аутентификации.
+// Ограничивает перебор ключей и запросы с неверными учетными данными,
+// которые отклоняются аутентификацией раньше основного ограничения
+func IPMiddleware(limiter *Limiter, deny auth.DenyFunc) gin.HandlerFunc {
+	deny = denyOrDefault(deny)
+
+	return func(c *gin.Context) {
+		limit, limited := limiter.ipLimit()
+		if !limited {
+			c.Next()
+			return
+		}
+
+		if limiter.take(c, "ip:"+c.ClientIP()+"|pre-auth", limit, deny) {
+			c.Next()
+		}
+	}
+}
+
+// Отказ по умолчанию в формате JSON
+func denyOrDefault(deny auth.DenyFunc) auth.DenyFunc {
+	if deny != nil {
+		return deny
+	}
+	return func(c *gin.Context, status int, detail string) {
+		c.AbortWithStatusJSON(status, gin.H{"error": detail})
+	}
+}
+
+// Взятие токена из ведра с заголовками RateLimit-*.
+// Возвращает false, если запрос отклонен
+func (l *Limiter) take(c *gin.Context, key string, limit Limit, deny auth.DenyFunc) bool {
+	result := l.store.Take(key, limit, l.now())
+
+	c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		deny(c, http.StatusTooManyRequests, "Rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// Округление длительности вверх до целых секунд
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
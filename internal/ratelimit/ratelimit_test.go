@@ -0,0 +1,182 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/venexene/wbl0-orders-service/internal/auth"
+)
+
+// Тестирование пополнения ведра токенов
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Unix(1000, 0)
+
+	if res := store.Take("client", limit, now); !res.Allowed || res.Remaining != 1 {
+		t.Errorf("Unexpected first result %+v", res)
+	}
+	if res := store.Take("client", limit, now); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Unexpected second result %+v", res)
+	}
+
+	res := store.Take("client", limit, now)
+	if res.Allowed {
+		t.Fatal("Expected request over burst to be rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("Expected retry after 1s, but got %s", res.RetryAfter)
+	}
+
+	if res := store.Take("client", limit, now.Add(time.Second)); !res.Allowed {
+		t.Error("Expected token to be refilled after 1s")
+	}
+	if res := store.Take("other", limit, now); !res.Allowed {
+		t.Error("Expected other client to have its own bucket")
+	}
+
+	store.Cleanup(now.Add(time.Hour), time.Minute)
+	if store.Len() != 0 {
+		t.Errorf("Expected idle buckets to be removed, but got %d", store.Len())
+	}
+}
+
+// Тестирование разбора правил маршрутов
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("GET /api/orders/:uid=5/10, POST  /admin/config/reload=0.5/1")
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+
+	if got := routes["GET /api/orders/:uid"]; got.Rate != 5 || got.Burst != 10 {
+		t.Errorf("Unexpected limit for orders %+v", got)
+	}
+	if got := routes["POST /admin/config/reload"]; got.Rate != 0.5 || got.Burst != 1 {
+		t.Errorf("Unexpected limit for reload %+v", got)
+	}
+
+	if _, err := ParseRoutes("GET /api/orders/:uid=5"); err == nil {
+		t.Error("Expected error for rule without burst")
+	}
+}
+
+// Тестирование middleware: отдельные ведра по маршрутам и клиентам, заголовки ответа
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewLimiter(Rules{
+		Default: Limit{Rate: 100, Burst: 100},
+		Routes:  map[string]Limit{"GET /api/orders/:uid": {Rate: 1, Burst: 1}},
+	}, NewMemoryStore())
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if key := c.GetHeader("X-Client"); key != "" {
+			auth.SetPrincipal(c, &auth.Principal{Subject: key, Method: auth.MethodAPIKey})
+		}
+	})
+	router.Use(Middleware(limiter, nil))
+	router.GET("/api/orders/:uid", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/all_orders_uids", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(path, client string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		if client != "" {
+			req.Header.Set("X-Client", client)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("/api/orders/1", "a"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Errorf("Unexpected first response %d %v", w.Code, w.Header())
+	}
+
+	w := do("/api/orders/2", "a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, but got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Unexpected rate limit headers %v", w.Header())
+	}
+
+	if w := do("/api/orders/1", "b"); w.Code != http.StatusOK {
+		t.Errorf("Expected other client to pass, but got %d", w.Code)
+	}
+	if w := do("/api/all_orders_uids", "a"); w.Code != http.StatusOK {
+		t.Errorf("Expected default limit for other route, but got %d", w.Code)
+	}
+
+	// Устаревший маршрут с тем же хендлером расходует ведро основного маршрута
+	router.GET("/api/v1/orders/:uid", func(c *gin.Context) { c.Status(http.StatusOK) })
+	limiter.Alias("GET /api/v1/orders/:uid", "GET /api/orders/:uid")
+	if w := do("/api/v1/orders/1", "c"); w.Code != http.StatusOK {
+		t.Errorf("Expected first aliased request to pass, but got %d", w.Code)
+	}
+	if w := do("/api/orders/1", "c"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected shared bucket for alias, but got %d", w.Code)
+	}
+
+	// Отключение ограничения для маршрута через обновление правил
+	limiter.Update(Rules{Routes: map[string]Limit{"GET /api/orders/:uid": {}}})
+	if w := do("/api/orders/3", "a"); w.Code != http.StatusOK {
+		t.Errorf("Expected route without limit after update, but got %d", w.Code)
+	}
+}
+
+// Тестирование ограничения по IP адресу до аутентификации и доверенных прокси
+func TestIPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewLimiter(Rules{IP: Limit{Rate: 1, Burst: 2}}, NewMemoryStore())
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+	router.Use(IPMiddleware(limiter, nil))
+	// Аутентификация отклоняет все запросы, как при переборе ключей
+	router.Use(func(c *gin.Context) { c.AbortWithStatus(http.StatusUnauthorized) })
+	router.GET("/api/v1/orders/:uid", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(remote, forwarded string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/v1/orders/1", nil)
+		req.RemoteAddr = remote + ":5000"
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if do("192.0.2.1", "") != http.StatusUnauthorized || do("192.0.2.1", "") != http.StatusUnauthorized {
+		t.Fatal("Expected failed authentications within burst")
+	}
+	if code := do("192.0.2.1", ""); code != http.StatusTooManyRequests {
+		t.Errorf("Expected failed authentications to be limited, but got %d", code)
+	}
+
+	// Недоверенный клиент не может выбрать ведро через X-Forwarded-For
+	if code := do("192.0.2.1", "198.51.100.7"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected X-Forwarded-For from untrusted client to be ignored, but got %d", code)
+	}
+	// Через доверенный прокси учитывается адрес клиента из заголовка
+	if code := do("10.0.0.1", "198.51.100.7"); code != http.StatusUnauthorized {
+		t.Errorf("Expected client address from trusted proxy, but got %d", code)
+	}
+
+	limiter.Update(Rules{})
+	if code := do("192.0.2.1", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected no IP limit after update, but got %d", code)
+	}
+}
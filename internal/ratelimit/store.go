@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Ограничение в виде ведра токенов
+type Limit struct {
+	// Скорость пополнения, токенов в секунду
+	Rate float64
+	// Емкость ведра
+	Burst int
+}
+
+// Результат попытки взять токен
+type Result struct {
+	Allowed bool
+	// Остаток токенов после попытки
+	Remaining int
+	// Время до появления следующего токена, если запрос отклонен
+	RetryAfter time.Duration
+	// Время до полного восстановления ведра
+	Reset time.Duration
+}
+
+// Хранилище счетчиков. Позволяет заменить память процесса общим хранилищем
+type Store interface {
+	Take(key string, limit Limit, now time.Time) Result
+}
+
+// Состояние ведра одного клиента
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Хранилище счетчиков в памяти процесса
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// Конструктор хранилища в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Попытка взять токен из ведра клиента
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	burst := float64(limit.Burst)
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: burst, updated: now}
+		s.buckets[key] = b
+	}
+
+	// Пополнение ведра за прошедшее время
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsToDuration((burst - b.tokens) / limit.Rate)
+	return result
+}
+
+// Удаление ведер, не использовавшихся дольше idle
+func (s *MemoryStore) Cleanup(now time.Time, idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > idle {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Количество отслеживаемых ведер
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// Очистка неиспользуемых ведер хранилища в памяти с заданным периодом
+func (s *MemoryStore) RunCleanup(stop <-chan struct{}, period, idle time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.Cleanup(now, idle)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,154 @@
+// Загрузка файлов swagger-ui-dist закрепленной версии в web/static/swagger-ui.
+// Страница /api/docs берет Swagger UI только оттуда, без обращения к CDN.
+// Запуск из корня репозитория: go run ./tools/swagger-ui
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Закрепленная версия; при обновлении поменять и комментарий в web/templates/swagger.html
+const version = "5.17.14"
+
+// Файлы пакета, которые нужны странице документации
+var files = []string{"swagger-ui.css", "swagger-ui-bundle.js", "LICENSE"}
+
+// Метаданные версии пакета в реестре npm
+type packageVersion struct {
+	Version string `json:"version"`
+	Dist    struct {
+		Tarball   string `json:"tarball"`
+		Integrity string `json:"integrity"`
+	} `json:"dist"`
+}
+
+func main() {
+	dir := flag.String("dir", "web/static/swagger-ui", "directory for Swagger UI files")
+	registry := flag.String("registry", "https://registry.npmjs.org", "npm registry URL")
+	flag.Parse()
+
+	if err := fetch(*registry, *dir); err != nil {
+		log.Fatalf("Failed to fetch swagger-ui-dist %s: %v", version, err)
+	}
+}
+
+// Загрузка и распаковка, если в каталоге еще нет файлов этой версии
+func fetch(registry, dir string) error {
+	if current, err := os.ReadFile(filepath.Join(dir, "VERSION")); err == nil && strings.TrimSpace(string(current)) == version {
+		log.Printf("swagger-ui-dist %s is already in %s", version, dir)
+		return nil
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	var meta packageVersion
+	body, err := get(client, registry+"/swagger-ui-dist/"+version)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return fmt.Errorf("Failed to parse package metadata: %v", err)
+	}
+	if meta.Version != version || meta.Dist.Tarball == "" {
+		return fmt.Errorf("Unexpected package metadata for version %q", meta.Version)
+	}
+
+	tarball, err := get(client, meta.Dist.Tarball)
+	if err != nil {
+		return err
+	}
+	if err := checkIntegrity(tarball, meta.Dist.Integrity); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("Failed to create %s: %v", dir, err)
+	}
+	if err := extract(tarball, dir); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte(version+"\n"), 0o644); err != nil {
+		return fmt.Errorf("Failed to write version: %v", err)
+	}
+	log.Printf("Saved swagger-ui-dist %s to %s", version, dir)
+	return nil
+}
+
+// Загрузка ресурса целиком
+func get(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to get %s: status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Проверка архива по хэшу sha512 из метаданных реестра
+func checkIntegrity(tarball []byte, integrity string) error {
+	expected, found := strings.CutPrefix(integrity, "sha512-")
+	if !found {
+		return fmt.Errorf("Unsupported integrity %q", integrity)
+	}
+	sum := sha512.Sum512(tarball)
+	if base64.StdEncoding.EncodeToString(sum[:]) != expected {
+		return errors.New("Tarball does not match registry integrity")
+	}
+	return nil
+}
+
+// Распаковка нужных файлов из архива пакета
+func extract(tarball []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return fmt.Errorf("Failed to open tarball: %v", err)
+	}
+	reader := tar.NewReader(gz)
+
+	found := 0
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read tarball: %v", err)
+		}
+
+		name := strings.TrimPrefix(header.Name, "package/")
+		for _, file := range files {
+			if name != file {
+				continue
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return fmt.Errorf("Failed to read %s: %v", name, err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+				return fmt.Errorf("Failed to write %s: %v", file, err)
+			}
+			found++
+		}
+	}
+	if found != len(files) {
+		return fmt.Errorf("Expected %d files in tarball, but found %d", len(files), found)
+	}
+	return nil
+}
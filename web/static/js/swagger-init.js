@@ -0,0 +1,10 @@
+// Инициализация Swagger UI; адрес спецификации берется из атрибута, чтобы страница
+// работала при Content-Security-Policy без inline скриптов
+window.addEventListener("load", () => {
+    const root = document.getElementById("swagger-ui");
+    window.ui = SwaggerUIBundle({
+        url: root.dataset.specUrl,
+        dom_id: "#swagger-ui",
+        persistAuthorization: true,
+    });
+});